@@ -0,0 +1,164 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tool-attendance/log"
+	"tool-attendance/model"
+	"tool-attendance/types"
+)
+
+// 操作类型
+const (
+	ActionCalendarInit = "calendar.init"
+	ActionReportExport = "report.export"
+	ActionApiKeyCreate = "api_key.create"
+	ActionApiKeyRevoke = "api_key.revoke"
+	ActionEmployeeSave = "employee.save"
+	ActionSiteSave     = "site.save"
+	ActionCronRun      = "cron.run"
+
+	ActionWebhookSave      = "webhook.save"
+	ActionWebhookDelete    = "webhook.delete"
+	ActionWebhookRedeliver = "webhook.redeliver"
+
+	ActionMonthClose  = "month.close"
+	ActionMonthReopen = "month.reopen"
+
+	ActionSummaryRebuild = "summary.rebuild"
+
+	ActionLeaveSubmit  = "leave.submit"
+	ActionLeaveApprove = "leave.approve"
+	ActionLeaveReject  = "leave.reject"
+	ActionLeaveCancel  = "leave.cancel"
+	ActionLeaveAdjust  = "leave.adjust"
+)
+
+const (
+	batchSize     = 100
+	flushInterval = time.Second
+)
+
+var (
+	entries chan *model.AuditLog
+	wg      sync.WaitGroup
+	mu      sync.RWMutex // 保护 entries 的发送和关闭
+	stopped bool
+)
+
+// Init 启动后台写库协程，size 为缓冲队列长度
+func Init(size int) {
+	mu.Lock()
+	entries, stopped = make(chan *model.AuditLog, size), false
+	mu.Unlock()
+	wg.Add(1)
+	go loop()
+}
+
+// Stop 停止接收新的日志，并等待队列中的日志写完
+func Stop() {
+	mu.Lock()
+	if entries == nil || stopped {
+		mu.Unlock()
+		return
+	}
+	stopped = true
+	close(entries)
+	mu.Unlock()
+	wg.Wait()
+}
+
+// Record 记录一条管理操作，写库在后台异步完成，队列满或已 Stop 时丢弃并记录错误日志
+func Record(c *gin.Context, action, targetType, targetId string, before, after interface{}) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if entries == nil {
+		return
+	}
+	entry := &model.AuditLog{
+		Action:     action,
+		TargetType: targetType,
+		TargetId:   targetId,
+		Before:     toJson(before),
+		After:      toJson(after),
+		Ip:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		CreatedAt:  time.Now(),
+	}
+	entry.ActorType, entry.ActorId, entry.ActorName = Actor(c)
+	if stopped {
+		log.Log.Errorf("audit stopped, drop: %s %s %s:%s", entry.ActorId, action, targetType, targetId)
+		return
+	}
+	select {
+	case entries <- entry:
+	default:
+		log.Log.WithAlarm().Errorf("audit queue is full, drop: %s %s %s:%s", entry.ActorId, action, targetType, targetId)
+	}
+}
+
+// Actor 从请求上下文中取出操作人的类型、ID 和名称
+func Actor(c *gin.Context) (string, string, string) {
+	if v, ok := c.Get("claims"); ok {
+		if claims, ok := v.(*types.AuthClaims); ok {
+			return "user", fmt.Sprintf("%d", claims.ID), claims.Name
+		}
+	}
+	if v, ok := c.Get("api_key"); ok {
+		if k, ok := v.(*model.ApiKey); ok {
+			return "api_key", fmt.Sprintf("%d", k.ID), k.Name
+		}
+	}
+	if v, ok := c.Get("sign_client"); ok {
+		return "device", fmt.Sprintf("%v", v), ""
+	}
+	return "anonymous", "", ""
+}
+
+func toJson(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+func loop() {
+	defer wg.Done()
+	t := time.NewTicker(flushInterval)
+	defer t.Stop()
+	buf := make([]*model.AuditLog, 0, batchSize)
+	flush := func() {
+		if len(buf) == 0 {
+			return
+		}
+		if err := model.MulCreateAuditLog(buf); err != nil {
+			log.Log.WithAlarm().Error("write audit log err:", err)
+		}
+		buf = make([]*model.AuditLog, 0, batchSize)
+	}
+	for {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				flush()
+				return
+			}
+			buf = append(buf, entry)
+			if len(buf) >= batchSize {
+				flush()
+			}
+		case <-t.C:
+			flush()
+		}
+	}
+}
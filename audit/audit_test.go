@@ -0,0 +1,36 @@
+package audit
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"tool-attendance/model"
+	"tool-attendance/types"
+)
+
+func TestActor(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if typ, _, _ := Actor(c); typ != "anonymous" {
+		t.Errorf("got %s, want anonymous", typ)
+	}
+
+	c.Set("api_key", &model.ApiKey{ID: 3, Name: "payroll"})
+	if typ, id, name := Actor(c); typ != "api_key" || id != "3" || name != "payroll" {
+		t.Errorf("got %s %s %s", typ, id, name)
+	}
+
+	c.Set("claims", &types.AuthClaims{ID: 7, Name: "hr"})
+	if typ, id, name := Actor(c); typ != "user" || id != "7" || name != "hr" {
+		t.Errorf("got %s %s %s", typ, id, name)
+	}
+}
+
+func TestToJson(t *testing.T) {
+	if s := toJson(nil); s != "" {
+		t.Errorf("got %q", s)
+	}
+	if s := toJson(map[string]int{"year": 2023}); s != `{"year":2023}` {
+		t.Errorf("got %q", s)
+	}
+}
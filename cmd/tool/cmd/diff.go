@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"tool-attendance/config"
+	"tool-attendance/log"
+	"tool-attendance/model"
+	"tool-attendance/report"
+	"tool-attendance/utils/i18n"
+	"tool-attendance/utils/tz"
+)
+
+var diffFlags struct {
+	config string
+	base   string
+	target string
+	rules  string
+	stored bool
+	out    string
+	lang   string
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "compare two attendance computations",
+	Long: `usage example:
+	server(.exe) diff -c config.json --base 2023-05 --target 2023-06
+	server(.exe) diff -c config.json --base 2023-05 --rules rules.json -o diff.xlsx
+	server(.exe) diff -c config.json --base 2023-05 --stored
+	compare two months, or a month's current result with a recomputation under the given rules
+	(omitted fields keep the current rules; without --target, --rules and --stored, recompute with the current rules).
+	--rules previews a change before editing the config. --stored shows what moved after the rules were edited
+	or the calendar was refreshed: the base is the rules and calendar the month was last computed with
+	(the close snapshot for closed months), the target uses the current rules and calendar`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDiff()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	flags := diffCmd.Flags()
+	flags.StringVarP(&diffFlags.config, "config", "c", "", "api config file (required)")
+	flags.StringVar(&diffFlags.base, "base", "", "base month, 2006-01 (required)")
+	flags.StringVar(&diffFlags.target, "target", "", "target month, 2006-01")
+	flags.StringVar(&diffFlags.rules, "rules", "", "rules json file for recomputing the base month")
+	flags.BoolVar(&diffFlags.stored, "stored", false, "compare the stored rules and calendar of the base month with the current ones")
+	flags.StringVarP(&diffFlags.out, "out", "o", "", "write a highlighted xlsx to this file instead of json to stdout")
+	flags.StringVar(&diffFlags.lang, "lang", i18n.ZhCN, "workbook language")
+	diffCmd.MarkFlagRequired("config")
+	diffCmd.MarkFlagRequired("base")
+}
+
+func runDiff() error {
+	if diffFlags.target != "" && diffFlags.rules != "" || diffFlags.stored && (diffFlags.target != "" || diffFlags.rules != "") {
+		return errors.New("--target, --rules and --stored are mutually exclusive")
+	}
+	base, err := time.Parse("2006-01", diffFlags.base)
+	if err != nil {
+		return fmt.Errorf("invalid --base: %w", err)
+	}
+	cfg, err := config.Init(&diffFlags.config)
+	if err != nil {
+		return err
+	}
+	if err = tz.Init(cfg.App.TimeZone); err != nil {
+		return err
+	}
+	if err = log.Init(&cfg.Logger); err != nil {
+		return err
+	}
+	if err = model.Init(&cfg.Mysql); err != nil {
+		return err
+	}
+
+	var res *report.DiffResult
+	if diffFlags.target != "" {
+		target, err := time.Parse("2006-01", diffFlags.target)
+		if err != nil {
+			return fmt.Errorf("invalid --target: %w", err)
+		}
+		res, err = report.DiffPeriods(base.Year(), int(base.Month()), target.Year(), int(target.Month()))
+		if err != nil {
+			return err
+		}
+	} else if diffFlags.stored {
+		if res, err = report.DiffStored(base.Year(), int(base.Month())); err != nil {
+			return err
+		}
+	} else {
+		var data []byte
+		if diffFlags.rules != "" {
+			if data, err = ioutil.ReadFile(diffFlags.rules); err != nil {
+				return err
+			}
+		}
+		rules, err := report.RulesFromJSON(data)
+		if err != nil {
+			return fmt.Errorf("invalid rules: %w", err)
+		}
+		if res, err = report.DiffRules(base.Year(), int(base.Month()), rules); err != nil {
+			return err
+		}
+	}
+
+	if diffFlags.out == "" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	}
+	f, err := report.DiffExcel(res, diffFlags.lang)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.SaveAs(diffFlags.out)
+}
@@ -12,11 +12,19 @@ import (
 	"syscall"
 	"time"
 
+	"tool-attendance/audit"
 	"tool-attendance/config"
 	"tool-attendance/log"
 	"tool-attendance/model"
+	"tool-attendance/report"
 	"tool-attendance/router"
+	"tool-attendance/scheduler"
+	"tool-attendance/utils/aws_s3"
+	"tool-attendance/utils/mailer"
+	"tool-attendance/utils/notifier"
+	"tool-attendance/utils/tz"
 	"tool-attendance/utils/wrapper"
+	"tool-attendance/webhook"
 )
 
 type Application struct {
@@ -24,10 +32,14 @@ type Application struct {
 	ginEngine  *gin.Engine
 	httpServer *http.Server
 	cron       *cron.Cron
+	alarmHook  *notifier.AlarmHook
 }
 
 var cfgFile *string
 
+// 审计日志缓冲队列长度
+const auditQueueSize = 1024
+
 var startCmd = &cobra.Command{
 	Use:   "start",
 	Short: "start the api",
@@ -53,6 +65,9 @@ func (app *Application) Init(env svc.Environment) error {
 	if err != nil {
 		return err
 	}
+	if err = tz.Init(cfg.App.TimeZone); err != nil {
+		return err
+	}
 	err = log.Init(&cfg.Logger)
 	if err != nil {
 		return err
@@ -61,6 +76,36 @@ func (app *Application) Init(env svc.Environment) error {
 	if err = model.Init(&cfg.Mysql); err != nil {
 		return err
 	}
+	if err = model.Migrate(); err != nil {
+		return err
+	}
+	if cfg.S3.Driver == aws_s3.DriverLocal || cfg.S3.Bucket != "" {
+		if err = aws_s3.InitWithConfig(&cfg.S3); err != nil {
+			return err
+		}
+	}
+	mailer.Init(cfg.Mail)
+	if err = notifier.Init(cfg.Notify); err != nil {
+		return err
+	}
+	if notifier.Enabled(notifier.EventAlarm) {
+		hostName, _ := os.Hostname()
+		app.alarmHook = notifier.NewAlarmHook(hostName)
+		log.Log.AddHook(app.alarmHook)
+	}
+	audit.Init(auditQueueSize)
+	if err = report.CheckPayrollConfig(cfg.Payroll); err != nil {
+		return err
+	}
+	if err = report.InitJobs(cfg.Report); err != nil {
+		return err
+	}
+	report.InitSummary()
+	webhook.Init(cfg.Webhook)
+	app.cron = cron.New(cron.WithLocation(tz.Default()))
+	if err = scheduler.Init(app.cron, cfg.Cron); err != nil {
+		return err
+	}
 
 	// http
 	app.ginEngine = router.InitAiRouter(&cfg)
@@ -86,6 +131,7 @@ func (app *Application) Start() error {
 		}
 	})
 	fmt.Println("start end")
+	app.cron.Start()
 
 	// 服务内存和cpu使用监控
 	go func() {
@@ -108,6 +154,17 @@ func (app *Application) Stop() error {
 		fmt.Println("http shutdown")
 	}
 	app.wrapper.Wait()
+	if app.cron != nil {
+		<-app.cron.Stop().Done()
+	}
+	scheduler.Stop()
+	report.StopJobs()
+	report.StopSummary()
+	webhook.Stop()
+	audit.Stop()
+	if app.alarmHook != nil {
+		app.alarmHook.Close()
+	}
 	fmt.Println("done end")
 	return nil
 }
@@ -14,11 +14,23 @@ var (
 
 type (
 	Configuration struct {
-		App    AppConfig    `json:"app"`
-		Server ServerConfig `json:"server"`
-		Mysql  MysqlConfig  `json:"mysql"`
-		Logger LoggerConfig `json:"logger"`
-		//S3     S3Config     `json:"s3"`
+		App     AppConfig     `json:"app"`
+		Server  ServerConfig  `json:"server"`
+		Mysql   MysqlConfig   `json:"mysql"`
+		Logger  LoggerConfig  `json:"logger"`
+		Sign    SignConfig    `json:"sign"`
+		Report  ReportConfig  `json:"report"`
+		Cron    CronConfig    `json:"cron"`
+		Rules   RulesConfig   `json:"rules"`
+		Mail    MailConfig    `json:"mail"`
+		Notify  NotifyConfig  `json:"notify"`
+		Remind  RemindConfig  `json:"remind"`
+		Webhook WebhookConfig `json:"webhook"`
+		Close   CloseConfig   `json:"close"`
+		Payroll PayrollConfig `json:"payroll"`
+		Anomaly AnomalyConfig `json:"anomaly"`
+		Leave   LeaveConfig   `json:"leave"`
+		S3      S3Config      `json:"s3"`
 		//Redis           RedisConfig              `json:"redis"`
 		//RabbitMqConfig  RabbitMqConfig           `json:"rabbitMq"`
 		//Elastic         ElasticConfig            `json:"elastic"`
@@ -59,15 +71,161 @@ type (
 	}
 
 	S3Config struct {
-		AccessKey string `json:"access_key"`
-		SecretKey string `json:"secret_key"`
-		Bucket    string `json:"bucket"`
-		BaseUrl   string `json:"base_url"`
+		Driver        string        `json:"driver" default:"s3"`                   // 存储后端：s3 / local
+		LocalDir      string        `json:"local_dir" default:"./runtime/storage"` // driver 为 local 时文件存放目录
+		AccessKey     string        `json:"access_key"`
+		SecretKey     string        `json:"secret_key"`
+		Bucket        string        `json:"bucket"` // 为空时不启用对象存储
+		BaseUrl       string        `json:"base_url"`
+		Endpoint      string        `json:"endpoint"` // 自定义服务地址，如 MinIO：http://127.0.0.1:9000，为空时使用 AWS
+		Region        string        `json:"region" default:"us-east-2"`
+		PathStyle     bool          `json:"path_style"`                  // 使用 path-style 地址（bucket 放在路径中），MinIO 需要开启
+		PresignExpire time.Duration `json:"presign_expire" default:"15"` // 预签名下载链接默认有效期（分钟）
 	}
 
 	AppConfig struct {
-		Secret string `json:"secret" default:"secret."`
-		Env    string `json:"env" default:""`
+		Secret   string `json:"secret" default:"secret."`
+		Env      string `json:"env" default:""`
+		TimeZone string `json:"time_zone" default:"Asia/Shanghai"` // 默认时区（IANA 时区名），员工和工作地点未设置时区时使用
+		BaseUrl  string `json:"base_url"`                          // 对外访问地址，用于生成回调和通知中的链接
+	}
+
+	// ReportConfig 异步报表任务配置
+	ReportConfig struct {
+		Dir            string        `json:"dir" default:"./runtime/reports"` // 报表文件存放目录
+		Workers        int           `json:"workers" default:"2"`             // 并发生成报表的协程数
+		QueueSize      int           `json:"queue_size" default:"100"`        // 等待生成的任务数上限，超过时拒绝新任务
+		CallbackHosts  []string      `json:"callback_hosts"`                  // 允许回调的主机，为空时允许任意公网地址；列出的主机可以是内网地址
+		RetentionHours time.Duration `json:"retention_hours" default:"72"`    // 报表文件保留时长（小时）
+		MailTo         []string      `json:"mail_to"`                         // 月度报表邮件的收件人（管理者）
+	}
+
+	// MailConfig 发信配置，host 为空时不发送邮件
+	MailConfig struct {
+		Host     string        `json:"host"`
+		Port     int           `json:"port" default:"465"`
+		Username string        `json:"username"`
+		Password string        `json:"password"`
+		From     string        `json:"from"`                   // 发件人，如：考勤 <hr@example.com>
+		Security string        `json:"security" default:"tls"` // tls：SMTPS（465）；starttls：连接后升级为 TLS（587）；none：不加密，仅用于本地测试
+		Timeout  time.Duration `json:"timeout" default:"30"`   // 单封邮件的发送超时（秒）
+	}
+
+	// RulesConfig 考勤规则，时间按员工所在时区计算
+	RulesConfig struct {
+		OnWorkTime  string         `json:"on_work_time" default:"09:30"`  // 上班时间，之后打卡算迟到
+		OffWorkTime string         `json:"off_work_time" default:"18:00"` // 下班时间，之前打卡算早退
+		MinHours    float64        `json:"min_hours" default:"9"`         // 上下班打卡间隔不足该小时数算时长不足
+		Tiers       []LatenessTier `json:"tiers"`                         // 迟到、早退按分钟数分档，按 max_minutes 从小到大匹配
+	}
+
+	// LatenessTier 迟到、早退的分档，max_minutes 为 0 表示不设上限
+	LatenessTier struct {
+		MaxMinutes    int    `json:"max_minutes"`
+		Name          string `json:"name"`            // 档位名称，如 ≤10min
+		HalfDayAbsent bool   `json:"half_day_absent"` // 落在该档时按半天旷工处理
+	}
+
+	// CronConfig 定时任务配置，未配置的任务不会执行
+	CronConfig struct {
+		Jobs []CronJobConfig `json:"jobs"`
+	}
+
+	// CronJobConfig 单个定时任务，spec 为标准 5 位 cron 表达式（分 时 日 月 周），按 app.time_zone 解析
+	CronJobConfig struct {
+		Name    string `json:"name"`
+		Spec    string `json:"spec"`
+		Disable bool   `json:"disable"`
+	}
+
+	// NotifyConfig 聊天群机器人通知配置
+	NotifyConfig struct {
+		Channels []NotifyChannel `json:"channels"`
+	}
+
+	NotifyChannel struct {
+		Type    string   `json:"type"`    // dingtalk / feishu / slack
+		Webhook string   `json:"webhook"` // 机器人 webhook 地址
+		Secret  string   `json:"secret"`  // 钉钉、飞书开启签名校验时的密钥
+		Events  []string `json:"events"`  // 接收的消息类型：alarm / digest / reminder，为空时接收全部
+	}
+
+	// RemindConfig 漏打卡提醒配置，免打扰时段按员工所在时区计算，可跨零点（如 22:00 ~ 08:00）
+	RemindConfig struct {
+		QuietStart    string `json:"quiet_start" default:"22:00"`
+		QuietEnd      string `json:"quiet_end" default:"08:00"`
+		CorrectionUrl string `json:"correction_url"` // 补卡申请地址，支持 {user_id} 和 {date} 占位符，为空时使用 app.base_url/correction
+	}
+
+	// WebhookConfig 事件推送配置，第 n 次失败后等待 backoff * 2^(n-1) 秒重试，最长 max_backoff 秒
+	WebhookConfig struct {
+		Workers     int           `json:"workers" default:"2"`        // 并发推送的协程数
+		QueueSize   int           `json:"queue_size" default:"100"`   // 等待推送的任务数上限，超过的留给重试扫描
+		MaxAttempts int           `json:"max_attempts" default:"6"`   // 最多推送次数，用尽后标记为失败
+		Backoff     time.Duration `json:"backoff" default:"30"`       // 首次重试的等待时长（秒）
+		MaxBackoff  time.Duration `json:"max_backoff" default:"3600"` // 重试等待时长上限（秒）
+		Timeout     time.Duration `json:"timeout" default:"10"`       // 单次推送的超时（秒）
+	}
+
+	// CloseConfig 月度关账配置
+	CloseConfig struct {
+		ReopenAdmins []int64 `json:"reopen_admins"` // 允许重新开放已关账月份的管理员 id
+	}
+
+	// PayrollConfig 薪资扣款导出配置
+	PayrollConfig struct {
+		Columns   []PayrollColumn `json:"columns"`                 // 导出的列及顺序，为空时导出全部列
+		Rounding  string          `json:"rounding" default:"none"` // 每天迟到、早退分钟数的取整方式：none / up / down / nearest
+		RoundUnit int             `json:"round_unit" default:"1"`  // 取整单位（分钟），如 15 表示按 15 分钟取整
+		Tiers     []PenaltyTier   `json:"tiers"`                   // 按迟到、早退分钟数分档扣款，按 max_minutes 从小到大匹配
+		Decimals  int             `json:"decimals" default:"2"`    // 金额保留的小数位数
+	}
+
+	PayrollColumn struct {
+		Field string `json:"field"` // 字段名，见 report.PayrollFields
+		Title string `json:"title"` // 表头，为空时使用默认表头
+	}
+
+	// PenaltyTier 单次迟到或早退的扣款档位，max_minutes 为 0 表示不设上限
+	PenaltyTier struct {
+		MaxMinutes int     `json:"max_minutes"`
+		Amount     float64 `json:"amount"`
+	}
+
+	// AnomalyConfig 异常打卡检测配置
+	AnomalyConfig struct {
+		MaxHours     float64 `json:"max_hours" default:"16"`      // 上下班打卡间隔超过该小时数视为异常
+		ShiftMinutes int     `json:"shift_minutes" default:"180"` // 上班打卡时间偏离近期中位数超过该分钟数视为作息突变
+		ShiftWindow  int     `json:"shift_window" default:"20"`   // 近期中位数取之前多少次上班打卡
+		ShiftMinDays int     `json:"shift_min_days" default:"5"`  // 之前的上班打卡不足该次数时不检测作息突变
+	}
+
+	// LeaveConfig 年假配置，司龄按员工入职日期计算，未填写入职日期的员工不发放
+	LeaveConfig struct {
+		Accrual         string      `json:"accrual" default:"yearly"`      // yearly：每年 1 月 1 日发放全年额度；monthly：每月 1 日发放当月额度
+		Tiers           []LeaveTier `json:"tiers"`                         // 按司龄分档的全年额度，为空时满 1 年 5 天、满 10 年 10 天、满 20 年 15 天
+		CarryOverMax    float64     `json:"carry_over_max" default:"5"`    // 年末未休的年假最多结转到次年的天数，0 表示不结转
+		CarryOverMonths int         `json:"carry_over_months" default:"3"` // 结转的年假在次年前几个月内有效，0 表示次年内有效
+
+		Approvers []int64 `json:"approvers"` // 允许审批、驳回、撤销请假和调整年假额度的管理员 id，不能审批自己的申请
+	}
+
+	// LeaveTier 司龄满 min_years 年时的全年额度
+	LeaveTier struct {
+		MinYears int     `json:"min_years"`
+		Days     float64 `json:"days"`
+	}
+
+	// SignConfig 设备请求签名配置
+	SignConfig struct {
+		Window  int64        `json:"window" default:"300"`       // 时间戳允许的误差（秒）
+		MaxBody int64        `json:"max_body" default:"1048576"` // 请求体的最大字节数
+		Clients []SignClient `json:"clients"`
+	}
+
+	SignClient struct {
+		ClientId string `json:"client_id"`
+		Secret   string `json:"secret"`
 	}
 
 	RedisConfig struct {
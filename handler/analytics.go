@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tool-attendance/report"
+	"tool-attendance/utils/render"
+	"tool-attendance/utils/tz"
+)
+
+// 单次统计的最大日期跨度，按天统计时为 maxSummaryDays
+const maxTrendDays = 731
+
+type reqTrend struct {
+	Begin       string `form:"begin" binding:"required"` // 2006-01-02
+	End         string `form:"end" binding:"required"`   // 2006-01-02
+	Granularity string `form:"granularity" binding:"required,oneof=day week month"`
+	Team        string `form:"team"`                                            // 为空时返回所有团队及合计
+	Compare     string `form:"compare" binding:"omitempty,oneof=previous year"` // previous：上一个同长度区间；year：去年同期
+}
+
+// AttendanceTrend 各团队的出勤率、平均上班时间、平均时长和异常次数的时间序列
+func AttendanceTrend(c *gin.Context) {
+	var req reqTrend
+	if err := c.ShouldBindQuery(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	begin, err1 := time.ParseInLocation(formatDayTime, req.Begin, tz.Default())
+	end, err2 := time.ParseInLocation(formatDayTime, req.End, tz.Default())
+	maxDays := maxTrendDays
+	if req.Granularity == report.GranularityDay {
+		maxDays = maxSummaryDays
+	}
+	if err1 != nil || err2 != nil || end.Before(begin) || end.Sub(begin) > time.Duration(maxDays)*24*time.Hour {
+		render.Json(c, render.ErrParams, "invalid begin or end")
+		return
+	}
+	res, err := report.Trend(begin, end, req.Granularity, req.Team, req.Compare)
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	render.Json(c, render.Ok, res)
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tool-attendance/config"
+	"tool-attendance/report"
+	"tool-attendance/utils/render"
+	"tool-attendance/utils/tz"
+)
+
+type reqAnomalyList struct {
+	Begin  string `form:"begin" binding:"required"` // 2006-01-02
+	End    string `form:"end" binding:"required"`   // 2006-01-02
+	UserId string `form:"user_id"`
+	Kind   string `form:"kind" binding:"omitempty,oneof=off_before_on too_long out_of_service same_timestamp pattern_shift"`
+}
+
+// AnomalyList 异常打卡记录，可按用户和异常类型筛选
+func AnomalyList(c *gin.Context) {
+	var req reqAnomalyList
+	if err := c.ShouldBindQuery(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	begin, err1 := time.ParseInLocation(formatDayTime, req.Begin, tz.Default())
+	end, err2 := time.ParseInLocation(formatDayTime, req.End, tz.Default())
+	if err1 != nil || err2 != nil || end.Before(begin) || end.Sub(begin) > maxSummaryDays*24*time.Hour {
+		render.Json(c, render.ErrParams, "invalid begin or end")
+		return
+	}
+	list, err := report.Anomalies(begin, end, config.GetConfig().Anomaly)
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	res := make([]report.Anomaly, 0, len(list))
+	for _, v := range list {
+		if (req.UserId == "" || v.UserId == req.UserId) && (req.Kind == "" || v.Kind == req.Kind) {
+			res = append(res, v)
+		}
+	}
+	render.Json(c, render.Ok, res)
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tool-attendance/audit"
+	"tool-attendance/model"
+	"tool-attendance/types"
+	"tool-attendance/utils"
+	"tool-attendance/utils/apikey"
+	"tool-attendance/utils/render"
+)
+
+type reqCreateApiKey struct {
+	Name       string   `json:"name" binding:"required,max=64"`
+	Scopes     []string `json:"scopes" binding:"required,min=1"`
+	ExpireDays int      `json:"expire_days" binding:"gte=0"` // 0：永不过期
+}
+
+type resCreateApiKey struct {
+	model.ApiKey
+	Key string `json:"key"` // 明文 key，仅在创建时返回一次
+}
+
+func CreateApiKey(c *gin.Context) {
+	var req reqCreateApiKey
+	if err := c.ShouldBindJSON(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	for _, s := range req.Scopes {
+		if !utils.InSliceString(model.ApiKeyScopes, s) {
+			render.Json(c, render.ErrParams, "unknown scope: "+s)
+			return
+		}
+	}
+
+	plain, prefix, hash, err := apikey.Generate()
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	k := model.ApiKey{
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    strings.Join(req.Scopes, ","),
+		CreatedAt: time.Now(),
+	}
+	if req.ExpireDays > 0 {
+		expiredAt := k.CreatedAt.AddDate(0, 0, req.ExpireDays)
+		k.ExpiredAt = &expiredAt
+	}
+	if claims := getClaims(c); claims != nil {
+		k.CreatedBy = claims.ID
+	}
+	if err = model.CreateApiKey(&k); err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	audit.Record(c, audit.ActionApiKeyCreate, "api_key", strconv.FormatInt(k.ID, 10), nil, k)
+	render.Json(c, render.Ok, resCreateApiKey{ApiKey: k, Key: plain})
+}
+
+func ApiKeyList(c *gin.Context) {
+	var req types.ReqPage
+	if err := c.ShouldBindQuery(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	list, total, err := model.FindApiKeyList(req.Page, req.Limit)
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	render.Json(c, render.Ok, types.PageResult{
+		Page:  req.Page,
+		Limit: req.Limit,
+		Items: list,
+		Total: total,
+	})
+}
+
+type reqApiKeyId struct {
+	ID int64 `uri:"id" binding:"required"`
+}
+
+func RevokeApiKey(c *gin.Context) {
+	var req reqApiKeyId
+	if err := c.ShouldBindUri(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	n, err := model.RevokeApiKey(req.ID, time.Now())
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	if n == 0 {
+		render.Json(c, render.NotFound, nil)
+		return
+	}
+	audit.Record(c, audit.ActionApiKeyRevoke, "api_key", strconv.FormatInt(req.ID, 10), nil, nil)
+	render.Json(c, render.Ok, nil)
+}
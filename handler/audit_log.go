@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tool-attendance/model"
+	"tool-attendance/types"
+	"tool-attendance/utils/render"
+)
+
+type reqAuditLogList struct {
+	types.ReqPage
+	ActorId    string `form:"actor_id"`
+	Action     string `form:"action"`
+	TargetType string `form:"target_type"`
+	TargetId   string `form:"target_id"`
+	BeginTime  int64  `form:"begin_time"` // 秒级时间戳
+	EndTime    int64  `form:"end_time"`   // 秒级时间戳
+}
+
+func AuditLogList(c *gin.Context) {
+	var req reqAuditLogList
+	if err := c.ShouldBindQuery(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	filter := model.AuditLogFilter{
+		ActorId:    req.ActorId,
+		Action:     req.Action,
+		TargetType: req.TargetType,
+		TargetId:   req.TargetId,
+	}
+	if req.BeginTime > 0 {
+		filter.BeginTime = time.Unix(req.BeginTime, 0)
+	}
+	if req.EndTime > 0 {
+		filter.EndTime = time.Unix(req.EndTime, 0)
+	}
+	list, total, err := model.FindAuditLogList(filter, req.Page, req.Limit)
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	render.Json(c, render.Ok, types.PageResult{
+		Page:  req.Page,
+		Limit: req.Limit,
+		Items: list,
+		Total: total,
+	})
+}
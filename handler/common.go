@@ -1,10 +1,43 @@
 package handler
 
 import (
+	"errors"
+
 	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+	"tool-attendance/types"
 	"tool-attendance/utils/render"
 )
 
+const formatDayTime = "2006-01-02"
+
 func Pong(c *gin.Context) {
 	render.Json(c, render.Ok, "pong!")
 }
+
+// getClaims 获取管理后台 token 中的用户信息，API Key 访问时返回 nil
+func getClaims(c *gin.Context) *types.AuthClaims {
+	v, ok := c.Get("claims")
+	if !ok {
+		return nil
+	}
+	claims, _ := v.(*types.AuthClaims)
+	return claims
+}
+
+// isNotFound 判断是否为记录不存在
+func isNotFound(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+// renderExcel 以附件形式返回 excel 文件
+func renderExcel(c *gin.Context, f *excelize.File, fileName string) {
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", "attachment; filename="+fileName)
+	c.Header("Content-Transfer-Encoding", "binary")
+	c.Header("Cache-Control", "no-cache")
+	if err := f.Write(c.Writer); err != nil {
+		render.Json(c, render.Failed, err.Error())
+	}
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"tool-attendance/audit"
+	"tool-attendance/model"
+	"tool-attendance/scheduler"
+	"tool-attendance/types"
+	"tool-attendance/utils/render"
+)
+
+func CronJobList(c *gin.Context) {
+	render.Json(c, render.Ok, scheduler.Jobs())
+}
+
+type reqRunCronJob struct {
+	Name string `uri:"name" binding:"required"`
+}
+
+// RunCronJob 手动触发定时任务，任务在后台执行，通过执行记录查看结果
+func RunCronJob(c *gin.Context) {
+	var req reqRunCronJob
+	if err := c.ShouldBindUri(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	actorType, actorId, _ := audit.Actor(c)
+	l, err := scheduler.Run(req.Name, actorType+":"+actorId)
+	if errors.Is(err, scheduler.ErrUnknownJob) {
+		render.Json(c, render.NotFound, err.Error())
+		return
+	}
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	audit.Record(c, audit.ActionCronRun, "cron_job", req.Name, nil, l)
+	render.Json(c, render.Ok, l)
+}
+
+type reqCronJobLogList struct {
+	types.ReqPage
+	Name string `form:"name"`
+}
+
+func CronJobLogList(c *gin.Context) {
+	var req reqCronJobLogList
+	if err := c.ShouldBindQuery(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	list, total, err := model.FindCronJobLogList(req.Name, req.Page, req.Limit)
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	render.Json(c, render.Ok, types.PageResult{
+		Page:  req.Page,
+		Limit: req.Limit,
+		Items: list,
+		Total: total,
+	})
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tool-attendance/audit"
+	"tool-attendance/model"
+	"tool-attendance/report"
+	"tool-attendance/types"
+	"tool-attendance/utils/render"
+	"tool-attendance/utils/tz"
+)
+
+// 单次查询的最大日期跨度
+const maxSummaryDays = 92
+
+type reqDailySummaryList struct {
+	types.ReqPage
+	UserId string `form:"user_id"`
+	Begin  string `form:"begin" binding:"required"` // 2006-01-02
+	End    string `form:"end" binding:"required"`   // 2006-01-02
+}
+
+// DailySummaryList 每日考勤汇总，只包含有打卡记录的日期
+func DailySummaryList(c *gin.Context) {
+	var req reqDailySummaryList
+	if err := c.ShouldBindQuery(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	begin, err1 := time.ParseInLocation(formatDayTime, req.Begin, tz.Default())
+	end, err2 := time.ParseInLocation(formatDayTime, req.End, tz.Default())
+	if err1 != nil || err2 != nil || end.Before(begin) || end.Sub(begin) > maxSummaryDays*24*time.Hour {
+		render.Json(c, render.ErrParams, "invalid begin or end")
+		return
+	}
+	list, total, err := model.FindDailySummaryPage(req.UserId, begin, end, req.Page, req.Limit)
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	render.Json(c, render.Ok, types.PageResult{
+		Page:  req.Page,
+		Limit: req.Limit,
+		Items: list,
+		Total: total,
+	})
+}
+
+// RebuildDailySummary 按当前规则重新计算整月的汇总，已关账的月份不受影响
+func RebuildDailySummary(c *gin.Context) {
+	var req reqYearMonth
+	if err := c.ShouldBindUri(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	err := report.CheckMonthOpen(time.Date(req.Year, time.Month(req.Month), 1, 0, 0, 0, 0, tz.Default()))
+	if errors.Is(err, report.ErrMonthClosed) {
+		render.Json(c, render.RepetitiveOperation, err.Error())
+		return
+	}
+	if err == nil {
+		err = report.RebuildSummary(req.Year, req.Month)
+	}
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	audit.Record(c, audit.ActionSummaryRebuild, "daily_summary", fmt.Sprintf("%d%02d", req.Year, req.Month), nil, nil)
+	render.Json(c, render.Ok, nil)
+}
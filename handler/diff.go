@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"tool-attendance/audit"
+	"tool-attendance/report"
+	"tool-attendance/utils/i18n"
+	"tool-attendance/utils/render"
+)
+
+type reqDiff struct {
+	Year        int             `json:"year" binding:"required"`
+	Month       int             `json:"month" binding:"required,min=1,max=12"`
+	TargetYear  int             `json:"target_year"`                                   // 默认与 year 相同
+	TargetMonth int             `json:"target_month" binding:"omitempty,min=1,max=12"` // 填写时对比两个月份
+	Rules       json.RawMessage `json:"rules"`                                         // 不对比月份时按该规则重新计算，未填写的项沿用当前规则
+	Format      string          `json:"format" binding:"omitempty,oneof=json xlsx"`    // 默认 json
+
+	Stored bool `json:"stored"` // 不对比月份时，对比之前保存的规则和日历与当前的结果，用于修改规则或刷新日历后
+}
+
+// AttendanceDiff 对比两个月份的考勤结果，某月现有结果与按新规则重新计算的结果（修改规则配置前预览），
+// 或按之前保存的规则和日历与当前规则和日历计算的结果（修改规则或刷新日历后查看变化），见 report.DiffStored
+func AttendanceDiff(c *gin.Context) {
+	var req reqDiff
+	if err := c.ShouldBindJSON(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+
+	var (
+		res *report.DiffResult
+		err error
+	)
+	if req.TargetMonth > 0 {
+		if req.TargetYear == 0 {
+			req.TargetYear = req.Year
+		}
+		res, err = report.DiffPeriods(req.Year, req.Month, req.TargetYear, req.TargetMonth)
+	} else if req.Stored {
+		res, err = report.DiffStored(req.Year, req.Month)
+	} else {
+		rules, e := report.RulesFromJSON(req.Rules)
+		if e != nil {
+			render.Json(c, render.ErrParams, "invalid rules: "+e.Error())
+			return
+		}
+		res, err = report.DiffRules(req.Year, req.Month, rules)
+	}
+	if errors.Is(err, report.ErrNoStoredVersion) {
+		render.Json(c, render.NotFound, err.Error())
+		return
+	}
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	if req.Format != "xlsx" {
+		render.Json(c, render.Ok, res)
+		return
+	}
+
+	f, err := report.DiffExcel(res, i18n.FromContext(c, i18n.ZhCN))
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	defer f.Close()
+	audit.Record(c, audit.ActionReportExport, "report", fmt.Sprintf("diff:%d%02d", req.Year, req.Month), nil, req)
+	renderExcel(c, f, fmt.Sprintf("diff_%d%02d.xlsx", req.Year, req.Month))
+}
@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tool-attendance/audit"
+	"tool-attendance/log"
+	"tool-attendance/model"
+	"tool-attendance/report"
+	"tool-attendance/types"
+	"tool-attendance/utils/render"
+	"tool-attendance/utils/tz"
+)
+
+func EmployeeList(c *gin.Context) {
+	var req types.ReqPage
+	if err := c.ShouldBindQuery(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	list, total, err := model.FindEmployeeList(req.Page, req.Limit)
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	render.Json(c, render.Ok, types.PageResult{
+		Page:  req.Page,
+		Limit: req.Limit,
+		Items: list,
+		Total: total,
+	})
+}
+
+type reqEmployeeId struct {
+	UserId string `uri:"user_id" binding:"required"`
+}
+
+type reqSaveEmployee struct {
+	Name       string `json:"name"`
+	Email      string `json:"email" binding:"omitempty,email"`
+	SiteId     int64  `json:"site_id"` // 修改后只影响新打卡的地点判定，见 model.PunchLog
+	Team       string `json:"team" binding:"max=64"`
+	TimeZone   string `json:"time_zone"`
+	HireDate   string `json:"hire_date"`   // 2006-01-02
+	ResignDate string `json:"resign_date"` // 2006-01-02
+}
+
+func SaveEmployee(c *gin.Context) {
+	var uri reqEmployeeId
+	if err := c.ShouldBindUri(&uri); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	var req reqSaveEmployee
+	if err := c.ShouldBindJSON(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	if _, err := tz.Load(req.TimeZone); err != nil {
+		render.Json(c, render.ErrParams, "invalid time_zone: "+err.Error())
+		return
+	}
+	if req.SiteId > 0 {
+		if _, err := model.FindSite(req.SiteId); err != nil {
+			render.Json(c, render.ErrParams, "invalid site_id")
+			return
+		}
+	}
+
+	e := model.Employee{UserId: uri.UserId, CreatedAt: time.Now()}
+	before, err := model.FindEmployee(uri.UserId)
+	if err != nil && !isNotFound(err) {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	if before != nil {
+		e = *before
+	}
+	e.Name = req.Name
+	e.Email = req.Email
+	e.SiteId = req.SiteId
+	e.Team = req.Team
+	e.TimeZone = req.TimeZone
+	e.HireDate = nil
+	if req.HireDate != "" {
+		hireDate, err := time.ParseInLocation(formatDayTime, req.HireDate, tz.Default())
+		if err != nil {
+			render.Json(c, render.ErrParams, "invalid hire_date")
+			return
+		}
+		e.HireDate = &hireDate
+	}
+	e.ResignDate = nil
+	if req.ResignDate != "" {
+		resignDate, err := time.ParseInLocation(formatDayTime, req.ResignDate, tz.Default())
+		if err != nil || (e.HireDate != nil && resignDate.Before(*e.HireDate)) {
+			render.Json(c, render.ErrParams, "invalid resign_date")
+			return
+		}
+		e.ResignDate = &resignDate
+	}
+	e.UpdatedAt = time.Now()
+	if err = model.SaveEmployee(&e); err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	if before == nil || before.TimeZone != e.TimeZone || before.SiteId != e.SiteId {
+		report.RebuildUserSummaryAsync(e.UserId)
+	}
+	audit.Record(c, audit.ActionEmployeeSave, "employee", e.UserId, before, e)
+	render.Json(c, render.Ok, e)
+}
+
+func SiteList(c *gin.Context) {
+	list, err := model.FindSiteList()
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	render.Json(c, render.Ok, list)
+}
+
+type reqSiteId struct {
+	ID int64 `uri:"id" binding:"required"`
+}
+
+type reqSaveSite struct {
+	Name     string `json:"name" binding:"required,max=64"`
+	TimeZone string `json:"time_zone"`
+
+	// 地理围栏，不配置时该地点的打卡都记为在办公室；修改后只影响新的打卡，已有打卡的地点不重新判定
+	Latitude  float64  `json:"latitude" binding:"min=-90,max=90"`
+	Longitude float64  `json:"longitude" binding:"min=-180,max=180"`
+	Radius    int      `json:"radius" binding:"min=0"` // 米
+	Bssids    []string `json:"bssids" binding:"max=32,dive,required,max=32"`
+	IpRanges  []string `json:"ip_ranges" binding:"max=32,dive,required,max=64"` // CIDR 或单个 IP
+}
+
+// check 校验时区和 IP 段
+func (req reqSaveSite) check() error {
+	if _, err := tz.Load(req.TimeZone); err != nil {
+		return fmt.Errorf("invalid time_zone: %w", err)
+	}
+	for _, v := range req.IpRanges {
+		if !report.ValidIpRange(v) {
+			return fmt.Errorf("invalid ip_ranges: %s", v)
+		}
+	}
+	return nil
+}
+
+// apply 将请求中的配置写入 s
+func (req reqSaveSite) apply(s *model.Site) {
+	s.Name = req.Name
+	s.TimeZone = req.TimeZone
+	s.Latitude = req.Latitude
+	s.Longitude = req.Longitude
+	s.Radius = req.Radius
+	s.Bssids = strings.Join(req.Bssids, ",")
+	s.IpRanges = strings.Join(req.IpRanges, ",")
+}
+
+func CreateSite(c *gin.Context) {
+	var req reqSaveSite
+	if err := c.ShouldBindJSON(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	if err := req.check(); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	s := model.Site{
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	req.apply(&s)
+	if err := model.SaveSite(&s); err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	audit.Record(c, audit.ActionSiteSave, "site", strconv.FormatInt(s.ID, 10), nil, s)
+	render.Json(c, render.Ok, s)
+}
+
+func UpdateSite(c *gin.Context) {
+	var uri reqSiteId
+	if err := c.ShouldBindUri(&uri); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	var req reqSaveSite
+	if err := c.ShouldBindJSON(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	if err := req.check(); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	before, err := model.FindSite(uri.ID)
+	if isNotFound(err) {
+		render.Json(c, render.NotFound, nil)
+		return
+	}
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	s := *before
+	req.apply(&s)
+	s.UpdatedAt = time.Now()
+	if err = model.SaveSite(&s); err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	if before.TimeZone != s.TimeZone {
+		rebuildSiteSummary(s.ID)
+	}
+	audit.Record(c, audit.ActionSiteSave, "site", strconv.FormatInt(s.ID, 10), before, s)
+	render.Json(c, render.Ok, s)
+}
+
+// rebuildSiteSummary 工作地点时区变化后重新计算未单独设置时区的员工的汇总
+func rebuildSiteSummary(siteId int64) {
+	list, err := model.FindEmployeeListBySite(siteId)
+	if err != nil {
+		log.Log.Error("find employees of site err:", err)
+		return
+	}
+	userIds := make([]string, 0, len(list))
+	for _, v := range list {
+		if v.TimeZone == "" {
+			userIds = append(userIds, v.UserId)
+		}
+	}
+	report.RebuildUserSummaryAsync(userIds...)
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tool-attendance/audit"
+	"tool-attendance/report"
+	"tool-attendance/utils/i18n"
+	"tool-attendance/utils/render"
+	"tool-attendance/utils/tz"
+)
+
+type reqHeatmap struct {
+	Year   int    `form:"year"`                                          // 默认今年
+	Slot   int    `form:"slot" binding:"omitempty,oneof=10 15 20 30 60"` // 时段分钟数，默认 30
+	Format string `form:"format" binding:"omitempty,oneof=json xlsx"`    // 默认 json
+}
+
+// AttendanceHeatmap 某月上下班打卡时间按星期和时段的分布
+func AttendanceHeatmap(c *gin.Context) {
+	var uri reqAttendanceDetail
+	if err := c.ShouldBindUri(&uri); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	var req reqHeatmap
+	if err := c.ShouldBindQuery(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	if req.Year == 0 {
+		req.Year = time.Now().In(tz.Default()).Year()
+	}
+	if req.Slot == 0 {
+		req.Slot = report.DefaultSlotMinutes
+	}
+
+	h, err := report.Heatmap(req.Year, uri.Month, req.Slot)
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	if req.Format != "xlsx" {
+		render.Json(c, render.Ok, h)
+		return
+	}
+
+	f, err := report.HeatmapExcel(h, i18n.FromContext(c, i18n.ZhCN))
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	defer f.Close()
+	audit.Record(c, audit.ActionReportExport, "report", fmt.Sprintf("heatmap:%d%02d", req.Year, uri.Month), nil, nil)
+	renderExcel(c, f, fmt.Sprintf("heatmap_%d%02d.xlsx", req.Year, uri.Month))
+}
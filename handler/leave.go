@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tool-attendance/audit"
+	"tool-attendance/config"
+	"tool-attendance/leave"
+	"tool-attendance/model"
+	"tool-attendance/report"
+	"tool-attendance/types"
+	"tool-attendance/utils/render"
+	"tool-attendance/utils/tz"
+)
+
+type reqSubmitLeave struct {
+	UserId    string `json:"user_id" binding:"required,max=64"`
+	Type      string `json:"type" binding:"required"`       // annual：年假；unpaid：事假；sick：病假；remote：远程办公
+	StartDate string `json:"start_date" binding:"required"` // 2006-01-02
+	EndDate   string `json:"end_date" binding:"required"`   // 2006-01-02
+	HalfDay   bool   `json:"half_day"`                      // 只请一天中的半天，开始和结束日期需相同
+	Reason    string `json:"reason" binding:"max=255"`
+}
+
+// SubmitLeave 提交请假申请，天数按日历中的工作日计算
+func SubmitLeave(c *gin.Context) {
+	var req reqSubmitLeave
+	if err := c.ShouldBindJSON(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	if !leave.IsValidType(req.Type) {
+		render.Json(c, render.ErrParams, "unknown type: "+req.Type)
+		return
+	}
+	start, err1 := time.ParseInLocation(formatDayTime, req.StartDate, tz.Default())
+	end, err2 := time.ParseInLocation(formatDayTime, req.EndDate, tz.Default())
+	if err1 != nil || err2 != nil {
+		render.Json(c, render.ErrParams, "invalid start_date or end_date")
+		return
+	}
+	r := model.LeaveRequest{
+		UserId:    req.UserId,
+		Type:      req.Type,
+		StartDate: start,
+		EndDate:   end,
+		HalfDay:   req.HalfDay,
+		Reason:    req.Reason,
+	}
+	actorType, actorId, _ := audit.Actor(c)
+	r.CreatedBy = actorType + ":" + actorId
+	err := leave.Submit(&r)
+	if errors.Is(err, leave.ErrInvalidPeriod) || errors.Is(err, leave.ErrNoWorkday) {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	if errors.Is(err, report.ErrMonthClosed) || errors.Is(err, leave.ErrOverlap) {
+		render.Json(c, render.RepetitiveOperation, err.Error())
+		return
+	}
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	audit.Record(c, audit.ActionLeaveSubmit, "leave_request", strconv.FormatInt(r.ID, 10), nil, r)
+	render.Json(c, render.Ok, r)
+}
+
+type reqLeaveList struct {
+	types.ReqPage
+	UserId string `form:"user_id"`
+	Status string `form:"status"`
+}
+
+func LeaveList(c *gin.Context) {
+	var req reqLeaveList
+	if err := c.ShouldBindQuery(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	list, total, err := model.FindLeaveRequestList(req.UserId, req.Status, req.Page, req.Limit)
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	render.Json(c, render.Ok, types.PageResult{
+		Page:  req.Page,
+		Limit: req.Limit,
+		Items: list,
+		Total: total,
+	})
+}
+
+type reqLeaveId struct {
+	ID int64 `uri:"id" binding:"required"`
+}
+
+// 请求体可以为空
+type reqReviewLeave struct {
+	Note string `json:"note" binding:"max=255"`
+}
+
+// 审批、驳回、撤销请假和调整年假额度只有 leave.approvers 中的管理员可以操作
+
+// ApproveLeave 审批通过，年假余额不足时不能通过，不能审批自己提交或自己的申请
+func ApproveLeave(c *gin.Context) {
+	reviewLeave(c, audit.ActionLeaveApprove, leave.Approve)
+}
+
+func RejectLeave(c *gin.Context) {
+	reviewLeave(c, audit.ActionLeaveReject, leave.Reject)
+}
+
+// CancelLeave 撤销待审批或已批准的申请，已扣减的年假退回余额
+func CancelLeave(c *gin.Context) {
+	reviewLeave(c, audit.ActionLeaveCancel, leave.Cancel)
+}
+
+func reviewLeave(c *gin.Context, action string, fn func(id int64, by, note string) (*model.LeaveRequest, error)) {
+	var uri reqLeaveId
+	if err := c.ShouldBindUri(&uri); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	var req reqReviewLeave
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			render.Json(c, render.ErrParams, err.Error())
+			return
+		}
+	}
+	if !canReviewLeave(c) {
+		render.Json(c, render.ErrForbidden, nil)
+		return
+	}
+	actorType, actorId, _ := audit.Actor(c)
+	before, _ := model.FindLeaveRequest(uri.ID)
+	if action == audit.ActionLeaveApprove && before != nil &&
+		(before.UserId == actorId || before.CreatedBy == actorType+":"+actorId) {
+		render.Json(c, render.ErrForbidden, "cannot approve your own leave request")
+		return
+	}
+	r, err := fn(uri.ID, actorType+":"+actorId, req.Note)
+	if isNotFound(err) {
+		render.Json(c, render.NotFound, nil)
+		return
+	}
+	if errors.Is(err, leave.ErrLeaveStatus) || errors.Is(err, report.ErrMonthClosed) {
+		render.Json(c, render.RepetitiveOperation, err.Error())
+		return
+	}
+	if errors.Is(err, leave.ErrInsufficientBalance) {
+		render.Json(c, render.TimesUsedUp, err.Error())
+		return
+	}
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	audit.Record(c, action, "leave_request", strconv.FormatInt(r.ID, 10), before, r)
+	render.Json(c, render.Ok, r)
+}
+
+type reqLeaveUser struct {
+	UserId string `uri:"user_id" binding:"required"`
+}
+
+// LeaveBalance 年假余额，按额度年份列出剩余天数
+func LeaveBalance(c *gin.Context) {
+	var req reqLeaveUser
+	if err := c.ShouldBindUri(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	res, err := leave.Balance(req.UserId, time.Now())
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	render.Json(c, render.Ok, res)
+}
+
+type reqLeaveLedgerList struct {
+	types.ReqPage
+	UserId string `form:"user_id" binding:"required"`
+	Year   int    `form:"year"`
+}
+
+// LeaveLedgerList 年假流水：发放、请假扣减、撤销退回、作废和人工调整
+func LeaveLedgerList(c *gin.Context) {
+	var req reqLeaveLedgerList
+	if err := c.ShouldBindQuery(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	list, total, err := model.FindLeaveLedgerList(req.UserId, req.Year, req.Page, req.Limit)
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	render.Json(c, render.Ok, types.PageResult{
+		Page:  req.Page,
+		Limit: req.Limit,
+		Items: list,
+		Total: total,
+	})
+}
+
+type reqAdjustLeave struct {
+	UserId string  `json:"user_id" binding:"required,max=64"`
+	Year   int     `json:"year" binding:"required"` // 调整的额度年份
+	Days   float64 `json:"days" binding:"required"` // 为负时扣减
+	Note   string  `json:"note" binding:"required,max=255"`
+}
+
+// AdjustLeave 人工调整年假额度，如导入历史余额
+func AdjustLeave(c *gin.Context) {
+	var req reqAdjustLeave
+	if err := c.ShouldBindJSON(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	if !canReviewLeave(c) {
+		render.Json(c, render.ErrForbidden, nil)
+		return
+	}
+	actorType, actorId, _ := audit.Actor(c)
+	l, err := leave.Adjust(req.UserId, req.Year, req.Days, req.Note, actorType+":"+actorId)
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	audit.Record(c, audit.ActionLeaveAdjust, "leave_ledger", req.UserId, nil, l)
+	render.Json(c, render.Ok, l)
+}
+
+func canReviewLeave(c *gin.Context) bool {
+	claims := getClaims(c)
+	if claims == nil {
+		return false
+	}
+	for _, id := range config.GetConfig().Leave.Approvers {
+		if id == claims.ID {
+			return true
+		}
+	}
+	return false
+}
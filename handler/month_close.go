@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tool-attendance/audit"
+	"tool-attendance/config"
+	"tool-attendance/model"
+	"tool-attendance/report"
+	"tool-attendance/utils/render"
+	"tool-attendance/utils/tz"
+)
+
+type reqMonthCloseList struct {
+	Year int `form:"year"` // 默认今年
+}
+
+func MonthCloseList(c *gin.Context) {
+	var req reqMonthCloseList
+	if err := c.ShouldBindQuery(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	if req.Year == 0 {
+		req.Year = time.Now().In(tz.Default()).Year()
+	}
+	list, err := model.FindMonthCloseList(req.Year)
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	render.Json(c, render.Ok, list)
+}
+
+type reqYearMonth struct {
+	Year  int `uri:"year" binding:"required,gte=2000"`
+	Month int `uri:"month" binding:"required,gte=1,lte=12"`
+}
+
+type resMonthStat struct {
+	Close *model.MonthClose `json:"close"` // 未关账过时为空
+	*report.MonthResult
+}
+
+// MonthStat 月度考勤结果，已关账的月份返回快照
+func MonthStat(c *gin.Context) {
+	var req reqYearMonth
+	if err := c.ShouldBindUri(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	m, err := model.FindMonthClose(req.Year, req.Month)
+	if err != nil && !isNotFound(err) {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	res, err := report.Compute(req.Year, req.Month)
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	render.Json(c, render.Ok, resMonthStat{Close: m, MonthResult: res})
+}
+
+func CloseMonth(c *gin.Context) {
+	var req reqYearMonth
+	if err := c.ShouldBindUri(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	actorType, actorId, _ := audit.Actor(c)
+	m, err := report.CloseMonth(req.Year, req.Month, actorType+":"+actorId)
+	if errors.Is(err, report.ErrMonthClosed) || errors.Is(err, report.ErrMonthNotEnded) {
+		render.Json(c, render.RepetitiveOperation, err.Error())
+		return
+	}
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	audit.Record(c, audit.ActionMonthClose, "month", fmt.Sprintf("%d%02d", req.Year, req.Month), nil, m)
+	render.Json(c, render.Ok, m)
+}
+
+type reqReopenMonth struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+// ReopenMonth 重新开放已关账的月份，只有 close.reopen_admins 中的管理员可以操作
+func ReopenMonth(c *gin.Context) {
+	var uri reqYearMonth
+	if err := c.ShouldBindUri(&uri); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	var req reqReopenMonth
+	if err := c.ShouldBindJSON(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	if !canReopenMonth(c) {
+		render.Json(c, render.ErrForbidden, nil)
+		return
+	}
+	before, _ := model.FindMonthClose(uri.Year, uri.Month)
+	actorType, actorId, _ := audit.Actor(c)
+	m, err := report.ReopenMonth(uri.Year, uri.Month, actorType+":"+actorId, req.Reason)
+	if errors.Is(err, report.ErrMonthNotClosed) {
+		render.Json(c, render.RepetitiveOperation, err.Error())
+		return
+	}
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	audit.Record(c, audit.ActionMonthReopen, "month", fmt.Sprintf("%d%02d", uri.Year, uri.Month), before, m)
+	render.Json(c, render.Ok, m)
+}
+
+func canReopenMonth(c *gin.Context) bool {
+	claims := getClaims(c)
+	if claims == nil {
+		return false
+	}
+	for _, id := range config.GetConfig().Close.ReopenAdmins {
+		if id == claims.ID {
+			return true
+		}
+	}
+	return false
+}
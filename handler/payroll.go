@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tool-attendance/audit"
+	"tool-attendance/config"
+	"tool-attendance/report"
+	"tool-attendance/utils/i18n"
+	"tool-attendance/utils/render"
+	"tool-attendance/utils/tz"
+)
+
+type reqPayroll struct {
+	Year   int    `form:"year"`                                           // 默认今年
+	Format string `form:"format" binding:"omitempty,oneof=csv xlsx json"` // 默认 csv
+}
+
+// PayrollExport 导出薪资扣款数据，列、取整和扣款档位见配置 payroll
+func PayrollExport(c *gin.Context) {
+	var uri reqAttendanceDetail
+	if err := c.ShouldBindUri(&uri); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	var req reqPayroll
+	if err := c.ShouldBindQuery(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	if req.Year == 0 {
+		req.Year = time.Now().In(tz.Default()).Year()
+	}
+	lang := i18n.FromContext(c, i18n.ZhCN)
+	cfg := config.GetConfig().Payroll
+
+	rows, err := report.Payroll(req.Year, uri.Month, cfg)
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	audit.Record(c, audit.ActionReportExport, "report", fmt.Sprintf("payroll:%d%02d", req.Year, uri.Month), nil, nil)
+
+	fileName := fmt.Sprintf("payroll_%d%02d", req.Year, uri.Month)
+	switch req.Format {
+	case "json":
+		render.Json(c, render.Ok, rows)
+	case "xlsx":
+		f, err := report.PayrollExcel(req.Year, uri.Month, rows, cfg, lang)
+		if err != nil {
+			render.Json(c, render.Failed, err.Error())
+			return
+		}
+		defer f.Close()
+		renderExcel(c, f, fileName+".xlsx")
+	default:
+		// 先写到缓冲区，出错时还能返回错误信息
+		buf := &bytes.Buffer{}
+		if err = report.WritePayrollCsv(buf, rows, cfg, lang); err != nil {
+			render.Json(c, render.Failed, err.Error())
+			return
+		}
+		c.Header("Content-Disposition", "attachment; filename="+fileName+".csv")
+		c.Header("Cache-Control", "no-cache")
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+	}
+}
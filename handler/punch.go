@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tool-attendance/model"
+	"tool-attendance/report"
+	"tool-attendance/utils/render"
+	"tool-attendance/utils/tz"
+	"tool-attendance/webhook"
+)
+
+type punchItem struct {
+	UserId    string `json:"user_id" binding:"required"`
+	Firstname string `json:"firstname"`
+	Username  string `json:"username"`
+	PunchTime int64  `json:"punch_time" binding:"required"` // 打卡时间（秒级时间戳）
+
+	// 打卡位置，都是可选的，用于按工作地点的围栏判定打卡地点
+	Latitude  *float64 `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" binding:"omitempty,min=-180,max=180"`
+	Bssid     string   `json:"bssid" binding:"max=32"` // 连接的 Wi-Fi
+}
+
+type reqPunch struct {
+	Punches []punchItem `json:"punches" binding:"required,min=1,max=500,dive"`
+}
+
+// PunchIn 考勤机上传打卡记录
+// 同一用户同一天的多次打卡，最早的一次记为上班卡，最晚的一次记为下班卡
+func PunchIn(c *gin.Context) {
+	var req reqPunch
+	if err := c.ShouldBindJSON(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	// 重复上传的打卡不改变记录，不再推送
+	publisher := webhook.NewPublisher()
+	rejected := make([]rejectedPunch, 0)
+	for i, v := range req.Punches {
+		changed, err := savePunch(v, c.ClientIP())
+		if changed {
+			// 记录已经更新，汇总失败重试时不会再有变化，这里就要推送
+			publisher.Publish(webhook.EventPunchCreated, v)
+		}
+		// 已关账月份的打卡单独拒绝，设备重试也不会成功，不影响同批的其他打卡
+		if errors.Is(err, report.ErrMonthClosed) {
+			rejected = append(rejected, rejectedPunch{Index: i, Reason: err.Error()})
+			continue
+		}
+		if err != nil {
+			render.Json(c, render.Failed, fmt.Sprintf("punches[%d]: %s", i, err.Error()))
+			return
+		}
+	}
+	render.Json(c, render.Ok, gin.H{"accepted": len(req.Punches) - len(rejected), "rejected": rejected})
+}
+
+// rejectedPunch 未保存的打卡，index 为在请求 punches 中的下标
+type rejectedPunch struct {
+	Index  int    `json:"index"`
+	Reason string `json:"reason"`
+}
+
+// savePunch 保存一次打卡，clientIp 为上传打卡的客户端 IP，返回当天的上下班时间是否有变化
+func savePunch(p punchItem, clientIp string) (bool, error) {
+	loc, err := report.UserLocation(p.UserId)
+	if err != nil {
+		return false, err
+	}
+	punchTime := time.Unix(p.PunchTime, 0).In(loc)
+	day := report.RecordDay(punchTime, loc)
+	punchLog, err := newPunchLog(p, clientIp, day, punchTime)
+	if err != nil {
+		return false, err
+	}
+
+	// 关账检查与合并在同一事务中，关账后不会再写入打卡
+	month := day.In(tz.Default())
+	changed, err := model.MergeRecordPunch(&model.Record{
+		UserId:     p.UserId,
+		Firstname:  p.Firstname,
+		Username:   p.Username,
+		DaysDate:   day,
+		OnworkTime: punchTime,
+	}, punchLog, month.Year(), int(month.Month()), func(onWork, offWork time.Time) (time.Time, time.Time) {
+		return mergePunchTime(onWork, offWork, punchTime)
+	})
+	if err != nil {
+		return false, err
+	}
+	// 重复上传同一打卡不会改变记录，汇总失败时设备重试即可
+	return changed, report.RefreshDaySummary(p.UserId, day)
+}
+
+// newPunchLog 生成打卡明细，按工作地点的围栏判定打卡地点；IP 使用上传打卡的客户端地址，设备上报的 IP 不可信。
+// 重复上传同一次打卡时保留第一次的明细
+func newPunchLog(p punchItem, clientIp string, day, punchTime time.Time) (*model.PunchLog, error) {
+	meta := report.PunchMeta{Latitude: p.Latitude, Longitude: p.Longitude, Bssid: p.Bssid, Ip: clientIp}
+	place, siteId, err := report.PunchPlace(p.UserId, day, meta)
+	if err != nil {
+		return nil, err
+	}
+	return &model.PunchLog{
+		UserId:    p.UserId,
+		Day:       day,
+		PunchTime: punchTime,
+		Latitude:  p.Latitude,
+		Longitude: p.Longitude,
+		Bssid:     p.Bssid,
+		Ip:        clientIp,
+		SiteId:    siteId,
+		Place:     place,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// mergePunchTime 将新的打卡时间合并进当日的上下班时间：最早为上班，最晚为下班
+func mergePunchTime(onWork, offWork, punchTime time.Time) (time.Time, time.Time) {
+	earliest, latest := punchTime, punchTime
+	for _, t := range []time.Time{onWork, offWork} {
+		if t.IsZero() {
+			continue
+		}
+		if t.Before(earliest) {
+			earliest = t
+		}
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	if earliest.Equal(latest) {
+		return earliest, time.Time{}
+	}
+	return earliest, latest
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tool-attendance/audit"
+	"tool-attendance/model"
+	"tool-attendance/report"
+	"tool-attendance/utils/i18n"
+	"tool-attendance/utils/render"
+)
+
+type reqSubmitReportJob struct {
+	Kind        string `json:"kind" binding:"required"` // detail：考勤明细；record：考勤记录；payroll：薪资扣款；heatmap：打卡时间热力图
+	Year        int    `json:"year" binding:"required,gte=2000"`
+	Month       int    `json:"month" binding:"required,gte=1,lte=12"`
+	CallbackUrl string `json:"callback_url" binding:"omitempty,url"` // 任务结束后 POST 通知的地址
+}
+
+type resReportJob struct {
+	*model.ReportJob
+	DownloadUrl string `json:"download_url,omitempty"`
+}
+
+func SubmitReportJob(c *gin.Context) {
+	var req reqSubmitReportJob
+	if err := c.ShouldBindJSON(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	if !report.IsValidKind(req.Kind) {
+		render.Json(c, render.ErrParams, "unknown kind: "+req.Kind)
+		return
+	}
+	if req.CallbackUrl != "" {
+		if err := report.CheckCallbackUrl(req.CallbackUrl); err != nil {
+			render.Json(c, render.ErrParams, err.Error())
+			return
+		}
+	}
+	actorType, actorId, _ := audit.Actor(c)
+	job, err := report.SubmitJob(req.Kind, req.Year, req.Month, i18n.FromContext(c, i18n.ZhCN), req.CallbackUrl, actorType+":"+actorId)
+	if errors.Is(err, report.ErrJobBusy) {
+		render.Json(c, render.ServiceUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	audit.Record(c, audit.ActionReportExport, "report", fmt.Sprintf("%s:%d%02d", req.Kind, req.Year, req.Month), nil, job)
+	render.Json(c, render.Ok, resReportJob{ReportJob: job})
+}
+
+type reqReportJobId struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+func ReportJobStatus(c *gin.Context) {
+	var req reqReportJobId
+	if err := c.ShouldBindUri(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	job, err := model.FindReportJob(req.ID)
+	if isNotFound(err) {
+		render.Json(c, render.NotFound, nil)
+		return
+	}
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	res := resReportJob{ReportJob: job}
+	if job.Status == model.ReportJobSuccess {
+		res.DownloadUrl = report.DownloadUrl(job.ID)
+	}
+	render.Json(c, render.Ok, res)
+}
+
+func DownloadReportJob(c *gin.Context) {
+	var req reqReportJobId
+	if err := c.ShouldBindUri(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	job, err := model.FindReportJob(req.ID)
+	if isNotFound(err) {
+		render.Json(c, render.NotFound, nil)
+		return
+	}
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	if job.Status == model.ReportJobExpired {
+		// 本地文件已清理，已归档的报表跳转到对象存储下载
+		u, _, err := report.PresignUrl(job, 0)
+		if err == nil {
+			c.Redirect(http.StatusFound, u)
+			return
+		}
+	}
+	if job.Status != model.ReportJobSuccess {
+		render.Json(c, render.NotFound, "report status: "+job.Status)
+		return
+	}
+	c.FileAttachment(job.FilePath, report.FileName(job))
+}
+
+type reqPresignReportJob struct {
+	Expire int `form:"expire" binding:"gte=0,lte=10080"` // 链接有效期（分钟），0 使用默认值，最长 7 天
+}
+
+type resPresignReportJob struct {
+	Url       string    `json:"url"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+// PresignReportJob 获取已归档报表的限时下载链接
+func PresignReportJob(c *gin.Context) {
+	var uri reqReportJobId
+	if err := c.ShouldBindUri(&uri); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	var req reqPresignReportJob
+	if err := c.ShouldBindQuery(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	job, err := model.FindReportJob(uri.ID)
+	if isNotFound(err) {
+		render.Json(c, render.NotFound, nil)
+		return
+	}
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	u, expiredAt, err := report.PresignUrl(job, time.Duration(req.Expire)*time.Minute)
+	if errors.Is(err, report.ErrNotArchived) {
+		render.Json(c, render.NotFound, err.Error())
+		return
+	}
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	render.Json(c, render.Ok, resPresignReportJob{Url: u, ExpiredAt: expiredAt})
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"tool-attendance/utils/aws_s3"
+	"tool-attendance/utils/render"
+)
+
+type reqLocalObject struct {
+	Expires   int64  `form:"expires" binding:"required"`
+	FileName  string `form:"filename"`
+	Signature string `form:"signature" binding:"required"`
+}
+
+// LocalObject 本地存储的预签名下载，链接由 aws_s3 的本地存储生成
+func LocalObject(c *gin.Context) {
+	var req reqLocalObject
+	if err := c.ShouldBindQuery(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	p, err := aws_s3.LocalPresignedPath(key, req.Expires, req.FileName, req.Signature)
+	if err != nil {
+		render.Json(c, render.ErrForbidden, err.Error())
+		return
+	}
+	if req.FileName != "" {
+		c.FileAttachment(p, req.FileName)
+		return
+	}
+	c.File(p)
+}
@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tool-attendance/audit"
+	"tool-attendance/model"
+	"tool-attendance/types"
+	"tool-attendance/utils/render"
+	"tool-attendance/utils/safehttp"
+	"tool-attendance/webhook"
+)
+
+type reqSaveWebhook struct {
+	Name   string   `json:"name" binding:"required,max=64"`
+	Url    string   `json:"url" binding:"required,url,max=512"`
+	Events []string `json:"events" binding:"required,min=1"`
+	Active *bool    `json:"active"` // 为空时启用
+}
+
+func (r *reqSaveWebhook) check() string {
+	if err := safehttp.CheckUrl(r.Url); err != nil {
+		return "url: " + err.Error()
+	}
+	for _, e := range r.Events {
+		if !webhook.IsValidEvent(e) {
+			return "unknown event: " + e
+		}
+	}
+	return ""
+}
+
+type resCreateWebhook struct {
+	model.WebhookSubscription
+	Secret string `json:"secret"` // 签名密钥，仅在创建时返回一次
+}
+
+func CreateWebhook(c *gin.Context) {
+	var req reqSaveWebhook
+	if err := c.ShouldBindJSON(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	if msg := req.check(); msg != "" {
+		render.Json(c, render.ErrParams, msg)
+		return
+	}
+	secret, err := webhook.GenSecret()
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	s := model.WebhookSubscription{
+		Name:      req.Name,
+		Url:       req.Url,
+		Events:    strings.Join(req.Events, ","),
+		Secret:    secret,
+		Active:    req.Active == nil || *req.Active,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	_, s.CreatedBy, _ = audit.Actor(c)
+	if err = model.CreateWebhookSubscription(&s); err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	audit.Record(c, audit.ActionWebhookSave, "webhook", strconv.FormatInt(s.ID, 10), nil, s)
+	render.Json(c, render.Ok, resCreateWebhook{WebhookSubscription: s, Secret: secret})
+}
+
+func WebhookList(c *gin.Context) {
+	list, err := model.FindWebhookSubscriptionList()
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	render.Json(c, render.Ok, list)
+}
+
+type reqWebhookId struct {
+	ID int64 `uri:"id" binding:"required"`
+}
+
+func UpdateWebhook(c *gin.Context) {
+	var uri reqWebhookId
+	if err := c.ShouldBindUri(&uri); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	var req reqSaveWebhook
+	if err := c.ShouldBindJSON(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	if msg := req.check(); msg != "" {
+		render.Json(c, render.ErrParams, msg)
+		return
+	}
+	before, err := model.FindWebhookSubscription(uri.ID)
+	if isNotFound(err) {
+		render.Json(c, render.NotFound, nil)
+		return
+	}
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	s := *before
+	s.Name = req.Name
+	s.Url = req.Url
+	s.Events = strings.Join(req.Events, ",")
+	if req.Active != nil {
+		s.Active = *req.Active
+	}
+	s.UpdatedAt = time.Now()
+	if err = model.SaveWebhookSubscription(&s); err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	audit.Record(c, audit.ActionWebhookSave, "webhook", strconv.FormatInt(s.ID, 10), before, s)
+	render.Json(c, render.Ok, s)
+}
+
+// DeleteWebhook 删除订阅，未完成的推送不再重试
+func DeleteWebhook(c *gin.Context) {
+	var req reqWebhookId
+	if err := c.ShouldBindUri(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	before, err := model.FindWebhookSubscription(req.ID)
+	if isNotFound(err) {
+		render.Json(c, render.NotFound, nil)
+		return
+	}
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	if err = model.DeleteWebhookSubscription(req.ID); err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	audit.Record(c, audit.ActionWebhookDelete, "webhook", strconv.FormatInt(req.ID, 10), before, nil)
+	render.Json(c, render.Ok, nil)
+}
+
+type reqWebhookDeliveryList struct {
+	types.ReqPage
+	SubscriptionId int64  `form:"subscription_id"`
+	Status         string `form:"status"`
+}
+
+func WebhookDeliveryList(c *gin.Context) {
+	var req reqWebhookDeliveryList
+	if err := c.ShouldBindQuery(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	list, total, err := model.FindWebhookDeliveryList(req.SubscriptionId, req.Status, req.Page, req.Limit)
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	render.Json(c, render.Ok, types.PageResult{
+		Page:  req.Page,
+		Limit: req.Limit,
+		Items: list,
+		Total: total,
+	})
+}
+
+// RedeliverWebhook 以原内容重新推送，返回新的推送记录
+func RedeliverWebhook(c *gin.Context) {
+	var req reqWebhookId
+	if err := c.ShouldBindUri(&req); err != nil {
+		render.Json(c, render.ErrParams, err.Error())
+		return
+	}
+	d, err := webhook.Redeliver(req.ID)
+	if isNotFound(err) {
+		render.Json(c, render.NotFound, nil)
+		return
+	}
+	if err != nil {
+		render.Json(c, render.Failed, err.Error())
+		return
+	}
+	audit.Record(c, audit.ActionWebhookRedeliver, "webhook_delivery", strconv.FormatInt(req.ID, 10), nil, d)
+	render.Json(c, render.Ok, d)
+}
@@ -0,0 +1,137 @@
+package leave
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"tool-attendance/config"
+	"tool-attendance/model"
+)
+
+// 发放方式
+const (
+	AccrualYearly  = "yearly"  // 每年 1 月 1 日发放全年额度
+	AccrualMonthly = "monthly" // 每月 1 日发放当月额度
+)
+
+// 未配置 tiers 时的司龄分档
+var defaultTiers = []config.LeaveTier{{MinYears: 1, Days: 5}, {MinYears: 10, Days: 10}, {MinYears: 20, Days: 15}}
+
+// serviceYears 截至 day 已满的司龄年数，入职前为 -1
+func serviceYears(hire, day time.Time) int {
+	years := day.Year() - hire.Year()
+	if day.Month() < hire.Month() || (day.Month() == hire.Month() && day.Day() < hire.Day()) {
+		years--
+	}
+	return years
+}
+
+// annualDays 司龄对应的全年额度，取 min_years 不超过司龄的最高一档
+func annualDays(tiers []config.LeaveTier, years int) float64 {
+	if len(tiers) == 0 {
+		tiers = defaultTiers
+	}
+	sorted := append([]config.LeaveTier(nil), tiers...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].MinYears < sorted[j].MinYears })
+	var days float64
+	for _, t := range sorted {
+		if years < 0 || years < t.MinYears {
+			break
+		}
+		days = t.Days
+	}
+	return days
+}
+
+// Entitled 截至 year 年第 throughMonth 月（含）累计应发放的年假。
+// 每月按当月 1 日的司龄折算全年额度的 1/12，离职日期之后的月份不发放，合计向下取整到 0.5 天
+func Entitled(tiers []config.LeaveTier, hire time.Time, resign *time.Time, year, throughMonth int) float64 {
+	var sum float64
+	for m := 1; m <= throughMonth; m++ {
+		first := time.Date(year, time.Month(m), 1, 0, 0, 0, 0, hire.Location())
+		if resign != nil && first.After(*resign) {
+			break
+		}
+		sum += annualDays(tiers, serviceYears(hire, first)) / 12
+	}
+	return math.Floor(sum*2+1e-9) / 2
+}
+
+// carryDeadline 上一年结转的年假在 year 年的失效时间，carry_over_months 为 0 时次年内有效
+func carryDeadline(year int, cfg config.LeaveConfig, loc *time.Location) time.Time {
+	if cfg.CarryOverMonths <= 0 {
+		return time.Date(year+1, 1, 1, 0, 0, 0, 0, loc)
+	}
+	return time.Date(year, time.Month(1+cfg.CarryOverMonths), 1, 0, 0, 0, 0, loc)
+}
+
+// plan 计算 now 时应补记的流水：本年度的发放、上年结转超出上限的部分和过期的结转。
+// 发放按 ref_key 只记一次；作废的 ref_key 带日期，撤销请假退回的天数会在之后重新判断
+func plan(e model.Employee, buckets []model.LeaveBucket, now time.Time, cfg config.LeaveConfig) []model.LeaveLedger {
+	if e.HireDate == nil {
+		return nil
+	}
+	loc := now.Location()
+	hire := e.HireDate.In(loc)
+	hire = time.Date(hire.Year(), hire.Month(), hire.Day(), 0, 0, 0, 0, loc)
+	year := now.Year()
+	var rows []model.LeaveLedger
+
+	// 发放
+	if cfg.Accrual == AccrualMonthly {
+		for m := 1; m <= int(now.Month()); m++ {
+			days := Entitled(cfg.Tiers, hire, e.ResignDate, year, m) - Entitled(cfg.Tiers, hire, e.ResignDate, year, m-1)
+			if days > 0 {
+				rows = append(rows, model.LeaveLedger{UserId: e.UserId, Year: year, Kind: model.LedgerAccrual, Days: days,
+					RefKey: fmt.Sprintf("accrual:%d-%02d", year, m), Note: fmt.Sprintf("%d年%d月发放", year, m)})
+			}
+		}
+	} else if days := Entitled(cfg.Tiers, hire, e.ResignDate, year, 12); days > 0 {
+		rows = append(rows, model.LeaveLedger{UserId: e.UserId, Year: year, Kind: model.LedgerAccrual, Days: days,
+			RefKey: fmt.Sprintf("accrual:%d", year), Note: fmt.Sprintf("%d年发放", year)})
+	}
+
+	// 结转和作废
+	today := now.Format("20060102")
+	for _, b := range buckets {
+		if b.Year >= year || b.Days <= 0 {
+			continue
+		}
+		expire, note := b.Days, "结转过期"
+		if b.Year == year-1 && now.Before(carryDeadline(year, cfg, loc)) {
+			expire, note = b.Days-cfg.CarryOverMax, "超出结转上限"
+		}
+		expire = math.Round(expire*100) / 100
+		if expire <= 0 {
+			continue
+		}
+		rows = append(rows, model.LeaveLedger{UserId: e.UserId, Year: b.Year, Kind: model.LedgerExpiry, Days: -expire,
+			RefKey: fmt.Sprintf("expiry:%d:%s", b.Year, today), Note: note})
+	}
+	return rows
+}
+
+// allocate 从最早年份的额度开始扣减 days 天，返回每个年份扣减的流水
+func allocate(buckets []model.LeaveBucket, days float64) ([]model.LeaveLedger, error) {
+	var (
+		rows []model.LeaveLedger
+		left = days
+	)
+	for _, b := range buckets {
+		if left <= 0 {
+			break
+		}
+		if b.Days <= 0 {
+			continue
+		}
+		use := math.Min(b.Days, left)
+		rows = append(rows, model.LeaveLedger{Year: b.Year, Kind: model.LedgerUsage, Days: -use})
+		left = math.Round((left-use)*100) / 100
+	}
+	if left > 0 {
+		return nil, ErrInsufficientBalance
+	}
+	return rows, nil
+}
@@ -0,0 +1,110 @@
+package leave
+
+import (
+	"testing"
+	"time"
+
+	"tool-attendance/config"
+	"tool-attendance/model"
+)
+
+func date(y, m, d int) time.Time {
+	return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestServiceYears(t *testing.T) {
+	hire := date(2020, 3, 15)
+	cases := map[time.Time]int{
+		date(2020, 1, 1):  -1,
+		date(2020, 3, 15): 0,
+		date(2021, 3, 14): 0,
+		date(2021, 3, 15): 1,
+		date(2030, 4, 1):  10,
+	}
+	for day, want := range cases {
+		if got := serviceYears(hire, day); got != want {
+			t.Errorf("serviceYears(%s) = %d, want %d", day.Format("2006-01-02"), got, want)
+		}
+	}
+}
+
+func TestEntitled(t *testing.T) {
+	// 2021-03-15 满 1 年：4~12 月按 5 天折算 9/12*5=3.75，向下取整为 3.5
+	hire := date(2020, 3, 15)
+	if got := Entitled(nil, hire, nil, 2021, 12); got != 3.5 {
+		t.Errorf("first year = %v", got)
+	}
+	if got := Entitled(nil, hire, nil, 2022, 12); got != 5 {
+		t.Errorf("full year = %v", got)
+	}
+	// 2030-03-15 满 10 年：1~3 月 5 天、4~12 月 10 天，3/12*5+9/12*10=8.75
+	if got := Entitled(nil, hire, nil, 2030, 12); got != 8.5 {
+		t.Errorf("tier change = %v", got)
+	}
+	// 2022-06-30 离职：1~6 月
+	resign := date(2022, 6, 30)
+	if got := Entitled(nil, hire, &resign, 2022, 12); got != 2.5 {
+		t.Errorf("resigned = %v", got)
+	}
+	tiers := []config.LeaveTier{{MinYears: 0, Days: 12}}
+	if got := Entitled(tiers, hire, nil, 2022, 6); got != 6 {
+		t.Errorf("custom tiers = %v", got)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	hire := date(2015, 1, 1)
+	e := model.Employee{UserId: "u1", HireDate: &hire}
+	cfg := config.LeaveConfig{Accrual: AccrualYearly, CarryOverMax: 5, CarryOverMonths: 3}
+
+	// 年初：发放全年额度，上年剩余 8 天超出结转上限 3 天，前年剩余全部作废
+	buckets := []model.LeaveBucket{{Year: 2022, Days: 1}, {Year: 2023, Days: 8}}
+	rows := plan(e, buckets, date(2024, 1, 2), cfg)
+	if len(rows) != 3 {
+		t.Fatalf("rows = %+v", rows)
+	}
+	if r := rows[0]; r.Kind != model.LedgerAccrual || r.Year != 2024 || r.Days != 5 || r.RefKey != "accrual:2024" {
+		t.Errorf("accrual = %+v", r)
+	}
+	if r := rows[1]; r.Kind != model.LedgerExpiry || r.Year != 2022 || r.Days != -1 {
+		t.Errorf("old expiry = %+v", r)
+	}
+	if r := rows[2]; r.Kind != model.LedgerExpiry || r.Year != 2023 || r.Days != -3 || r.RefKey != "expiry:2023:20240102" {
+		t.Errorf("cap expiry = %+v", r)
+	}
+
+	// 结转期满：上年剩余全部作废
+	rows = plan(e, []model.LeaveBucket{{Year: 2023, Days: 2}, {Year: 2024, Days: 5}}, date(2024, 4, 1), cfg)
+	if len(rows) != 2 || rows[1].Year != 2023 || rows[1].Days != -2 {
+		t.Errorf("carry expiry = %+v", rows)
+	}
+
+	// 按月发放，补记到当月；累计不足半天的月份不发放
+	cfg.Accrual = AccrualMonthly
+	rows = plan(e, nil, date(2024, 3, 1), cfg)
+	if len(rows) != 2 || rows[0].RefKey != "accrual:2024-02" || rows[1].RefKey != "accrual:2024-03" {
+		t.Fatalf("monthly = %+v", rows)
+	}
+	var sum float64
+	for _, r := range rows {
+		sum += r.Days
+	}
+	if sum != Entitled(nil, hire, nil, 2024, 3) {
+		t.Errorf("monthly sum = %v", sum)
+	}
+
+	if rows = plan(model.Employee{UserId: "u2"}, nil, date(2024, 3, 1), cfg); rows != nil {
+		t.Errorf("no hire date = %+v", rows)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	buckets := []model.LeaveBucket{{Year: 2023, Days: 1.5}, {Year: 2024, Days: 0}, {Year: 2025, Days: 5}}
+	rows, err := allocate(buckets, 3)
+	if err != nil || len(rows) != 2 || rows[0].Year != 2023 || rows[0].Days != -1.5 || rows[1].Year != 2025 || rows[1].Days != -1.5 {
+		t.Fatalf("rows = %+v, err = %v", rows, err)
+	}
+	if _, err = allocate(buckets, 7); err != ErrInsufficientBalance {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,353 @@
+package leave
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"tool-attendance/config"
+	"tool-attendance/log"
+	"tool-attendance/model"
+	"tool-attendance/report"
+	"tool-attendance/utils"
+	"tool-attendance/utils/tz"
+	"tool-attendance/webhook"
+)
+
+var (
+	ErrLeaveStatus         = errors.New("leave request status does not allow this operation")
+	ErrInsufficientBalance = errors.New("insufficient annual leave balance")
+	ErrNoWorkday           = errors.New("no workday in the leave period")
+	ErrInvalidPeriod       = errors.New("invalid leave period")
+	ErrOverlap             = errors.New("leave period overlaps another pending or approved request")
+)
+
+// 串行化余额的读取和扣减，避免并发审批时超额扣减
+var mu sync.Mutex
+
+// IsValidType 是否为支持的请假类型
+func IsValidType(leaveType string) bool {
+	switch leaveType {
+	case model.LeaveTypeAnnual, model.LeaveTypeUnpaid, model.LeaveTypeSick, model.LeaveTypeRemote:
+		return true
+	}
+	return false
+}
+
+// Submit 提交请假申请，天数按日历中的工作日计算；请假期间涉及已关账的月份，或与同一用户待审批、已批准的申请重叠时不能提交
+func Submit(r *model.LeaveRequest) error {
+	loc := tz.Default()
+	r.StartDate, r.EndDate = dayOf(r.StartDate, loc), dayOf(r.EndDate, loc)
+	if r.EndDate.Before(r.StartDate) || (r.HalfDay && !r.EndDate.Equal(r.StartDate)) {
+		return ErrInvalidPeriod
+	}
+	if err := checkMonthsOpen(r.StartDate, r.EndDate); err != nil {
+		return err
+	}
+
+	// 检查重叠和创建申请之间不能插入同一用户的其他申请
+	mu.Lock()
+	defer mu.Unlock()
+	overlap, err := model.HasOverlappingLeave(r.UserId, r.StartDate, r.EndDate)
+	if err != nil {
+		return err
+	}
+	if overlap {
+		return ErrOverlap
+	}
+	n, err := report.CountWorkdays(r.StartDate, r.EndDate)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoWorkday
+	}
+	r.Days = float64(n)
+	if r.HalfDay {
+		r.Days = 0.5
+	}
+	r.Status = model.LeavePending
+	r.CreatedAt = time.Now()
+	r.UpdatedAt = r.CreatedAt
+	return model.CreateLeaveRequest(r)
+}
+
+// Approve 审批通过，年假从余额中按额度年份从早到晚扣减，通过后推送 leave.approved 事件
+func Approve(id int64, by, note string) (*model.LeaveRequest, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	r, err := findPending(id)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkMonthsOpen(r.StartDate, r.EndDate); err != nil {
+		return nil, err
+	}
+	var ledger []model.LeaveLedger
+	if r.Type == model.LeaveTypeAnnual {
+		// 先补记发放和结转作废，超出结转上限或已过期的额度不能再用
+		if err = settle(r.UserId, time.Now().In(tz.Default()), config.GetConfig().Leave); err != nil {
+			return nil, err
+		}
+		buckets, err := model.FindLeaveBuckets(r.UserId)
+		if err != nil {
+			return nil, err
+		}
+		if ledger, err = allocate(buckets, r.Days); err != nil {
+			return nil, err
+		}
+		for i := range ledger {
+			ledger[i].UserId = r.UserId
+			ledger[i].RefKey = fmt.Sprintf("usage:%d:%d", r.ID, ledger[i].Year)
+			ledger[i].RequestId = r.ID
+			ledger[i].CreatedBy = by
+			ledger[i].CreatedAt = time.Now()
+		}
+	}
+	if err = review(r, model.LeavePending, model.LeaveApproved, by, note, ledger); err != nil {
+		return nil, err
+	}
+	if r.Type == model.LeaveTypeRemote {
+		reclassifyRemote(r, true)
+	}
+	webhook.Publish(webhook.EventLeaveApproved, r)
+	return r, nil
+}
+
+// Reject 驳回待审批的申请
+func Reject(id int64, by, note string) (*model.LeaveRequest, error) {
+	r, err := findPending(id)
+	if err != nil {
+		return nil, err
+	}
+	if err = review(r, model.LeavePending, model.LeaveRejected, by, note, nil); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Cancel 撤销待审批或已批准的申请，已批准的年假退回到原额度年份
+func Cancel(id int64, by, note string) (*model.LeaveRequest, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	r, err := model.FindLeaveRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	if r.Status != model.LeavePending && r.Status != model.LeaveApproved {
+		return nil, ErrLeaveStatus
+	}
+	from := r.Status
+	var ledger []model.LeaveLedger
+	if from == model.LeaveApproved {
+		if err = checkMonthsOpen(r.StartDate, r.EndDate); err != nil {
+			return nil, err
+		}
+		usages, err := model.FindLeaveLedgerByRequest(r.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range usages {
+			if v.Kind != model.LedgerUsage {
+				continue
+			}
+			ledger = append(ledger, model.LeaveLedger{
+				UserId:    r.UserId,
+				Year:      v.Year,
+				Kind:      model.LedgerReversal,
+				Days:      -v.Days,
+				RefKey:    fmt.Sprintf("reversal:%d:%d", r.ID, v.Year),
+				RequestId: r.ID,
+				CreatedBy: by,
+				CreatedAt: time.Now(),
+			})
+		}
+	}
+	if err = review(r, from, model.LeaveCancelled, by, note, ledger); err != nil {
+		return nil, err
+	}
+	if from == model.LeaveApproved && r.Type == model.LeaveTypeRemote {
+		reclassifyRemote(r, false)
+	}
+	return r, nil
+}
+
+// Adjust 人工调整某年份额度的天数，days 为负时扣减
+func Adjust(userId string, year int, days float64, note, by string) (*model.LeaveLedger, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	l := model.LeaveLedger{
+		UserId:    userId,
+		Year:      year,
+		Kind:      model.LedgerAdjust,
+		Days:      days,
+		RefKey:    "adjust:" + utils.UUID(),
+		Note:      note,
+		CreatedBy: by,
+		CreatedAt: time.Now(),
+	}
+	if err := model.CreateLeaveLedger([]model.LeaveLedger{l}); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// Bucket 某一年份额度的剩余天数
+type Bucket struct {
+	Year     int     `json:"year"`
+	Days     float64 `json:"days"`
+	ExpireAt string  `json:"expire_at,omitempty"` // 上一年结转额度的最后有效日期，2006-01-02
+}
+
+// BalanceResult 年假余额
+type BalanceResult struct {
+	UserId      string   `json:"user_id"`
+	Balance     float64  `json:"balance"`     // 可用天数
+	Pending     float64  `json:"pending"`     // 待审批的年假天数
+	Entitlement float64  `json:"entitlement"` // 本年度的全年额度
+	Buckets     []Bucket `json:"buckets"`
+}
+
+// Balance 查询年假余额
+func Balance(userId string, now time.Time) (*BalanceResult, error) {
+	cfg := config.GetConfig().Leave
+	now = now.In(tz.Default())
+	res := &BalanceResult{UserId: userId, Buckets: make([]Bucket, 0)}
+	e, err := model.FindEmployee(userId)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if e != nil && e.HireDate != nil {
+		res.Entitlement = Entitled(cfg.Tiers, dayOf(*e.HireDate, now.Location()), e.ResignDate, now.Year(), 12)
+	}
+	buckets, err := model.FindLeaveBuckets(userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range buckets {
+		if b.Days == 0 {
+			continue
+		}
+		bucket := Bucket{Year: b.Year, Days: b.Days}
+		if b.Year == now.Year()-1 {
+			bucket.ExpireAt = carryDeadline(now.Year(), cfg, now.Location()).AddDate(0, 0, -1).Format("2006-01-02")
+		}
+		res.Buckets = append(res.Buckets, bucket)
+		res.Balance += b.Days
+	}
+	if res.Pending, err = model.SumPendingLeaveDays(userId, model.LeaveTypeAnnual); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Run 给所有员工补记到 now 为止的年假发放、结转作废，可重复执行
+func Run(now time.Time) error {
+	cfg := config.GetConfig().Leave
+	if cfg.Accrual != "" && cfg.Accrual != AccrualYearly && cfg.Accrual != AccrualMonthly {
+		return fmt.Errorf("invalid leave.accrual: %s", cfg.Accrual)
+	}
+	now = now.In(tz.Default())
+	employees, err := model.FindEmployeeMap()
+	if err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(employees))
+	for id := range employees {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	mu.Lock()
+	defer mu.Unlock()
+	failed := 0
+	for _, id := range ids {
+		e := employees[id]
+		if e.HireDate == nil {
+			continue
+		}
+		if err = settleEmployee(e, now, cfg); err != nil {
+			failed++
+			log.Log.Errorf("leave accrual of %s err:%v", id, err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("leave accrual: %d of %d employees failed", failed, len(ids))
+	}
+	return nil
+}
+
+// settle 补记用户到 now 为止的发放和结转作废，不是员工或未填写入职日期时不处理；调用方需持有 mu
+func settle(userId string, now time.Time, cfg config.LeaveConfig) error {
+	e, err := model.FindEmployee(userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return settleEmployee(*e, now, cfg)
+}
+
+func settleEmployee(e model.Employee, now time.Time, cfg config.LeaveConfig) error {
+	buckets, err := model.FindLeaveBuckets(e.UserId)
+	if err != nil {
+		return err
+	}
+	rows := plan(e, buckets, now, cfg)
+	for i := range rows {
+		rows[i].CreatedBy = "system"
+		rows[i].CreatedAt = time.Now()
+	}
+	return model.CreateLeaveLedger(rows)
+}
+
+func findPending(id int64) (*model.LeaveRequest, error) {
+	r, err := model.FindLeaveRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	if r.Status != model.LeavePending {
+		return nil, ErrLeaveStatus
+	}
+	return r, nil
+}
+
+func review(r *model.LeaveRequest, from, to, by, note string, ledger []model.LeaveLedger) error {
+	now := time.Now()
+	r.Status, r.ReviewedBy, r.ReviewedAt, r.ReviewNote, r.UpdatedAt = to, by, &now, note, now
+	err := model.ReviewLeaveRequest(r, from, ledger)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrLeaveStatus
+	}
+	return err
+}
+
+// reclassifyRemote 远程办公通过或撤销后改判期间内已有的打卡，申请状态已保存，失败时只告警
+func reclassifyRemote(r *model.LeaveRequest, approved bool) {
+	if err := report.ReclassifyRemote(r.UserId, r.StartDate, r.EndDate, approved); err != nil {
+		log.Log.WithAlarm().Errorf("reclassify punches of leave %d err:%v", r.ID, err)
+	}
+}
+
+// checkMonthsOpen 请假期间涉及的月份都未关账
+func checkMonthsOpen(begin, end time.Time) error {
+	for day := begin; !day.After(end); day = time.Date(day.Year(), day.Month()+1, 1, 0, 0, 0, 0, day.Location()) {
+		if err := report.CheckMonthOpen(day); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dayOf(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
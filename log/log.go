@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 	"tool-attendance/config"
+	"tool-attendance/utils/tz"
 
 	"github.com/sirupsen/logrus"
 )
@@ -86,7 +87,7 @@ func Init(config *config.LoggerConfig) error {
 			rotatelogs.WithClock(rotatelogs.Local),
 			rotatelogs.WithMaxAge(config.MaxAge*time.Hour),
 			rotatelogs.WithRotationTime(config.RotationTime*time.Hour),
-			rotatelogs.WithLocation(time.FixedZone("CST", 8*3600)),
+			rotatelogs.WithLocation(tz.Default()),
 			rotatelogs.WithLinkName(filePath),
 		)
 		if err != nil {
@@ -117,8 +118,7 @@ func (c *customerFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 
 	newLog := make(map[string]interface{}, len(entry.Data)+5)
 
-	var cstZone = time.FixedZone("CST", 8*3600)
-	newLog["time"] = entry.Time.In(cstZone).Format("2006-01-02 15:04:05")
+	newLog["time"] = entry.Time.In(tz.Default()).Format("2006-01-02 15:04:05")
 	newLog["level"] = entry.Level.String()
 	if entry.HasCaller() {
 		fileName := path.Base(entry.Caller.File)
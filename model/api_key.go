@@ -0,0 +1,79 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// API Key 权限范围
+const (
+	ApiKeyScopePunchWrite = "punch:write" // 上传打卡记录
+	ApiKeyScopeReportRead = "report:read" // 拉取考勤报表
+)
+
+var ApiKeyScopes = []string{ApiKeyScopePunchWrite, ApiKeyScopeReportRead}
+
+type ApiKey struct {
+	ID         int64      `gorm:"column:id;primaryKey" json:"id"`
+	Name       string     `gorm:"column:name;size:64" json:"name"`
+	Prefix     string     `gorm:"column:prefix;size:16;uniqueIndex" json:"prefix"`
+	KeyHash    string     `gorm:"column:key_hash;size:64" json:"-"`
+	Scopes     string     `gorm:"column:scopes" json:"scopes"` // 逗号分隔
+	ExpiredAt  *time.Time `gorm:"column:expired_at" json:"expired_at"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at" json:"last_used_at"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at" json:"revoked_at"`
+	CreatedBy  int64      `gorm:"column:created_by" json:"created_by"`
+	CreatedAt  time.Time  `gorm:"column:created_at" json:"created_at"`
+}
+
+func (k *ApiKey) HasScope(scope string) bool {
+	for _, v := range strings.Split(k.Scopes, ",") {
+		if v == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsActive 未吊销且未过期
+func (k *ApiKey) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiredAt == nil || now.Before(*k.ExpiredAt)
+}
+
+func CreateApiKey(k *ApiKey) error {
+	return db.Create(k).Error
+}
+
+func FindApiKeyByPrefix(prefix string) (*ApiKey, error) {
+	var k ApiKey
+	err := db.Model(&ApiKey{}).Where("prefix=?", prefix).First(&k).Error
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+func FindApiKeyList(page, limit int) ([]ApiKey, int64, error) {
+	var (
+		rows  []ApiKey
+		total int64
+	)
+	tx := db.Model(&ApiKey{})
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := tx.Order("id desc").Offset((page - 1) * limit).Limit(limit).Find(&rows).Error
+	return rows, total, err
+}
+
+func RevokeApiKey(id int64, t time.Time) (int64, error) {
+	res := db.Model(&ApiKey{}).Where("id=? and revoked_at is null", id).Update("revoked_at", t)
+	return res.RowsAffected, res.Error
+}
+
+func UpdateApiKeyLastUsed(id int64, t time.Time) error {
+	return db.Model(&ApiKey{}).Where("id=?", id).Update("last_used_at", t).Error
+}
@@ -0,0 +1,64 @@
+package model
+
+import "time"
+
+type AuditLog struct {
+	ID         int64     `gorm:"column:id;primaryKey" json:"id"`
+	ActorType  string    `gorm:"column:actor_type;size:16" json:"actor_type"` // user / api_key / device
+	ActorId    string    `gorm:"column:actor_id;size:64;index" json:"actor_id"`
+	ActorName  string    `gorm:"column:actor_name;size:64" json:"actor_name"`
+	Action     string    `gorm:"column:action;size:64;index" json:"action"`
+	TargetType string    `gorm:"column:target_type;size:32" json:"target_type"`
+	TargetId   string    `gorm:"column:target_id;size:64" json:"target_id"`
+	Before     string    `gorm:"column:before;type:text" json:"before"` // 变更前的值（json）
+	After      string    `gorm:"column:after;type:text" json:"after"`   // 变更后的值（json）
+	Ip         string    `gorm:"column:ip;size:64" json:"ip"`
+	UserAgent  string    `gorm:"column:user_agent;size:255" json:"user_agent"`
+	Method     string    `gorm:"column:method;size:8" json:"method"`
+	Path       string    `gorm:"column:path;size:255" json:"path"`
+	CreatedAt  time.Time `gorm:"column:created_at;index" json:"created_at"`
+}
+
+type AuditLogFilter struct {
+	ActorId    string
+	Action     string
+	TargetType string
+	TargetId   string
+	BeginTime  time.Time
+	EndTime    time.Time
+}
+
+func MulCreateAuditLog(list []*AuditLog) error {
+	return db.Model(&AuditLog{}).CreateInBatches(list, 100).Error
+}
+
+func FindAuditLogList(filter AuditLogFilter, page, limit int) ([]AuditLog, int64, error) {
+	var (
+		rows  []AuditLog
+		total int64
+	)
+	tx := db.Model(&AuditLog{})
+	if filter.ActorId != "" {
+		tx = tx.Where("actor_id=?", filter.ActorId)
+	}
+	if filter.Action != "" {
+		tx = tx.Where("action=?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		tx = tx.Where("target_type=?", filter.TargetType)
+	}
+	if filter.TargetId != "" {
+		tx = tx.Where("target_id=?", filter.TargetId)
+	}
+	if !filter.BeginTime.IsZero() {
+		tx = tx.Where("created_at>=?", filter.BeginTime)
+	}
+	if !filter.EndTime.IsZero() {
+		tx = tx.Where("created_at<=?", filter.EndTime)
+	}
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := tx.Order("id desc").Offset((page - 1) * limit).Limit(limit).Find(&rows).Error
+	return rows, total, err
+}
@@ -1,5 +1,7 @@
 package model
 
+import "gorm.io/gorm"
+
 const (
 	WorkDay = 1
 	RestDay = 2
@@ -30,3 +32,27 @@ func FindCalendarByMonth(year int64, month string) (map[string]Calendar, error)
 	}
 	return resMap, nil
 }
+
+// FindCalendarByYear 指定年份的日历，按日期排序
+func FindCalendarByYear(year int64) ([]Calendar, error) {
+	var rows []Calendar
+	err := db.Model(&Calendar{}).Where("year=?", year).Order("date").Find(&rows).Error
+	return rows, err
+}
+
+// ReplaceCalendarYear 用新拉取的日历替换指定年份的日历，keepMonths（如 "202401"）中的月份保留原有数据
+func ReplaceCalendarYear(year int64, keepMonths []string, list []Calendar) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		q := tx.Where("year=?", year)
+		if len(keepMonths) > 0 {
+			q = q.Where("month not in ?", keepMonths)
+		}
+		if err := q.Delete(&Calendar{}).Error; err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return nil
+		}
+		return tx.Model(&Calendar{}).CreateInBatches(list, 100).Error
+	})
+}
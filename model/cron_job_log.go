@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// 定时任务执行状态
+const (
+	CronJobRunning = "running"
+	CronJobSuccess = "success"
+	CronJobFailed  = "failed"
+)
+
+// 定时任务触发方式
+const (
+	CronTriggerSchedule = "schedule" // 按 cron 表达式触发
+	CronTriggerManual   = "manual"   // 通过接口手动触发
+)
+
+type CronJobLog struct {
+	ID          int64      `gorm:"column:id;primaryKey" json:"id"`
+	Name        string     `gorm:"column:name;size:64;index" json:"name"`
+	Trigger     string     `gorm:"column:trigger;size:16" json:"trigger"`
+	TriggeredBy string     `gorm:"column:triggered_by;size:64" json:"triggered_by"`
+	Status      string     `gorm:"column:status;size:16" json:"status"`
+	Error       string     `gorm:"column:error;type:text" json:"error"`
+	StartedAt   time.Time  `gorm:"column:started_at;index" json:"started_at"`
+	FinishedAt  *time.Time `gorm:"column:finished_at" json:"finished_at"`
+}
+
+func CreateCronJobLog(l *CronJobLog) error {
+	return db.Create(l).Error
+}
+
+func UpdateCronJobLog(id int64, values map[string]interface{}) error {
+	return db.Model(&CronJobLog{}).Where("id=?", id).Updates(values).Error
+}
+
+func FindCronJobLogList(name string, page, limit int) ([]CronJobLog, int64, error) {
+	var (
+		rows  []CronJobLog
+		total int64
+	)
+	tx := db.Model(&CronJobLog{})
+	if name != "" {
+		tx = tx.Where("name=?", name)
+	}
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := tx.Order("id desc").Offset((page - 1) * limit).Limit(limit).Find(&rows).Error
+	return rows, total, err
+}
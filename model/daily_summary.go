@@ -0,0 +1,142 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DailySummary 用户单日的考勤结果，由打卡记录按考勤规则计算，打卡、日历或时区变化时重新计算
+// 只保存有打卡记录的日期，工作日没有记录即为旷工；打卡合并时在同一事务中标记为 dirty，重新计算后清除
+type DailySummary struct {
+	ID           int64     `gorm:"column:id;primaryKey" json:"id"`
+	UserId       string    `gorm:"column:user_id;size:64;uniqueIndex:idx_daily_summary" json:"user_id"`
+	Day          time.Time `gorm:"column:day;uniqueIndex:idx_daily_summary;index" json:"day"` // 与 Record.DaysDate 一致
+	Firstname    string    `gorm:"column:firstname" json:"firstname"`
+	Username     string    `gorm:"column:username" json:"username"`
+	Workday      bool      `gorm:"column:workday" json:"workday"`
+	Present      bool      `gorm:"column:present" json:"present"`
+	Late         bool      `gorm:"column:late" json:"late"`
+	Early        bool      `gorm:"column:early" json:"early"`
+	Short        bool      `gorm:"column:short" json:"short"`
+	LackCard     bool      `gorm:"column:lack_card" json:"lack_card"`
+	LateMinutes  int       `gorm:"column:late_minutes" json:"late_minutes"`
+	EarlyMinutes int       `gorm:"column:early_minutes" json:"early_minutes"`
+	LateTier     string    `gorm:"column:late_tier;size:32" json:"late_tier"`
+	EarlyTier    string    `gorm:"column:early_tier;size:32" json:"early_tier"`
+	HalfAbsent   bool      `gorm:"column:half_absent" json:"half_absent"`
+	Duration     float64   `gorm:"column:duration" json:"duration"`
+	OnworkTime   time.Time `gorm:"column:onwork_time" json:"onwork_time"`
+	OffworkTime  time.Time `gorm:"column:offwork_time" json:"offwork_time"`
+	Place        string    `gorm:"column:place;size:16" json:"place"`  // 打卡地点，见 PunchLog.Place，没有打卡明细时为空
+	RulesHash    string    `gorm:"column:rules_hash;size:16" json:"-"` // 计算时使用的考勤规则，规则变化后需要重新计算
+	Dirty        bool      `gorm:"column:dirty" json:"-"`              // 打卡、地点或时区已变化，等待重新计算
+	UpdatedAt    time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// SummaryVersion 整月重新计算汇总时使用的考勤规则和日历，规则或日历与上一次不同时新增一条，
+// 修改规则或刷新日历后按之前的版本对比结果的变化。没有记录的月份还没有整月计算过，读取前需要先重新计算
+type SummaryVersion struct {
+	ID        int64     `gorm:"column:id;primaryKey" json:"id"`
+	Year      int       `gorm:"column:year;index:idx_summary_version" json:"year"`
+	Month     int       `gorm:"column:month;index:idx_summary_version" json:"month"`
+	RulesHash string    `gorm:"column:rules_hash;size:16" json:"rules_hash"`
+	Rules     string    `gorm:"column:rules;type:text" json:"rules"`     // 计算时使用的考勤规则（json）
+	Workdays  string    `gorm:"column:workdays;size:31" json:"workdays"` // 每天是否为工作日，1 为工作日，0 为休息日
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// FindLatestSummaryVersion 月份最近一次整月计算的版本
+func FindLatestSummaryVersion(year, month int) (*SummaryVersion, error) {
+	var v SummaryVersion
+	err := db.Model(&SummaryVersion{}).Where("year=? and month=?", year, month).Order("id desc").First(&v).Error
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// FindSummaryVersionList 月份整月计算过的版本，最近的在前
+func FindSummaryVersionList(year, month int) ([]SummaryVersion, error) {
+	var rows []SummaryVersion
+	err := db.Model(&SummaryVersion{}).Where("year=? and month=?", year, month).Order("id desc").Find(&rows).Error
+	return rows, err
+}
+
+func CreateSummaryVersion(v *SummaryVersion) error {
+	return db.Create(v).Error
+}
+
+// UpsertDailySummary 按用户和日期新增或覆盖
+func UpsertDailySummary(s *DailySummary) error {
+	return db.Clauses(clause.OnConflict{UpdateAll: true}).Create(s).Error
+}
+
+// markSummaryDirty 在事务中把用户某天的汇总标记为待重新计算，没有汇总时先插入一条
+func markSummaryDirty(tx *gorm.DB, r *Record) error {
+	return tx.Clauses(clause.OnConflict{DoUpdates: clause.Assignments(map[string]interface{}{"dirty": true})}).
+		Create(&DailySummary{
+			UserId:    r.UserId,
+			Day:       r.DaysDate,
+			Firstname: r.Firstname,
+			Username:  r.Username,
+			Dirty:     true,
+			UpdatedAt: time.Now(),
+		}).Error
+}
+
+// MarkUserSummaryDirty 把用户所有的汇总标记为待重新计算，用于员工或工作地点时区变化后
+func MarkUserSummaryDirty(userIds ...string) error {
+	if len(userIds) == 0 {
+		return nil
+	}
+	return db.Model(&DailySummary{}).Where("user_id in ?", userIds).Update("dirty", true).Error
+}
+
+func DeleteDailySummary(userId string, day time.Time) error {
+	return db.Where("user_id=? and day=?", userId, day).Delete(&DailySummary{}).Error
+}
+
+// ReplaceDailySummary 替换时间段内的汇总，userId 为空时替换所有用户
+func ReplaceDailySummary(userId string, beginDay, endDay time.Time, list []DailySummary) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		del := tx.Where("? <= day and day <= ?", beginDay, endDay)
+		if userId != "" {
+			del = del.Where("user_id=?", userId)
+		}
+		if err := del.Delete(&DailySummary{}).Error; err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return nil
+		}
+		return tx.Model(&DailySummary{}).CreateInBatches(list, 100).Error
+	})
+}
+
+func FindDailySummaryList(beginDay, endDay time.Time) ([]DailySummary, error) {
+	var rows []DailySummary
+	err := db.Model(&DailySummary{}).
+		Where("? <= day and day <= ?", beginDay, endDay).
+		Order("user_id, day").
+		Find(&rows).Error
+	return rows, err
+}
+
+// FindDailySummaryPage 按条件分页查询，userId 为空时查询所有用户
+func FindDailySummaryPage(userId string, beginDay, endDay time.Time, page, limit int) ([]DailySummary, int64, error) {
+	var (
+		rows  []DailySummary
+		total int64
+	)
+	tx := db.Model(&DailySummary{}).Where("? <= day and day <= ?", beginDay, endDay)
+	if userId != "" {
+		tx = tx.Where("user_id=?", userId)
+	}
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := tx.Order("day, user_id").Offset((page - 1) * limit).Limit(limit).Find(&rows).Error
+	return rows, total, err
+}
@@ -48,6 +48,36 @@ func Init(cfg *config.MysqlConfig) error {
 	return nil
 }
 
+// Migrate 创建或更新本服务新增的数据表
+func Migrate() error {
+	err := db.AutoMigrate(
+		&ApiKey{},
+		&AuditLog{},
+		&Employee{},
+		&Site{},
+		&ReportJob{},
+		&CronJobLog{},
+		&ReminderLog{},
+		&WebhookSubscription{},
+		&WebhookDelivery{},
+		&MonthClose{},
+		&MonthSnapshot{},
+		&DailySummary{},
+		&SummaryVersion{},
+		&LeaveRequest{},
+		&LeaveLedger{},
+		&PunchLog{},
+	)
+	if err != nil {
+		return err
+	}
+	// 打卡记录表由外部同步创建，这里只补充 (user_id, days_date) 唯一索引，避免并发上传时重复创建同一天的记录
+	if !db.Migrator().HasIndex(&Record{}, "idx_record_user_day") {
+		return db.Migrator().CreateIndex(&Record{}, "idx_record_user_day")
+	}
+	return nil
+}
+
 func GetDb() *gorm.DB {
 	return db
 }
@@ -0,0 +1,63 @@
+package model
+
+import "time"
+
+// Employee 员工的考勤设置，time_zone 为空时使用工作地点的时区
+type Employee struct {
+	UserId     string     `gorm:"column:user_id;primaryKey;size:64" json:"user_id"`
+	Name       string     `gorm:"column:name;size:64" json:"name"`
+	Email      string     `gorm:"column:email;size:128" json:"email"`
+	SiteId     int64      `gorm:"column:site_id" json:"site_id"`
+	Team       string     `gorm:"column:team;size:64;index" json:"team"`     // 所属团队，用于按团队统计
+	TimeZone   string     `gorm:"column:time_zone;size:64" json:"time_zone"` // IANA 时区名，如 Asia/Shanghai
+	HireDate   *time.Time `gorm:"column:hire_date" json:"hire_date"`
+	ResignDate *time.Time `gorm:"column:resign_date" json:"resign_date"` // 离职日期，最后一个工作日
+	CreatedAt  time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func FindEmployee(userId string) (*Employee, error) {
+	var e Employee
+	err := db.Model(&Employee{}).Where("user_id=?", userId).First(&e).Error
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func FindEmployeeMap() (map[string]Employee, error) {
+	var rows []Employee
+	err := db.Model(&Employee{}).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	resMap := make(map[string]Employee, len(rows))
+	for _, v := range rows {
+		resMap[v.UserId] = v
+	}
+	return resMap, nil
+}
+
+func FindEmployeeList(page, limit int) ([]Employee, int64, error) {
+	var (
+		rows  []Employee
+		total int64
+	)
+	tx := db.Model(&Employee{})
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := tx.Order("user_id").Offset((page - 1) * limit).Limit(limit).Find(&rows).Error
+	return rows, total, err
+}
+
+// SaveEmployee 不存在时创建，存在时更新
+func SaveEmployee(e *Employee) error {
+	return db.Save(e).Error
+}
+
+func FindEmployeeListBySite(siteId int64) ([]Employee, error) {
+	var rows []Employee
+	err := db.Model(&Employee{}).Where("site_id=?", siteId).Find(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,201 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 请假类型
+const (
+	LeaveTypeAnnual = "annual" // 年假，审批通过时从余额扣减
+	LeaveTypeUnpaid = "unpaid" // 事假，计入薪资扣款的无薪假天数
+	LeaveTypeSick   = "sick"   // 病假
+	LeaveTypeRemote = "remote" // 远程办公，批准期间在围栏外的打卡记为远程
+)
+
+// 请假申请状态
+const (
+	LeavePending   = "pending"
+	LeaveApproved  = "approved"
+	LeaveRejected  = "rejected"
+	LeaveCancelled = "cancelled"
+)
+
+// 年假流水类型
+const (
+	LedgerAccrual  = "accrual"  // 发放
+	LedgerUsage    = "usage"    // 请假扣减
+	LedgerReversal = "reversal" // 撤销已批准的请假，退回扣减的天数
+	LedgerExpiry   = "expiry"   // 超出结转上限或结转过期作废
+	LedgerAdjust   = "adjust"   // 人工调整
+)
+
+// LeaveRequest 请假申请，天数按日历中的工作日计算
+type LeaveRequest struct {
+	ID         int64      `gorm:"column:id;primaryKey" json:"id"`
+	UserId     string     `gorm:"column:user_id;size:64;index" json:"user_id"`
+	Type       string     `gorm:"column:type;size:16" json:"type"`
+	StartDate  time.Time  `gorm:"column:start_date;index" json:"start_date"`
+	EndDate    time.Time  `gorm:"column:end_date" json:"end_date"`
+	HalfDay    bool       `gorm:"column:half_day" json:"half_day"` // 只请一天中的半天
+	Days       float64    `gorm:"column:days" json:"days"`
+	Reason     string     `gorm:"column:reason;size:255" json:"reason"`
+	Status     string     `gorm:"column:status;size:16;index" json:"status"`
+	ReviewedBy string     `gorm:"column:reviewed_by;size:64" json:"reviewed_by"`
+	ReviewedAt *time.Time `gorm:"column:reviewed_at" json:"reviewed_at"`
+	ReviewNote string     `gorm:"column:review_note;size:255" json:"review_note"`
+	CreatedBy  string     `gorm:"column:created_by;size:64" json:"created_by"`
+	CreatedAt  time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// LeaveLedger 年假流水，余额为所有流水之和；按额度所属年份分开结算，扣减时从最早的年份开始
+type LeaveLedger struct {
+	ID        int64     `gorm:"column:id;primaryKey" json:"id"`
+	UserId    string    `gorm:"column:user_id;size:64;uniqueIndex:idx_leave_ledger" json:"user_id"`
+	Year      int       `gorm:"column:year" json:"year"` // 额度所属年份
+	Kind      string    `gorm:"column:kind;size:16" json:"kind"`
+	Days      float64   `gorm:"column:days" json:"days"`                                            // 增加为正，扣减为负
+	RefKey    string    `gorm:"column:ref_key;size:64;uniqueIndex:idx_leave_ledger" json:"ref_key"` // 幂等键，同一用户不重复记账
+	RequestId int64     `gorm:"column:request_id;index" json:"request_id"`                          // 关联的请假申请
+	Note      string    `gorm:"column:note;size:255" json:"note"`
+	CreatedBy string    `gorm:"column:created_by;size:64" json:"created_by"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// LeaveBucket 某一年份额度的剩余天数
+type LeaveBucket struct {
+	Year int     `json:"year"`
+	Days float64 `json:"days"`
+}
+
+func CreateLeaveRequest(r *LeaveRequest) error {
+	return db.Create(r).Error
+}
+
+func FindLeaveRequest(id int64) (*LeaveRequest, error) {
+	var r LeaveRequest
+	err := db.Model(&LeaveRequest{}).Where("id=?", id).First(&r).Error
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func FindLeaveRequestList(userId, status string, page, limit int) ([]LeaveRequest, int64, error) {
+	var (
+		rows  []LeaveRequest
+		total int64
+	)
+	tx := db.Model(&LeaveRequest{})
+	if userId != "" {
+		tx = tx.Where("user_id=?", userId)
+	}
+	if status != "" {
+		tx = tx.Where("status=?", status)
+	}
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := tx.Order("id desc").Offset((page - 1) * limit).Limit(limit).Find(&rows).Error
+	return rows, total, err
+}
+
+// FindApprovedLeaveList 与 [beginDay, endDay] 有交集的已批准请假
+func FindApprovedLeaveList(leaveType string, beginDay, endDay time.Time) ([]LeaveRequest, error) {
+	var rows []LeaveRequest
+	err := db.Model(&LeaveRequest{}).
+		Where("type=? and status=? and start_date<=? and end_date>=?", leaveType, LeaveApproved, endDay, beginDay).
+		Order("start_date").Find(&rows).Error
+	return rows, err
+}
+
+// HasApprovedLeave 用户某天是否有已批准的某类请假
+func HasApprovedLeave(userId, leaveType string, day time.Time) (bool, error) {
+	var n int64
+	err := db.Model(&LeaveRequest{}).
+		Where("user_id=? and type=? and status=? and start_date<=? and end_date>=?", userId, leaveType, LeaveApproved, day, day).
+		Count(&n).Error
+	return n > 0, err
+}
+
+// HasOverlappingLeave 用户在 [beginDay, endDay] 内是否已有待审批或已批准的申请
+func HasOverlappingLeave(userId string, beginDay, endDay time.Time) (bool, error) {
+	var n int64
+	err := db.Model(&LeaveRequest{}).
+		Where("user_id=? and status in ? and start_date<=? and end_date>=?", userId, []string{LeavePending, LeaveApproved}, endDay, beginDay).
+		Count(&n).Error
+	return n > 0, err
+}
+
+// SumPendingLeaveDays 待审批的请假天数
+func SumPendingLeaveDays(userId, leaveType string) (float64, error) {
+	var days float64
+	err := db.Model(&LeaveRequest{}).Select("coalesce(sum(days), 0)").
+		Where("user_id=? and type=? and status=?", userId, leaveType, LeavePending).Scan(&days).Error
+	return days, err
+}
+
+// ReviewLeaveRequest 更新处于 fromStatus 的申请并写入流水，申请已被其他操作修改时返回 gorm.ErrRecordNotFound
+func ReviewLeaveRequest(r *LeaveRequest, fromStatus string, ledger []LeaveLedger) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&LeaveRequest{}).Where("id=? and status=?", r.ID, fromStatus).Updates(map[string]interface{}{
+			"status":      r.Status,
+			"reviewed_by": r.ReviewedBy,
+			"reviewed_at": r.ReviewedAt,
+			"review_note": r.ReviewNote,
+			"updated_at":  r.UpdatedAt,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if len(ledger) > 0 {
+			return tx.Create(&ledger).Error
+		}
+		return nil
+	})
+}
+
+// CreateLeaveLedger 写入流水，ref_key 已存在的忽略
+func CreateLeaveLedger(rows []LeaveLedger) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+// FindLeaveBuckets 用户各年份额度的剩余天数，按年份从早到晚排列
+func FindLeaveBuckets(userId string) ([]LeaveBucket, error) {
+	var rows []LeaveBucket
+	err := db.Model(&LeaveLedger{}).Select("year, sum(days) as days").
+		Where("user_id=?", userId).Group("year").Order("year").Scan(&rows).Error
+	return rows, err
+}
+
+// FindLeaveLedgerByRequest 请假申请关联的流水
+func FindLeaveLedgerByRequest(requestId int64) ([]LeaveLedger, error) {
+	var rows []LeaveLedger
+	err := db.Model(&LeaveLedger{}).Where("request_id=?", requestId).Order("id").Find(&rows).Error
+	return rows, err
+}
+
+func FindLeaveLedgerList(userId string, year, page, limit int) ([]LeaveLedger, int64, error) {
+	var (
+		rows  []LeaveLedger
+		total int64
+	)
+	tx := db.Model(&LeaveLedger{}).Where("user_id=?", userId)
+	if year > 0 {
+		tx = tx.Where("year=?", year)
+	}
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := tx.Order("id desc").Offset((page - 1) * limit).Limit(limit).Find(&rows).Error
+	return rows, total, err
+}
@@ -0,0 +1,143 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrMonthClosed 月份已关账，不能再修改打卡和日历
+var ErrMonthClosed = errors.New("month is closed")
+
+// 关账状态
+const (
+	MonthClosed   = "closed"
+	MonthReopened = "reopened" // 重新开放后按打卡记录实时计算
+)
+
+// MonthClose 月度关账记录，关账后该月的打卡和日历不能再修改，报表从快照读取
+type MonthClose struct {
+	ID           int64      `gorm:"column:id;primaryKey" json:"id"`
+	Year         int        `gorm:"column:year;uniqueIndex:idx_month_close" json:"year"`
+	Month        int        `gorm:"column:month;uniqueIndex:idx_month_close" json:"month"`
+	Status       string     `gorm:"column:status;size:16" json:"status"`
+	TotalDay     int        `gorm:"column:total_day" json:"total_day"`
+	NeedWorkDay  int        `gorm:"column:need_work_day" json:"need_work_day"`
+	Rules        string     `gorm:"column:rules;type:text" json:"rules"` // 关账时使用的考勤规则（json）
+	ClosedBy     string     `gorm:"column:closed_by;size:64" json:"closed_by"`
+	ClosedAt     time.Time  `gorm:"column:closed_at" json:"closed_at"`
+	ReopenedBy   string     `gorm:"column:reopened_by;size:64" json:"reopened_by"`
+	ReopenedAt   *time.Time `gorm:"column:reopened_at" json:"reopened_at"`
+	ReopenReason string     `gorm:"column:reopen_reason;size:255" json:"reopen_reason"`
+}
+
+// MonthSnapshot 关账时用户的月度考勤结果
+type MonthSnapshot struct {
+	ID            int64  `gorm:"column:id;primaryKey" json:"id"`
+	Year          int    `gorm:"column:year;index:idx_month_snapshot" json:"year"`
+	Month         int    `gorm:"column:month;index:idx_month_snapshot" json:"month"`
+	UserId        string `gorm:"column:user_id;size:64" json:"user_id"`
+	Firstname     string `gorm:"column:firstname" json:"firstname"`
+	Username      string `gorm:"column:username" json:"username"`
+	WorkDay       int    `gorm:"column:work_day" json:"work_day"`
+	AbsentDay     int    `gorm:"column:absent_day" json:"absent_day"`
+	LateDay       int    `gorm:"column:late_day" json:"late_day"`
+	EarlyDay      int    `gorm:"column:early_day" json:"early_day"`
+	ShortDay      int    `gorm:"column:short_day" json:"short_day"`
+	LackCardDay   int    `gorm:"column:lack_card_day" json:"lack_card_day"`
+	LateMinutes   int    `gorm:"column:late_minutes" json:"late_minutes"`
+	EarlyMinutes  int    `gorm:"column:early_minutes" json:"early_minutes"`
+	HalfAbsentDay int    `gorm:"column:half_absent_day" json:"half_absent_day"`
+	OutOfFenceDay int    `gorm:"column:out_of_fence_day" json:"out_of_fence_day"`
+	RemoteDay     int    `gorm:"column:remote_day" json:"remote_day"`
+	Days          string `gorm:"column:days;type:mediumtext" json:"-"` // 每日考勤结果（json）
+}
+
+func FindMonthClose(year, month int) (*MonthClose, error) {
+	var m MonthClose
+	err := db.Model(&MonthClose{}).Where("year=? and month=?", year, month).First(&m).Error
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func FindMonthCloseList(year int) ([]MonthClose, error) {
+	var rows []MonthClose
+	err := db.Model(&MonthClose{}).Where("year=?", year).Order("month").Find(&rows).Error
+	return rows, err
+}
+
+// FindClosedMonths 指定年份已关账的月份
+func FindClosedMonths(year int) ([]int, error) {
+	var months []int
+	err := db.Model(&MonthClose{}).Where("year=? and status=?", year, MonthClosed).Order("month").Pluck("month", &months).Error
+	return months, err
+}
+
+// lockMonthOpen 在事务中锁定该月的关账记录（SELECT ... FOR UPDATE），已关账时返回 ErrMonthClosed。
+// 关账在同一行上加锁，正在合并的打卡提交后才能关账，关账提交后的打卡会被拒绝
+func lockMonthOpen(tx *gorm.DB, year, month int) error {
+	var m MonthClose
+	err := tx.Model(&MonthClose{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("year=? and month=?", year, month).Take(&m).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if m.Status == MonthClosed {
+		return ErrMonthClosed
+	}
+	return nil
+}
+
+// SaveMonthClose 在事务中锁定该月的关账记录（没有时先创建），由 build 填写关账信息并生成快照，再替换该月的快照。
+// 已关账时返回 ErrMonthClosed；锁定期间打卡合并会等待，快照与打卡记录一致
+func SaveMonthClose(year, month int, build func(m *MonthClose) ([]MonthSnapshot, error)) (*MonthClose, error) {
+	var m MonthClose
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&MonthClose{Year: year, Month: month}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&MonthClose{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("year=? and month=?", year, month).Take(&m).Error
+		if err != nil {
+			return err
+		}
+		if m.Status == MonthClosed {
+			return ErrMonthClosed
+		}
+		snapshots, err := build(&m)
+		if err != nil {
+			return err
+		}
+		if err = tx.Where("year=? and month=?", year, month).Delete(&MonthSnapshot{}).Error; err != nil {
+			return err
+		}
+		if len(snapshots) > 0 {
+			if err = tx.Model(&MonthSnapshot{}).CreateInBatches(snapshots, 100).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(&m).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func UpdateMonthClose(id int64, values map[string]interface{}) error {
+	return db.Model(&MonthClose{}).Where("id=?", id).Updates(values).Error
+}
+
+func FindMonthSnapshots(year, month int) ([]MonthSnapshot, error) {
+	var rows []MonthSnapshot
+	err := db.Model(&MonthSnapshot{}).Where("year=? and month=?", year, month).Order("id").Find(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 打卡地点
+const (
+	PlaceOffice     = "office"       // 在工作地点的围栏内，或工作地点未配置围栏
+	PlaceRemote     = "remote"       // 在围栏外，但当天有已批准的远程办公
+	PlaceOutOfFence = "out_of_fence" // 在围栏外且未批准远程办公
+	PlaceUnknown    = "unknown"      // 工作地点配置了围栏，但打卡时没有上报可比较的位置信息
+)
+
+// PunchLog 单次打卡的明细：设备上报的位置信息和按工作地点围栏判定的打卡地点。
+// Record 只保存每天的上下班时间，打卡地点按天汇总见 report.DayResult.Place。
+// 地点在打卡时判定，之后修改工作地点的围栏或员工所属的工作地点只影响新的打卡，已有明细不重新判定
+
+type PunchLog struct {
+	ID        int64     `gorm:"column:id;primaryKey" json:"id"`
+	UserId    string    `gorm:"column:user_id;size:64;uniqueIndex:idx_punch_log;index:idx_punch_log_day" json:"user_id"`
+	Day       time.Time `gorm:"column:day;index:idx_punch_log_day" json:"day"` // 与 Record.DaysDate 一致
+	PunchTime time.Time `gorm:"column:punch_time;uniqueIndex:idx_punch_log" json:"punch_time"`
+	Latitude  *float64  `gorm:"column:latitude" json:"latitude"`
+	Longitude *float64  `gorm:"column:longitude" json:"longitude"`
+	Bssid     string    `gorm:"column:bssid;size:32" json:"bssid"`
+	Ip        string    `gorm:"column:ip;size:64" json:"ip"`   // 上传打卡的客户端 IP，不使用设备上报的 IP
+	SiteId    int64     `gorm:"column:site_id" json:"site_id"` // 判定时员工所属的工作地点
+	Place     string    `gorm:"column:place;size:16" json:"place"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// FindPunchPlaceList 时间段内的打卡地点，userId 为空时查询所有用户
+func FindPunchPlaceList(userId string, beginDay, endDay time.Time) ([]PunchLog, error) {
+	var rows []PunchLog
+	tx := db.Model(&PunchLog{}).Select("user_id", "day", "place").Where("? <= day and day <= ?", beginDay, endDay)
+	if userId != "" {
+		tx = tx.Where("user_id=?", userId)
+	}
+	err := tx.Find(&rows).Error
+	return rows, err
+}
+
+// UpdatePunchPlace 将用户时间段内地点为 from 的打卡改为 to，用于远程办公审批通过或撤销后；同一事务中把期间的汇总标记为 dirty
+func UpdatePunchPlace(userId string, beginDay, endDay time.Time, from, to string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&PunchLog{}).
+			Where("user_id=? and ? <= day and day <= ? and place=?", userId, beginDay, endDay, from).
+			Update("place", to).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&DailySummary{}).
+			Where("user_id=? and ? <= day and day <= ?", userId, beginDay, endDay).
+			Update("dirty", true).Error
+	})
+}
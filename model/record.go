@@ -1,12 +1,17 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
 
 type Record struct {
-	UserId      string    `gorm:"column:user_id" json:"user_id"`
+	UserId      string    `gorm:"column:user_id;uniqueIndex:idx_record_user_day" json:"user_id"`
 	Firstname   string    `gorm:"column:firstname" json:"firstname"`
 	Username    string    `gorm:"column:username" json:"username"`
-	DaysDate    time.Time `gorm:"column:days_date" json:"days_date"`
+	DaysDate    time.Time `gorm:"column:days_date;uniqueIndex:idx_record_user_day" json:"days_date"`
 	OnworkTime  time.Time `gorm:"column:onwork_time" json:"onwork_time"`
 	OffworkTime time.Time `gorm:"column:offwork_time" json:"offwork_time"`
 }
@@ -21,6 +26,56 @@ func FindRecordList(beginDay, endDay time.Time) ([]Record, error) {
 	return raws, err
 }
 
+func FindRecordByUserDay(userId string, day time.Time) (*Record, error) {
+	var r Record
+	err := db.Model(&Record{}).Where("user_id=? and days_date=?", userId, day).Take(&r).Error
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// MergeRecordPunch 将一次打卡合并进 r 所在日期的记录：当天没有记录时以 r 创建，否则在事务中锁定记录并按 merge 计算新的上下班时间。
+// 依赖 (user_id, days_date) 唯一索引，并发上传同一天的打卡不会重复创建记录；返回记录是否有变化。
+// 同一事务中先锁定 year 年 month 月的关账记录，已关账时返回 ErrMonthClosed；打卡明细 l 与记录一起保存，当天的汇总标记为 dirty
+func MergeRecordPunch(r *Record, l *PunchLog, year, month int, merge func(onworkTime, offworkTime time.Time) (time.Time, time.Time)) (bool, error) {
+	changed := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := lockMonthOpen(tx, year, month); err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(l).Error; err != nil {
+			return err
+		}
+		if err := markSummaryDirty(tx, r); err != nil {
+			return err
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(r)
+		if res.Error != nil || res.RowsAffected > 0 {
+			changed = res.RowsAffected > 0
+			return res.Error
+		}
+		var cur Record
+		err := tx.Model(&Record{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id=? and days_date=?", r.UserId, r.DaysDate).Take(&cur).Error
+		if err != nil {
+			return err
+		}
+		onworkTime, offworkTime := merge(cur.OnworkTime, cur.OffworkTime)
+		if onworkTime.Equal(cur.OnworkTime) && offworkTime.Equal(cur.OffworkTime) {
+			return nil
+		}
+		changed = true
+		return tx.Model(&Record{}).
+			Where("user_id=? and days_date=?", r.UserId, r.DaysDate).
+			Updates(map[string]interface{}{
+				"onwork_time":  onworkTime,
+				"offwork_time": offworkTime,
+			}).Error
+	})
+	return changed, err
+}
+
 type RecordList []Record
 
 func (l RecordList) Len() int {
@@ -44,3 +99,9 @@ func (l RecordList) Less(i, j int) bool {
 		}
 	}
 }
+
+func FindRecordListByUser(userId string) ([]Record, error) {
+	var raws []Record
+	err := db.Model(&Record{}).Where("user_id=?", userId).Order("days_date").Find(&raws).Error
+	return raws, err
+}
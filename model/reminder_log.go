@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// 提醒渠道
+const (
+	ReminderChannelMail    = "mail"
+	ReminderChannelWebhook = "webhook"
+)
+
+// ReminderLog 已发送的提醒，同一员工同一天同一渠道只提醒一次
+type ReminderLog struct {
+	ID        int64     `gorm:"column:id;primaryKey" json:"id"`
+	Kind      string    `gorm:"column:kind;size:32;uniqueIndex:idx_reminder" json:"kind"`
+	UserId    string    `gorm:"column:user_id;size:64;uniqueIndex:idx_reminder" json:"user_id"`
+	Day       time.Time `gorm:"column:day;uniqueIndex:idx_reminder" json:"day"`
+	Channel   string    `gorm:"column:channel;size:16;uniqueIndex:idx_reminder" json:"channel"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func CreateReminderLog(l *ReminderLog) error {
+	return db.Create(l).Error
+}
+
+// FindReminderLogSet 某天已发送的提醒，key 为 user_id:channel
+func FindReminderLogSet(kind string, day time.Time) (map[string]bool, error) {
+	var rows []ReminderLog
+	err := db.Model(&ReminderLog{}).Where("kind=? and day=?", kind, day).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(rows))
+	for _, v := range rows {
+		set[v.UserId+":"+v.Channel] = true
+	}
+	return set, nil
+}
@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// 报表任务状态
+const (
+	ReportJobPending = "pending"
+	ReportJobRunning = "running"
+	ReportJobSuccess = "success"
+	ReportJobFailed  = "failed"
+	ReportJobExpired = "expired" // 文件已超过保留时长被清理
+)
+
+type ReportJob struct {
+	ID          string     `gorm:"column:id;primaryKey;size:36" json:"id"`
+	Kind        string     `gorm:"column:kind;size:32" json:"kind"`
+	Year        int        `gorm:"column:year" json:"year"`
+	Month       int        `gorm:"column:month" json:"month"`
+	Lang        string     `gorm:"column:lang;size:16" json:"lang"`
+	Status      string     `gorm:"column:status;size:16;index" json:"status"`
+	FilePath    string     `gorm:"column:file_path" json:"-"`
+	ObjectKey   string     `gorm:"column:object_key" json:"object_key"` // 归档到对象存储的 key，未归档时为空
+	Error       string     `gorm:"column:error;type:text" json:"error"`
+	CallbackUrl string     `gorm:"column:callback_url" json:"callback_url"`
+	CreatedBy   string     `gorm:"column:created_by;size:64" json:"created_by"`
+	CreatedAt   time.Time  `gorm:"column:created_at" json:"created_at"`
+	StartedAt   *time.Time `gorm:"column:started_at" json:"started_at"`
+	FinishedAt  *time.Time `gorm:"column:finished_at" json:"finished_at"`
+	ExpiredAt   *time.Time `gorm:"column:expired_at;index" json:"expired_at"`
+}
+
+func CreateReportJob(j *ReportJob) error {
+	return db.Create(j).Error
+}
+
+func FindReportJob(id string) (*ReportJob, error) {
+	var j ReportJob
+	err := db.Model(&ReportJob{}).Where("id=?", id).First(&j).Error
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+func UpdateReportJob(id string, values map[string]interface{}) error {
+	return db.Model(&ReportJob{}).Where("id=?", id).Updates(values).Error
+}
+
+// FindUnfinishedReportJobs 服务重启前未完成的任务
+func FindUnfinishedReportJobs() ([]ReportJob, error) {
+	var rows []ReportJob
+	err := db.Model(&ReportJob{}).
+		Where("status in ?", []string{ReportJobPending, ReportJobRunning}).
+		Order("created_at").
+		Find(&rows).Error
+	return rows, err
+}
+
+// FindExpiredReportJobs 已过保留期但文件尚未清理的任务
+func FindExpiredReportJobs(now time.Time) ([]ReportJob, error) {
+	var rows []ReportJob
+	err := db.Model(&ReportJob{}).
+		Where("status=? and expired_at<=?", ReportJobSuccess, now).
+		Find(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// Site 工作地点
+type Site struct {
+	ID        int64     `gorm:"column:id;primaryKey" json:"id"`
+	Name      string    `gorm:"column:name;size:64" json:"name"`
+	TimeZone  string    `gorm:"column:time_zone;size:64" json:"time_zone"`   // IANA 时区名，为空时使用默认时区
+	Latitude  float64   `gorm:"column:latitude" json:"latitude"`             // 围栏中心纬度，围栏用于判定打卡地点，见 PunchLog
+	Longitude float64   `gorm:"column:longitude" json:"longitude"`           // 围栏中心经度
+	Radius    int       `gorm:"column:radius" json:"radius"`                 // 围栏半径（米），为 0 时不按坐标判断
+	Bssids    string    `gorm:"column:bssids;type:text" json:"bssids"`       // 办公室 Wi-Fi 的 BSSID，逗号分隔
+	IpRanges  string    `gorm:"column:ip_ranges;type:text" json:"ip_ranges"` // 办公室出口 IP 段（CIDR 或单个 IP），逗号分隔；只用于没有上报坐标和 Wi-Fi 的打卡
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func FindSite(id int64) (*Site, error) {
+	var s Site
+	err := db.Model(&Site{}).Where("id=?", id).First(&s).Error
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func FindSiteMap() (map[int64]Site, error) {
+	var rows []Site
+	err := db.Model(&Site{}).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	resMap := make(map[int64]Site, len(rows))
+	for _, v := range rows {
+		resMap[v.ID] = v
+	}
+	return resMap, nil
+}
+
+func FindSiteList() ([]Site, error) {
+	var rows []Site
+	err := db.Model(&Site{}).Order("id").Find(&rows).Error
+	return rows, err
+}
+
+func SaveSite(s *Site) error {
+	return db.Save(s).Error
+}
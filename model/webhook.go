@@ -0,0 +1,121 @@
+package model
+
+import "time"
+
+// WebhookSubscription 外部系统订阅的事件推送
+type WebhookSubscription struct {
+	ID        int64     `gorm:"column:id;primaryKey" json:"id"`
+	Name      string    `gorm:"column:name;size:64" json:"name"`
+	Url       string    `gorm:"column:url;size:512" json:"url"`
+	Events    string    `gorm:"column:events;size:255" json:"events"` // 订阅的事件，逗号分隔
+	Secret    string    `gorm:"column:secret;size:128" json:"-"`      // 签名密钥，仅在创建时返回
+	Active    bool      `gorm:"column:active" json:"active"`
+	CreatedBy string    `gorm:"column:created_by;size:64" json:"created_by"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// 推送状态
+const (
+	WebhookDeliveryPending = "pending" // 等待推送或等待重试
+	WebhookDeliverySuccess = "success"
+	WebhookDeliveryFailed  = "failed" // 重试次数用尽
+)
+
+// WebhookDelivery 一次事件推送，失败后按 next_retry_at 重试
+type WebhookDelivery struct {
+	ID             int64      `gorm:"column:id;primaryKey" json:"id"`
+	SubscriptionId int64      `gorm:"column:subscription_id;index" json:"subscription_id"`
+	EventId        string     `gorm:"column:event_id;size:36;index" json:"event_id"` // 重新推送时保持不变，接收方可据此去重
+	Event          string     `gorm:"column:event;size:64" json:"event"`
+	Payload        string     `gorm:"column:payload;type:text" json:"payload"`
+	Status         string     `gorm:"column:status;size:16" json:"status"`
+	Attempts       int        `gorm:"column:attempts" json:"attempts"`
+	ResponseCode   int        `gorm:"column:response_code" json:"response_code"`
+	ResponseBody   string     `gorm:"column:response_body;type:text" json:"response_body"`
+	Error          string     `gorm:"column:error;type:text" json:"error"`
+	RedeliveryOf   int64      `gorm:"column:redelivery_of" json:"redelivery_of"` // 手动重新推送时原推送的 id
+	NextRetryAt    *time.Time `gorm:"column:next_retry_at;index" json:"next_retry_at"`
+	CreatedAt      time.Time  `gorm:"column:created_at" json:"created_at"`
+	FinishedAt     *time.Time `gorm:"column:finished_at" json:"finished_at"`
+}
+
+func CreateWebhookSubscription(s *WebhookSubscription) error {
+	return db.Create(s).Error
+}
+
+func SaveWebhookSubscription(s *WebhookSubscription) error {
+	return db.Save(s).Error
+}
+
+func DeleteWebhookSubscription(id int64) error {
+	return db.Where("id=?", id).Delete(&WebhookSubscription{}).Error
+}
+
+func FindWebhookSubscription(id int64) (*WebhookSubscription, error) {
+	var s WebhookSubscription
+	err := db.Model(&WebhookSubscription{}).Where("id=?", id).First(&s).Error
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func FindWebhookSubscriptionList() ([]WebhookSubscription, error) {
+	var rows []WebhookSubscription
+	err := db.Model(&WebhookSubscription{}).Order("id").Find(&rows).Error
+	return rows, err
+}
+
+func FindActiveWebhookSubscriptions() ([]WebhookSubscription, error) {
+	var rows []WebhookSubscription
+	err := db.Model(&WebhookSubscription{}).Where("active=?", true).Find(&rows).Error
+	return rows, err
+}
+
+func CreateWebhookDelivery(d *WebhookDelivery) error {
+	return db.Create(d).Error
+}
+
+func FindWebhookDelivery(id int64) (*WebhookDelivery, error) {
+	var d WebhookDelivery
+	err := db.Model(&WebhookDelivery{}).Where("id=?", id).First(&d).Error
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func UpdateWebhookDelivery(id int64, values map[string]interface{}) error {
+	return db.Model(&WebhookDelivery{}).Where("id=?", id).Updates(values).Error
+}
+
+// FindDueWebhookDeliveries 到了推送时间的待推送记录
+func FindDueWebhookDeliveries(now time.Time, limit int) ([]WebhookDelivery, error) {
+	var rows []WebhookDelivery
+	err := db.Model(&WebhookDelivery{}).
+		Where("status=? and next_retry_at<=?", WebhookDeliveryPending, now).
+		Order("next_retry_at").
+		Limit(limit).
+		Find(&rows).Error
+	return rows, err
+}
+
+func FindWebhookDeliveryList(subscriptionId int64, status string, page, limit int) ([]WebhookDelivery, int64, error) {
+	var (
+		rows  []WebhookDelivery
+		total int64
+	)
+	tx := db.Model(&WebhookDelivery{})
+	if subscriptionId > 0 {
+		tx = tx.Where("subscription_id=?", subscriptionId)
+	}
+	if status != "" {
+		tx = tx.Where("status=?", status)
+	}
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := tx.Order("id desc").Offset((page - 1) * limit).Limit(limit).Find(&rows).Error
+	return rows, total, err
+}
@@ -0,0 +1,271 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"tool-attendance/config"
+	"tool-attendance/model"
+	"tool-attendance/utils/i18n"
+	"tool-attendance/utils/tz"
+)
+
+// 异常类型
+const (
+	AnomalyOffBeforeOn   = "off_before_on"  // 下班打卡早于上班打卡
+	AnomalyTooLong       = "too_long"       // 上下班打卡间隔过长，见配置 anomaly.max_hours
+	AnomalyOutOfService  = "out_of_service" // 打卡日期不在入职至离职期间
+	AnomalySameTimestamp = "same_timestamp" // 与其他人的打卡时间精确到秒相同，可能代打卡
+	AnomalyPatternShift  = "pattern_shift"  // 上班打卡时间与近期习惯相差过大
+)
+
+// Anomaly 一条异常打卡，一天的记录命中多种异常时每种各一条
+type Anomaly struct {
+	UserId       string    `json:"user_id"`
+	Name         string    `json:"name"`
+	Day          string    `json:"day"` // 2006-01-02
+	Kind         string    `json:"kind"`
+	OnworkTime   time.Time `json:"onwork_time"`             // 员工所在时区
+	OffworkTime  time.Time `json:"offwork_time"`            // 员工所在时区
+	Duration     float64   `json:"duration,omitempty"`      // too_long：上下班打卡间隔（小时）
+	UsualArrival string    `json:"usual_arrival,omitempty"` // pattern_shift：近期上班打卡时间的中位数，15:04
+	Related      []string  `json:"related,omitempty"`       // same_timestamp：打卡时间相同的其他用户
+
+	Location *time.Location `json:"-"`
+}
+
+// Anomalies 检测 [begin, end] 期间的异常打卡，按用户、日期排列。
+// 作息突变以之前的上班打卡为参照，会额外查询 begin 之前的记录
+func Anomalies(begin, end time.Time, cfg config.AnomalyConfig) ([]Anomaly, error) {
+	cfg = anomalyDefaults(cfg)
+	// 按每周 5 个工作日估算参照期
+	from := begin.AddDate(0, 0, -(cfg.ShiftWindow*7/5 + 7))
+	records, err := model.FindRecordList(from, end)
+	if err != nil {
+		return nil, err
+	}
+	zones, err := NewZoneResolver()
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(model.RecordList(records))
+	return detectAnomalies(records, zones, begin, cfg), nil
+}
+
+func anomalyDefaults(cfg config.AnomalyConfig) config.AnomalyConfig {
+	if cfg.MaxHours <= 0 {
+		cfg.MaxHours = 16
+	}
+	if cfg.ShiftMinutes <= 0 {
+		cfg.ShiftMinutes = 180
+	}
+	if cfg.ShiftWindow <= 0 {
+		cfg.ShiftWindow = 20
+	}
+	if cfg.ShiftMinDays <= 0 {
+		cfg.ShiftMinDays = 5
+	}
+	return cfg
+}
+
+// detectAnomalies records 需按用户、日期排序，只返回 begin 及之后的异常，之前的记录仅作为参照
+func detectAnomalies(records []model.Record, zones *ZoneResolver, begin time.Time, cfg config.AnomalyConfig) []Anomaly {
+	defaultLoc := tz.Default()
+	stamps := punchStamps(records)
+
+	var (
+		list     []Anomaly
+		lastUser string
+		arrivals []int // 当前用户近期的上班打卡时间（分钟）
+	)
+	for _, r := range records {
+		if r.UserId != lastUser {
+			lastUser, arrivals = r.UserId, nil
+		}
+		loc := zones.Location(r.UserId)
+		day := r.DaysDate.In(defaultLoc)
+		check := !day.Before(getZeroTime(begin.In(defaultLoc)))
+		base := Anomaly{
+			UserId:   r.UserId,
+			Name:     r.Username,
+			Day:      day.Format(formatDayTime),
+			Location: loc,
+		}
+		if base.Name == "" {
+			base.Name = r.Firstname
+		}
+		if !r.OnworkTime.IsZero() {
+			base.OnworkTime = r.OnworkTime.In(loc)
+		}
+		if !r.OffworkTime.IsZero() {
+			base.OffworkTime = r.OffworkTime.In(loc)
+		}
+		flag := func(kind string) *Anomaly {
+			list = append(list, base)
+			a := &list[len(list)-1]
+			a.Kind = kind
+			return a
+		}
+
+		if check {
+			if !r.OnworkTime.IsZero() && !r.OffworkTime.IsZero() {
+				hours := r.OffworkTime.Sub(r.OnworkTime).Hours()
+				if hours < 0 {
+					flag(AnomalyOffBeforeOn)
+				} else if hours > cfg.MaxHours {
+					flag(AnomalyTooLong).Duration = math.Round(hours*10) / 10
+				}
+			}
+			if !inService(base.Day, zones.employees[r.UserId], defaultLoc) {
+				flag(AnomalyOutOfService)
+			}
+			if related := stamps.others(r); len(related) > 0 {
+				flag(AnomalySameTimestamp).Related = related
+			}
+		}
+
+		if r.OnworkTime.IsZero() {
+			continue
+		}
+		t := r.OnworkTime.In(loc)
+		minute := t.Hour()*60 + t.Minute()
+		if check && len(arrivals) >= cfg.ShiftMinDays {
+			usual := medianMinute(arrivals)
+			if diff := minute - usual; diff > cfg.ShiftMinutes || -diff > cfg.ShiftMinutes {
+				flag(AnomalyPatternShift).UsualArrival = fmt.Sprintf("%02d:%02d", usual/60, usual%60)
+			}
+		}
+		arrivals = append(arrivals, minute)
+		if len(arrivals) > cfg.ShiftWindow {
+			arrivals = arrivals[1:]
+		}
+	}
+	return list
+}
+
+// inService 打卡日期是否在入职至离职期间，未填写的日期不限制
+func inService(day string, e model.Employee, loc *time.Location) bool {
+	if e.HireDate != nil && day < e.HireDate.In(loc).Format(formatDayTime) {
+		return false
+	}
+	if e.ResignDate != nil && day > e.ResignDate.In(loc).Format(formatDayTime) {
+		return false
+	}
+	return true
+}
+
+// stampIndex 打卡时间（秒）到打卡用户的索引
+type stampIndex map[int64][]string
+
+func punchStamps(records []model.Record) stampIndex {
+	idx := make(stampIndex)
+	add := func(t time.Time, userId string) {
+		if t.IsZero() {
+			return
+		}
+		users := idx[t.Unix()]
+		for _, v := range users {
+			if v == userId {
+				return
+			}
+		}
+		idx[t.Unix()] = append(users, userId)
+	}
+	for _, r := range records {
+		add(r.OnworkTime, r.UserId)
+		add(r.OffworkTime, r.UserId)
+	}
+	return idx
+}
+
+// others 与该记录任一次打卡时间相同的其他用户
+func (idx stampIndex) others(r model.Record) []string {
+	var res []string
+	for _, t := range []time.Time{r.OnworkTime, r.OffworkTime} {
+		if t.IsZero() {
+			continue
+		}
+		for _, userId := range idx[t.Unix()] {
+			if userId != r.UserId && !containsString(res, userId) {
+				res = append(res, userId)
+			}
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func medianMinute(list []int) int {
+	sorted := append([]int(nil), list...)
+	sort.Ints(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// describe 异常说明，用于报表
+func (a Anomaly) describe(lang string) string {
+	switch a.Kind {
+	case AnomalyTooLong:
+		return i18n.T(lang, "anomaly.desc.too_long", a.Duration)
+	case AnomalyPatternShift:
+		return i18n.T(lang, "anomaly.desc.pattern_shift", a.UsualArrival)
+	case AnomalySameTimestamp:
+		return i18n.T(lang, "anomaly.desc.same_timestamp", strings.Join(a.Related, ", "))
+	}
+	return ""
+}
+
+// addAnomalySheet 在工作簿中追加当月的异常打卡工作表
+func addAnomalySheet(f *excelize.File, year, month int, lang string) error {
+	begin := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, tz.Default())
+	list, err := Anomalies(begin, getLastDateOfMonth(begin), config.GetConfig().Anomaly)
+	if err != nil {
+		return err
+	}
+	return writeAnomalySheet(f, list, lang)
+}
+
+func writeAnomalySheet(f *excelize.File, list []Anomaly, lang string) error {
+	sheetName := i18n.T(lang, "anomaly.sheet")
+	if _, err := f.NewSheet(sheetName); err != nil {
+		return err
+	}
+	head := []interface{}{
+		i18n.T(lang, "payroll.user_id"), i18n.T(lang, "report.name"), i18n.T(lang, "report.date"),
+		i18n.T(lang, "anomaly.kind"), i18n.T(lang, "report.onwork"), i18n.T(lang, "report.offwork"),
+		i18n.T(lang, "anomaly.desc"),
+	}
+	_ = f.SetSheetRow(sheetName, "A1", &head)
+	for i, a := range list {
+		onWork, offWork := "", ""
+		if !a.OnworkTime.IsZero() {
+			onWork = a.OnworkTime.Format(formatTime)
+		}
+		if !a.OffworkTime.IsZero() {
+			offWork = a.OffworkTime.Format(formatTime)
+		}
+		row := []interface{}{a.UserId, a.Name, a.Day, i18n.T(lang, "anomaly.kind."+a.Kind), onWork, offWork, a.describe(lang)}
+		_ = f.SetSheetRow(sheetName, cellName(1, i+2), &row)
+	}
+	styleHead, _ := getExcelStyle(f, cellStyleHead)
+	_ = f.SetCellStyle(sheetName, "A1", cellName(len(head), 1), styleHead)
+	_ = f.SetColWidth(sheetName, "A", "F", 12)
+	_ = f.SetColWidth(sheetName, "G", "G", 30)
+	return nil
+}
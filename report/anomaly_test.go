@@ -0,0 +1,94 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"tool-attendance/config"
+	"tool-attendance/model"
+	"tool-attendance/utils/i18n"
+	"tool-attendance/utils/tz"
+)
+
+func TestDetectAnomalies(t *testing.T) {
+	loc := tz.Default()
+	day := func(d int) time.Time { return time.Date(2023, 5, d, 0, 0, 0, 0, loc) }
+	at := func(d, hour, minute int) time.Time { return time.Date(2023, 5, d, hour, minute, 0, 0, loc) }
+	hire, resign := day(3), day(20)
+	zones := &ZoneResolver{employees: map[string]model.Employee{
+		"u2": {UserId: "u2", HireDate: &hire, ResignDate: &resign},
+	}}
+
+	records := []model.Record{
+		// 参照期，不报告
+		{UserId: "u1", DaysDate: day(1), OnworkTime: at(1, 9, 0), OffworkTime: at(1, 8, 0)},
+		{UserId: "u1", DaysDate: day(2), OnworkTime: at(2, 9, 5)},
+		{UserId: "u1", DaysDate: day(3), OnworkTime: at(3, 8, 55)},
+		{UserId: "u1", DaysDate: day(4), OnworkTime: at(4, 9, 10), OffworkTime: at(4, 18, 0)},
+		{UserId: "u1", DaysDate: day(5), OnworkTime: at(5, 9, 0), OffworkTime: at(5, 18, 0)},
+		// 作息突变 + 打卡间隔过长
+		{UserId: "u1", DaysDate: day(8), OnworkTime: at(8, 13, 30), OffworkTime: at(9, 6, 0)},
+		{UserId: "u1", DaysDate: day(10), OnworkTime: at(10, 18, 0), OffworkTime: at(10, 9, 0)},
+		// 入职前、与 u1 相同的打卡时间
+		{UserId: "u2", DaysDate: day(2), OnworkTime: at(2, 9, 0)},
+		{UserId: "u2", DaysDate: day(10), OnworkTime: at(10, 18, 0)},
+		{UserId: "u2", DaysDate: day(20), OnworkTime: at(20, 9, 0)},
+		{UserId: "u2", DaysDate: day(21), OnworkTime: at(21, 9, 0)},
+	}
+	list := detectAnomalies(records, zones, day(8), anomalyDefaults(config.AnomalyConfig{}))
+
+	type key struct{ user, day, kind string }
+	got := make(map[key]Anomaly)
+	for _, v := range list {
+		got[key{v.UserId, v.Day, v.Kind}] = v
+	}
+	want := []key{
+		{"u1", "2023-05-08", AnomalyPatternShift},
+		{"u1", "2023-05-08", AnomalyTooLong},
+		{"u1", "2023-05-10", AnomalyOffBeforeOn},
+		{"u1", "2023-05-10", AnomalySameTimestamp},
+		{"u1", "2023-05-10", AnomalyPatternShift},
+		{"u2", "2023-05-10", AnomalySameTimestamp},
+		{"u2", "2023-05-21", AnomalyOutOfService},
+	}
+	if len(list) != len(want) {
+		t.Fatalf("anomalies = %+v", list)
+	}
+	for _, k := range want {
+		if _, ok := got[k]; !ok {
+			t.Errorf("missing %v in %+v", k, list)
+		}
+	}
+	if a := got[key{"u1", "2023-05-08", AnomalyTooLong}]; a.Duration != 16.5 {
+		t.Errorf("duration = %v", a.Duration)
+	}
+	if a := got[key{"u1", "2023-05-08", AnomalyPatternShift}]; a.UsualArrival != "09:00" {
+		t.Errorf("usual arrival = %s", a.UsualArrival)
+	}
+	if a := got[key{"u2", "2023-05-10", AnomalySameTimestamp}]; len(a.Related) != 1 || a.Related[0] != "u1" {
+		t.Errorf("related = %v", a.Related)
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := writeAnomalySheet(f, list, i18n.ZhCN); err != nil {
+		t.Fatal(err)
+	}
+	rows, _ := f.GetRows(i18n.T(i18n.ZhCN, "anomaly.sheet"))
+	if len(rows) != len(list)+1 {
+		t.Fatalf("rows = %v", rows)
+	}
+}
+
+func TestInService(t *testing.T) {
+	loc := tz.Default()
+	hire := time.Date(2023, 5, 3, 0, 0, 0, 0, loc)
+	e := model.Employee{HireDate: &hire}
+	if inService("2023-05-02", e, loc) || !inService("2023-05-03", e, loc) || !inService("2099-01-01", e, loc) {
+		t.Error("hire date")
+	}
+	if !inService("2000-01-01", model.Employee{}, loc) {
+		t.Error("no dates")
+	}
+}
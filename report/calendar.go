@@ -0,0 +1,186 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"tool-attendance/log"
+	"tool-attendance/model"
+	"tool-attendance/utils/tz"
+)
+
+// InitCalendar 从节假日接口拉取指定年份的日历并入库，已关账月份的日历不写入
+func InitCalendar(year int) error {
+	closed, err := closedCalendarMonths(year)
+	if err != nil {
+		return err
+	}
+	dayList, err := fetchCalendar(year)
+	if err != nil {
+		return err
+	}
+	dayList = openDays(dayList, closed)
+	if len(dayList) == 0 {
+		return nil
+	}
+	return model.MulCreateDate(dayList)
+}
+
+// RefreshCalendar 重新拉取指定年份的日历并覆盖已有数据，用于节假日安排公布后更新；已关账月份保留原有日历
+func RefreshCalendar(year int) error {
+	closed, err := closedCalendarMonths(year)
+	if err != nil {
+		return err
+	}
+	if len(closed) == 12 {
+		return ErrMonthClosed
+	}
+	dayList, err := fetchCalendar(year)
+	if err != nil {
+		return err
+	}
+	dayList = openDays(dayList, closed)
+	if len(dayList) == 0 {
+		return fmt.Errorf("calendar of %d is empty", year)
+	}
+	keep := make([]string, 0, len(closed))
+	for m := range closed {
+		keep = append(keep, m)
+	}
+	if err = model.ReplaceCalendarYear(int64(year), keep, dayList); err != nil {
+		return err
+	}
+	submitRebuild(fmt.Sprintf("year %d", year), func() { rebuildYearSummary(year) })
+	return nil
+}
+
+// closedCalendarMonths 年内已关账的月份，键与日历的 month 字段一致（如 "202401"）
+func closedCalendarMonths(year int) (map[string]bool, error) {
+	months, err := model.FindClosedMonths(year)
+	if err != nil {
+		return nil, err
+	}
+	closed := make(map[string]bool, len(months))
+	for _, m := range months {
+		closed[fmt.Sprintf("%d%02d", year, m)] = true
+	}
+	return closed, nil
+}
+
+// openDays 去掉已关账月份的日期，关账后这些月份的日历不再变化
+func openDays(list []model.Calendar, closed map[string]bool) []model.Calendar {
+	if len(closed) == 0 {
+		return list
+	}
+	res := make([]model.Calendar, 0, len(list))
+	for _, v := range list {
+		if !closed[v.Month] {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func fetchCalendar(year int) ([]model.Calendar, error) {
+	list, err := getCalendar(year, 366)
+	if err != nil {
+		return nil, err
+	}
+	dayList := make([]model.Calendar, 0, len(list))
+	for _, v := range list {
+		dayList = append(dayList, model.Calendar{
+			ID:      0,
+			Year:    v.Year,
+			Month:   fmt.Sprintf("%d", v.Month),
+			Date:    fmt.Sprintf("%d", v.Date),
+			Week:    v.Week,
+			Workday: v.Workday,
+		})
+	}
+	return dayList, nil
+}
+
+// loadCalendar 获取某月的日历，当年日历未初始化时自动初始化
+func loadCalendar(year, month int) (map[string]model.Calendar, error) {
+	calendarMap, err := model.FindCalendarByMonth(int64(year), fmt.Sprintf("%d%02d", year, month))
+	if err != nil {
+		return nil, err
+	}
+	if len(calendarMap) > 0 {
+		return calendarMap, nil
+	}
+	// 初始化日历
+	if err = InitCalendar(year); err != nil {
+		return nil, err
+	}
+	return model.FindCalendarByMonth(int64(year), fmt.Sprintf("%d%02d", year, month))
+}
+
+// CountWorkdays [beginDay, endDay] 期间日历中的工作日天数，按默认时区的日期计算
+func CountWorkdays(beginDay, endDay time.Time) (int, error) {
+	loc := tz.Default()
+	beginDay, endDay = getZeroTime(beginDay.In(loc)), getZeroTime(endDay.In(loc))
+	n := 0
+	for first := getFirstDateOfMonth(beginDay); !first.After(endDay); first = first.AddDate(0, 1, 0) {
+		calendarMap, err := loadCalendar(first.Year(), int(first.Month()))
+		if err != nil {
+			return 0, err
+		}
+		_, workdays := newMonthResult(first.Year(), int(first.Month()), calendarMap)
+		for i := 1; i < len(workdays); i++ {
+			day := first.AddDate(0, 0, i-1)
+			if workdays[i] && !day.Before(beginDay) && !day.After(endDay) {
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
+type dayInfo struct {
+	Year    int64 `json:"year"`
+	Month   int64 `json:"month"`
+	Date    int64 `json:"date"`
+	Week    uint8 `json:"week"`
+	Workday uint8 `json:"workday"`
+}
+
+type resCalendar struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+	Data struct {
+		List  []dayInfo `json:"list"`
+		Page  int       `json:"page"`
+		Size  int       `json:"size"`
+		Total int       `json:"total"`
+	} `json:"data"`
+}
+
+func getCalendar(year, size int) ([]dayInfo, error) {
+	_url := fmt.Sprintf("https://api.apihubs.cn/holiday/get?year=%d&size=%d", year, size)
+	req, _ := http.NewRequest("GET", _url, nil)
+	//req.Header.Set("accept", "application/json")
+	//req.Header.Set("X-API-KEY", nftScanAPIKey)
+	srcResp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		log.Log.Error("Get err:", err)
+		return nil, err
+	}
+	body, _ := ioutil.ReadAll(srcResp.Body)
+	resp := resCalendar{}
+	err = json.Unmarshal(body, &resp)
+	if err != nil {
+		log.Log.Error("Unmarshal err: body:", err, string(body))
+		return nil, err
+	}
+	if resp.Code != 0 {
+		log.Log.Error("fail.", resp.Msg)
+		return nil, errors.New(resp.Msg)
+	}
+
+	return resp.Data.List, nil
+}
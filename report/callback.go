@@ -0,0 +1,80 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"tool-attendance/config"
+	"tool-attendance/utils/safehttp"
+)
+
+// ErrCallbackUrl 回调地址不是 http(s)，或指向内网、回环等不允许访问的地址
+var ErrCallbackUrl = errors.New("callback_url is not allowed")
+
+// 只连接公网地址的客户端
+var publicClient = safehttp.NewClient(10 * time.Second)
+
+// 回调 callback_hosts 中配置的主机，允许内网地址
+var trustedClient = &http.Client{
+	Timeout:       10 * time.Second,
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+// trustedHost 主机是否在 report.callback_hosts 中
+func trustedHost(host string) bool {
+	for _, v := range config.GetConfig().Report.CallbackHosts {
+		if strings.EqualFold(v, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckCallbackUrl 校验回调地址：只能是 http(s)；配置了 callback_hosts 时主机必须在其中，
+// 否则主机解析出的地址都必须是公网地址
+func CheckCallbackUrl(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrCallbackUrl
+	}
+	host := u.Hostname()
+	if len(config.GetConfig().Report.CallbackHosts) > 0 {
+		if !trustedHost(host) {
+			return ErrCallbackUrl
+		}
+		return nil
+	}
+	if err = safehttp.CheckHost(host); errors.Is(err, safehttp.ErrNotAllowed) {
+		return ErrCallbackUrl
+	} else if err != nil {
+		return fmt.Errorf("%w: %v", ErrCallbackUrl, err)
+	}
+	return nil
+}
+
+// postCallback 以 json POST 回调地址，返回状态码
+func postCallback(raw string, payload interface{}) (int, error) {
+	if err := CheckCallbackUrl(raw); err != nil {
+		return 0, err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	client := publicClient
+	if u, _ := url.Parse(raw); trustedHost(u.Hostname()) {
+		client = trustedClient
+	}
+	resp, err := client.Post(raw, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, nil
+}
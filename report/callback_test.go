@@ -0,0 +1,33 @@
+package report
+
+import (
+	"errors"
+	"testing"
+
+	"tool-attendance/config"
+)
+
+func TestCheckCallbackUrl(t *testing.T) {
+	config.Cfg.Report.CallbackHosts = nil
+	for _, v := range []string{
+		"ftp://8.8.8.8/cb", "http://127.0.0.1:8080/cb", "http://10.1.2.3/cb", "http://169.254.169.254/latest",
+		"http://[::1]/cb", "http://[::ffff:192.168.1.1]/cb", "not a url",
+	} {
+		if err := CheckCallbackUrl(v); !errors.Is(err, ErrCallbackUrl) {
+			t.Errorf("%s: err = %v", v, err)
+		}
+	}
+	if err := CheckCallbackUrl("https://8.8.8.8/cb"); err != nil {
+		t.Errorf("public ip: %v", err)
+	}
+
+	// 配置了 callback_hosts 时只允许列出的主机，内网地址也可以
+	config.Cfg.Report.CallbackHosts = []string{"10.1.2.3"}
+	defer func() { config.Cfg.Report.CallbackHosts = nil }()
+	if err := CheckCallbackUrl("http://10.1.2.3/cb"); err != nil {
+		t.Errorf("trusted host: %v", err)
+	}
+	if err := CheckCallbackUrl("https://8.8.8.8/cb"); !errors.Is(err, ErrCallbackUrl) {
+		t.Errorf("host not in callback_hosts: %v", err)
+	}
+}
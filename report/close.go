@@ -0,0 +1,188 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"tool-attendance/model"
+	"tool-attendance/utils/tz"
+	"tool-attendance/webhook"
+)
+
+var (
+	ErrMonthClosed    = model.ErrMonthClosed
+	ErrMonthNotClosed = errors.New("month is not closed")
+	ErrMonthNotEnded  = errors.New("month has not ended")
+)
+
+// CloseMonth 按当前规则计算月度考勤并保存为快照，之后该月的报表都从快照读取；已关账的月份需先重新开放
+func CloseMonth(year, month int, closedBy string) (*model.MonthClose, error) {
+	end := time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, tz.Default())
+	if time.Now().Before(end) {
+		return nil, ErrMonthNotEnded
+	}
+	// 在锁定关账记录后计算，计算期间的打卡等关账提交后会被拒绝
+	var res *MonthResult
+	m, err := model.SaveMonthClose(year, month, func(m *model.MonthClose) ([]model.MonthSnapshot, error) {
+		rules := CurrentRules()
+		var err error
+		if res, err = ComputeWithRules(year, month, rules); err != nil {
+			return nil, err
+		}
+		snapshots := make([]model.MonthSnapshot, 0, len(res.Users))
+		for _, u := range res.Users {
+			v, err := toSnapshot(year, month, u)
+			if err != nil {
+				return nil, err
+			}
+			snapshots = append(snapshots, v)
+		}
+		rulesJson, _ := json.Marshal(rules)
+
+		m.Status = model.MonthClosed
+		m.TotalDay, m.NeedWorkDay = res.TotalDay, res.NeedWorkDay
+		m.Rules = string(rulesJson)
+		m.ClosedBy, m.ClosedAt = closedBy, time.Now()
+		return snapshots, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	webhook.Publish(webhook.EventMonthFinalised, map[string]interface{}{
+		"year":          year,
+		"month":         month,
+		"need_work_day": res.NeedWorkDay,
+		"users":         len(res.Users),
+		"closed_at":     m.ClosedAt.Unix(),
+	})
+	return m, nil
+}
+
+// ReopenMonth 重新开放已关账的月份，快照保留到下次关账时替换
+func ReopenMonth(year, month int, reopenedBy, reason string) (*model.MonthClose, error) {
+	m, err := model.FindMonthClose(year, month)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMonthNotClosed
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.Status != model.MonthClosed {
+		return nil, ErrMonthNotClosed
+	}
+	now := time.Now()
+	m.Status, m.ReopenedBy, m.ReopenedAt, m.ReopenReason = model.MonthReopened, reopenedBy, &now, reason
+	err = model.UpdateMonthClose(m.ID, map[string]interface{}{
+		"status":        m.Status,
+		"reopened_by":   reopenedBy,
+		"reopened_at":   now,
+		"reopen_reason": reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CheckMonthOpen day（默认时区）所在的月份已关账时返回 ErrMonthClosed
+func CheckMonthOpen(day time.Time) error {
+	day = day.In(tz.Default())
+	m, err := model.FindMonthClose(day.Year(), int(day.Month()))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if m.Status == model.MonthClosed {
+		return ErrMonthClosed
+	}
+	return nil
+}
+
+// loadSnapshot 已关账月份的考勤结果，未关账时返回 nil
+func loadSnapshot(year, month int) (*MonthResult, error) {
+	m, err := model.FindMonthClose(year, month)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil || m.Status != model.MonthClosed {
+		return nil, err
+	}
+	rows, err := model.FindMonthSnapshots(year, month)
+	if err != nil {
+		return nil, err
+	}
+	zones, err := NewZoneResolver()
+	if err != nil {
+		return nil, err
+	}
+	res := &MonthResult{
+		Year:        year,
+		Month:       month,
+		TotalDay:    m.TotalDay,
+		NeedWorkDay: m.NeedWorkDay,
+		Users:       make([]UserResult, 0, len(rows)),
+	}
+	for _, v := range rows {
+		u, err := fromSnapshot(v, zones.Location(v.UserId))
+		if err != nil {
+			return nil, err
+		}
+		res.Users = append(res.Users, u)
+	}
+	return res, nil
+}
+
+func toSnapshot(year, month int, u UserResult) (model.MonthSnapshot, error) {
+	days, err := json.Marshal(u.Days)
+	if err != nil {
+		return model.MonthSnapshot{}, err
+	}
+	return model.MonthSnapshot{
+		Year:          year,
+		Month:         month,
+		UserId:        u.UserId,
+		Firstname:     u.Firstname,
+		Username:      u.Username,
+		WorkDay:       u.Stat.WorkDay,
+		AbsentDay:     u.Stat.AbsentDay,
+		LateDay:       u.Stat.LateDay,
+		EarlyDay:      u.Stat.EarlyDay,
+		ShortDay:      u.Stat.ShortDay,
+		LackCardDay:   u.Stat.LackCardDay,
+		LateMinutes:   u.Stat.LateMinutes,
+		EarlyMinutes:  u.Stat.EarlyMinutes,
+		HalfAbsentDay: u.Stat.HalfAbsentDay,
+		OutOfFenceDay: u.Stat.OutOfFenceDay,
+		RemoteDay:     u.Stat.RemoteDay,
+		Days:          string(days),
+	}, nil
+}
+
+func fromSnapshot(v model.MonthSnapshot, loc *time.Location) (UserResult, error) {
+	u := UserResult{
+		UserId:    v.UserId,
+		Firstname: v.Firstname,
+		Username:  v.Username,
+		Location:  loc,
+		Stat: UserStat{
+			WorkDay:       v.WorkDay,
+			AbsentDay:     v.AbsentDay,
+			LateDay:       v.LateDay,
+			EarlyDay:      v.EarlyDay,
+			ShortDay:      v.ShortDay,
+			LackCardDay:   v.LackCardDay,
+			LateMinutes:   v.LateMinutes,
+			EarlyMinutes:  v.EarlyMinutes,
+			HalfAbsentDay: v.HalfAbsentDay,
+			OutOfFenceDay: v.OutOfFenceDay,
+			RemoteDay:     v.RemoteDay,
+		},
+	}
+	err := json.Unmarshal([]byte(v.Days), &u.Days)
+	return u, err
+}
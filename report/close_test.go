@@ -0,0 +1,50 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"tool-attendance/model"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+	u := UserResult{
+		UserId:    "u1",
+		Firstname: "张三",
+		Location:  loc,
+		Days: []DayResult{
+			{Day: 1, Workday: true, Present: true, Late: true, OnworkTime: time.Date(2023, 5, 1, 9, 40, 0, 0, loc), Duration: 8.5},
+			{Day: 2, Workday: true, Absent: true},
+		},
+		Stat: UserStat{WorkDay: 1, AbsentDay: 1, LateDay: 1},
+	}
+	v, err := toSnapshot(2023, 5, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Year != 2023 || v.Month != 5 || v.LateDay != 1 || v.AbsentDay != 1 {
+		t.Fatalf("snapshot = %+v", v)
+	}
+	got, err := fromSnapshot(v, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Stat != u.Stat || got.Name() != "张三" || len(got.Days) != 2 {
+		t.Fatalf("user = %+v", got)
+	}
+	if !got.Days[0].OnworkTime.Equal(u.Days[0].OnworkTime) || !got.Days[0].Late || !got.Days[1].OffworkTime.IsZero() || !got.Days[1].Absent {
+		t.Fatalf("days = %+v", got.Days)
+	}
+}
+
+func TestOpenDays(t *testing.T) {
+	list := []model.Calendar{{Month: "202401", Date: "20240101"}, {Month: "202402", Date: "20240201"}, {Month: "202403", Date: "20240301"}}
+	got := openDays(list, map[string]bool{"202402": true})
+	if len(got) != 2 || got[0].Month != "202401" || got[1].Month != "202403" {
+		t.Fatalf("open days = %+v", got)
+	}
+	if got = openDays(list, nil); len(got) != 3 {
+		t.Fatalf("open days without closed months = %+v", got)
+	}
+}
@@ -0,0 +1,336 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"tool-attendance/config"
+	"tool-attendance/model"
+)
+
+// 统计备注：
+// 出勤：工作日只要有打卡记录
+// 旷工：工作日无打卡记录
+// 迟到：工作日上班打卡在上班时间（默认 9:30）后
+// 早退：工作日下班打卡在下班时间（默认 18:00）前
+// 时长不足：工作日上下班打卡记录都有，但不足规定时长（默认 9 小时）
+// 漏打卡：工作日只有上班卡，或只有下班卡
+// 迟到、早退分钟数：与上下班时间相差的分钟数，按规则 tiers 分档，可配置某一档按半天旷工处理
+
+// Rules 考勤规则
+type Rules = config.RulesConfig
+
+// CurrentRules 配置文件中的考勤规则，未配置的项使用默认值
+func CurrentRules() Rules {
+	r := config.GetConfig().Rules
+	if r.OnWorkTime == "" {
+		r.OnWorkTime = "09:30"
+	}
+	if r.OffWorkTime == "" {
+		r.OffWorkTime = "18:00"
+	}
+	if r.MinHours <= 0 {
+		r.MinHours = 9
+	}
+	return r
+}
+
+// RulesFromJSON 在当前规则的基础上覆盖 JSON 中填写的项，并校验规则
+func RulesFromJSON(data []byte) (Rules, error) {
+	rules := CurrentRules()
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &rules); err != nil {
+			return rules, err
+		}
+	}
+	if _, err := parseRules(rules); err != nil {
+		return rules, err
+	}
+	return rules, nil
+}
+
+// clock 解析 15:04 格式的时间
+func clock(s string) (int, int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid rule time %q: %w", s, err)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// dayRules 解析后的考勤规则
+type dayRules struct {
+	onHour, onMinute   int
+	offHour, offMinute int
+	minHours           float64
+	tiers              []config.LatenessTier // 按上限从小到大排列，不设上限的档位在最后
+}
+
+func parseRules(rules Rules) (*dayRules, error) {
+	r := &dayRules{minHours: rules.MinHours, tiers: sortLatenessTiers(rules.Tiers)}
+	var err error
+	if r.onHour, r.onMinute, err = clock(rules.OnWorkTime); err != nil {
+		return nil, err
+	}
+	if r.offHour, r.offMinute, err = clock(rules.OffWorkTime); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func sortLatenessTiers(tiers []config.LatenessTier) []config.LatenessTier {
+	sorted := append([]config.LatenessTier(nil), tiers...)
+	sortByMaxMinutes(sorted, func(i int) int { return sorted[i].MaxMinutes })
+	return sorted
+}
+
+// sortByMaxMinutes 把分档按上限从小到大排列，上限为 0（不设上限）的档位放在最后；maxMinutes 返回 tiers[i] 的上限
+func sortByMaxMinutes(tiers interface{}, maxMinutes func(i int) int) {
+	sort.SliceStable(tiers, func(i, j int) bool {
+		a, b := maxMinutes(i), maxMinutes(j)
+		if a == 0 || b == 0 {
+			return b == 0 && a != 0
+		}
+		return a < b
+	})
+}
+
+// tier 分钟数所在的档位，未配置或没有匹配的档位时返回 nil
+func (r *dayRules) tier(minutes int) *config.LatenessTier {
+	for i := range r.tiers {
+		if r.tiers[i].MaxMinutes == 0 || minutes <= r.tiers[i].MaxMinutes {
+			return &r.tiers[i]
+		}
+	}
+	return nil
+}
+
+// DayResult 用户单日的考勤结果，非工作日只记录打卡时间，不做判定
+type DayResult struct {
+	Day         int       `json:"day"`
+	Workday     bool      `json:"workday"`
+	OnworkTime  time.Time `json:"onwork_time"`  // 未打卡为零值
+	OffworkTime time.Time `json:"offwork_time"` // 未打卡为零值
+	Present     bool      `json:"present"`      // 出勤
+	Absent      bool      `json:"absent"`       // 旷工
+	Late        bool      `json:"late"`         // 迟到
+	Early       bool      `json:"early"`        // 早退
+	Short       bool      `json:"short"`        // 时长不足
+	LackCard    bool      `json:"lack_card"`    // 漏打卡
+	Duration    float64   `json:"duration"`     // 工作时长（小时），上下班卡都有时才计算
+
+	LateMinutes  int    `json:"late_minutes"`         // 迟到分钟数，不足一分钟按一分钟计
+	EarlyMinutes int    `json:"early_minutes"`        // 早退分钟数，不足一分钟按一分钟计
+	LateTier     string `json:"late_tier,omitempty"`  // 迟到分档，见规则 tiers
+	EarlyTier    string `json:"early_tier,omitempty"` // 早退分档
+	HalfAbsent   bool   `json:"half_absent"`          // 迟到或早退落在半天旷工的档位
+	Place        string `json:"place,omitempty"`      // 打卡地点，见 mergePlace；没有打卡明细时为空
+}
+
+// UserStat 用户当月统计
+type UserStat struct {
+	WorkDay     int `json:"work_day"`      // 出勤天数（有一次打卡就算出勤）
+	AbsentDay   int `json:"absent_day"`    // 旷工天数（工作日一次打卡记录也没有）
+	LateDay     int `json:"late_day"`      // 迟到天数
+	EarlyDay    int `json:"early_day"`     // 早退天数
+	ShortDay    int `json:"short_day"`     // 时长不足天数
+	LackCardDay int `json:"lack_card_day"` // 漏打卡天数
+
+	LateMinutes   int `json:"late_minutes"`    // 迟到分钟合计
+	EarlyMinutes  int `json:"early_minutes"`   // 早退分钟合计
+	HalfAbsentDay int `json:"half_absent_day"` // 按半天旷工处理的天数
+
+	OutOfFenceDay int `json:"out_of_fence_day"` // 在围栏外打卡的天数
+	RemoteDay     int `json:"remote_day"`       // 远程办公的天数
+}
+
+// UserResult 用户当月的考勤结果，Days 按日期排列，下标为日期减 1
+type UserResult struct {
+	UserId    string         `json:"user_id"`
+	Firstname string         `json:"firstname"`
+	Username  string         `json:"username"`
+	Location  *time.Location `json:"-"`
+	Days      []DayResult    `json:"days"`
+	Stat      UserStat       `json:"stat"`
+}
+
+// add 记入单日结果并累加统计
+func (u *UserResult) add(day DayResult) {
+	u.Days[day.Day-1] = day
+	if day.Present {
+		u.Stat.WorkDay++
+	}
+	if day.Absent {
+		u.Stat.AbsentDay++
+	}
+	if day.Late {
+		u.Stat.LateDay++
+	}
+	if day.Early {
+		u.Stat.EarlyDay++
+	}
+	if day.Short {
+		u.Stat.ShortDay++
+	}
+	if day.LackCard {
+		u.Stat.LackCardDay++
+	}
+	if day.HalfAbsent {
+		u.Stat.HalfAbsentDay++
+	}
+	switch day.Place {
+	case model.PlaceOutOfFence:
+		u.Stat.OutOfFenceDay++
+	case model.PlaceRemote:
+		u.Stat.RemoteDay++
+	}
+	u.Stat.LateMinutes += day.LateMinutes
+	u.Stat.EarlyMinutes += day.EarlyMinutes
+}
+
+// Name 显示的姓名，优先使用 username
+func (u UserResult) Name() string {
+	if u.Username != "" {
+		return u.Username
+	}
+	return u.Firstname
+}
+
+// MonthResult 月度考勤结果，只包含当月有打卡记录的用户
+type MonthResult struct {
+	Year        int          `json:"year"`
+	Month       int          `json:"month"`
+	TotalDay    int          `json:"total_day"`     // 当月天数
+	NeedWorkDay int          `json:"need_work_day"` // 应出勤天数
+	Users       []UserResult `json:"users"`
+}
+
+// Compute 按当前规则计算月度考勤：已关账的月份返回关账时的快照，否则从每日汇总组装
+func Compute(year, month int) (*MonthResult, error) {
+	res, err := loadSnapshot(year, month)
+	if err != nil || res != nil {
+		return res, err
+	}
+	return computeFromSummary(year, month)
+}
+
+// ComputeWithRules 按指定规则从打卡记录实时计算月度考勤，不读取关账快照
+func ComputeWithRules(year, month int, rules Rules) (*MonthResult, error) {
+	d, err := loadMonth(year, month)
+	if err != nil {
+		return nil, err
+	}
+	return compute(year, month, d, rules)
+}
+
+func compute(year, month int, d *monthData, rules Rules) (*MonthResult, error) {
+	r, err := parseRules(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	res, workdays := newMonthResult(year, month, d.calendarMap)
+	res.Users = make([]UserResult, 0, len(d.userRecords))
+	for _, userRecordList := range d.userRecords {
+		last := userRecordList[len(userRecordList)-1]
+		user := UserResult{
+			UserId:    last.UserId,
+			Firstname: last.Firstname,
+			Username:  last.Username,
+			Location:  d.zones.Location(last.UserId),
+			Days:      make([]DayResult, res.TotalDay),
+		}
+
+		// 用户打卡记录 map
+		userRecordMap := make(map[string]model.Record, len(userRecordList))
+		for _, v := range userRecordList {
+			userRecordMap[v.DaysDate.In(d.defaultLoc).Format(formatDayTime)] = v
+		}
+
+		for i := 1; i <= res.TotalDay; i++ {
+			dayTimeStr := time.Date(year, time.Month(month), i, 0, 0, 0, 0, d.defaultLoc).Format(formatDayTime)
+			if record, ok := userRecordMap[dayTimeStr]; ok {
+				day := classifyDay(year, month, i, workdays[i], record, user.Location, r)
+				day.Place = d.places[placeKey(record.UserId, record.DaysDate)]
+				user.add(day)
+			} else {
+				user.add(noRecordDay(i, workdays[i]))
+			}
+		}
+		res.Users = append(res.Users, user)
+	}
+	return res, nil
+}
+
+// newMonthResult 按日历初始化月度结果，workdays 下标为日期
+func newMonthResult(year, month int, calendarMap map[string]model.Calendar) (*MonthResult, []bool) {
+	res := &MonthResult{
+		Year:     year,
+		Month:    month,
+		TotalDay: getYearMonthToDay(year, month),
+	}
+	workdays := make([]bool, res.TotalDay+1)
+	for i := 1; i <= res.TotalDay; i++ {
+		workdays[i] = calendarMap[fmt.Sprintf("%d%02d%02d", year, month, i)].Workday == model.WorkDay
+		if workdays[i] {
+			res.NeedWorkDay++
+		}
+	}
+	return res, workdays
+}
+
+// noRecordDay 没有打卡记录的一天：工作日算旷工，休息日不做判定
+func noRecordDay(day int, workday bool) DayResult {
+	return DayResult{Day: day, Workday: workday, Absent: workday}
+}
+
+// classifyDay 判定有打卡记录的一天，上下班时间按用户所在时区比较
+func classifyDay(year, month, i int, workday bool, record model.Record, loc *time.Location, r *dayRules) DayResult {
+	day := DayResult{Day: i, Workday: workday, OnworkTime: record.OnworkTime, OffworkTime: record.OffworkTime}
+	if !workday {
+		// 休息日
+		return day
+	}
+
+	// 当日存在用户的打卡记录
+	day.Present = true
+	onWorkLimitTime := time.Date(year, time.Month(month), i, r.onHour, r.onMinute, 0, 0, loc)
+	offWorkLimitTime := time.Date(year, time.Month(month), i, r.offHour, r.offMinute, 0, 0, loc)
+	if !record.OnworkTime.IsZero() {
+		if late := record.OnworkTime.Sub(onWorkLimitTime); late > 0 {
+			day.Late = true
+			day.LateMinutes = ceilMinutes(late)
+			if t := r.tier(day.LateMinutes); t != nil {
+				day.LateTier = t.Name
+				day.HalfAbsent = day.HalfAbsent || t.HalfDayAbsent
+			}
+		}
+	} else {
+		day.LackCard = true
+	}
+	if !record.OffworkTime.IsZero() {
+		if early := offWorkLimitTime.Sub(record.OffworkTime); early > 0 {
+			day.Early = true
+			day.EarlyMinutes = ceilMinutes(early)
+			if t := r.tier(day.EarlyMinutes); t != nil {
+				day.EarlyTier = t.Name
+				day.HalfAbsent = day.HalfAbsent || t.HalfDayAbsent
+			}
+		}
+	} else {
+		day.LackCard = true
+	}
+	if !record.OnworkTime.IsZero() && !record.OffworkTime.IsZero() {
+		day.Duration = float64(record.OffworkTime.Sub(record.OnworkTime)) / float64(time.Hour)
+		if day.Duration < r.minHours {
+			day.Short = true
+		}
+	}
+	return day
+}
+
+func ceilMinutes(d time.Duration) int {
+	return int((d + time.Minute - 1) / time.Minute)
+}
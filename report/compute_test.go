@@ -0,0 +1,97 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"tool-attendance/config"
+	"tool-attendance/model"
+	"tool-attendance/utils/tz"
+)
+
+func TestCompute(t *testing.T) {
+	if err := tz.Init("Asia/Shanghai"); err != nil {
+		t.Fatal(err)
+	}
+	loc := tz.Default()
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2023, 5, day, hour, minute, 0, 0, loc)
+	}
+	record := func(day int, on, off time.Time) model.Record {
+		return model.Record{UserId: "u1", Firstname: "张三", DaysDate: at(day, 0, 0), OnworkTime: on, OffworkTime: off}
+	}
+
+	// 2023-05：1 日休息，2~5 日工作日
+	calendarMap := map[string]model.Calendar{"20230501": {Workday: model.RestDay}}
+	for _, d := range []string{"20230502", "20230503", "20230504", "20230505"} {
+		calendarMap[d] = model.Calendar{Workday: model.WorkDay}
+	}
+	d := &monthData{
+		calendarMap: calendarMap,
+		userRecords: [][]model.Record{{
+			record(1, at(1, 10, 0), at(1, 12, 0)), // 休息日加班
+			record(2, at(2, 9, 0), at(2, 18, 30)), // 正常
+			record(3, at(3, 9, 45), at(3, 17, 0)), // 迟到、早退、时长不足
+			record(4, at(4, 9, 0), time.Time{}),   // 漏打卡
+		}},
+		zones:      &ZoneResolver{},
+		defaultLoc: loc,
+	}
+	res, err := compute(2023, 5, d, Rules{OnWorkTime: "09:30", OffWorkTime: "18:00", MinHours: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.TotalDay != 31 || res.NeedWorkDay != 4 || len(res.Users) != 1 {
+		t.Fatalf("unexpected result: total %d, need %d, users %d", res.TotalDay, res.NeedWorkDay, len(res.Users))
+	}
+	u := res.Users[0]
+	want := UserStat{WorkDay: 3, AbsentDay: 1, LateDay: 1, EarlyDay: 1, ShortDay: 1, LackCardDay: 1, LateMinutes: 15, EarlyMinutes: 60}
+	if u.Stat != want {
+		t.Fatalf("stat = %+v, want %+v", u.Stat, want)
+	}
+	if day := u.Days[0]; day.Workday || day.Present || day.OnworkTime.IsZero() {
+		t.Fatalf("rest day = %+v", day)
+	}
+	if day := u.Days[1]; !day.Present || day.Late || day.Early || day.Short || day.Duration != 9.5 {
+		t.Fatalf("normal day = %+v", day)
+	}
+	if day := u.Days[2]; !day.Late || !day.Early || !day.Short {
+		t.Fatalf("late day = %+v", day)
+	}
+	if day := u.Days[3]; !day.LackCard || day.Duration != 0 {
+		t.Fatalf("lack card day = %+v", day)
+	}
+	if day := u.Days[4]; !day.Absent {
+		t.Fatalf("absent day = %+v", day)
+	}
+
+	// 放宽规则后不再迟到
+	res, err = compute(2023, 5, d, Rules{OnWorkTime: "10:00", OffWorkTime: "17:00", MinHours: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := res.Users[0].Stat; st.LateDay != 0 || st.EarlyDay != 0 || st.ShortDay != 0 {
+		t.Fatalf("stat with loose rules = %+v", st)
+	}
+
+	// 分档：≤10 分钟、≤30 分钟、超过 30 分钟按半天旷工
+	tiers := []config.LatenessTier{
+		{MaxMinutes: 0, Name: ">30", HalfDayAbsent: true},
+		{MaxMinutes: 10, Name: "≤10"},
+		{MaxMinutes: 30, Name: "≤30"},
+	}
+	res, err = compute(2023, 5, d, Rules{OnWorkTime: "09:30", OffWorkTime: "18:00", MinHours: 9, Tiers: tiers})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if day := res.Users[0].Days[2]; day.LateTier != "≤30" || day.EarlyTier != ">30" || !day.HalfAbsent {
+		t.Fatalf("tiered day = %+v", day)
+	}
+	if st := res.Users[0].Stat; st.HalfAbsentDay != 1 {
+		t.Fatalf("stat with tiers = %+v", st)
+	}
+
+	if _, err = compute(2023, 5, d, Rules{OnWorkTime: "9点", OffWorkTime: "18:00"}); err == nil {
+		t.Fatal("invalid rule time accepted")
+	}
+}
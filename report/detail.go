@@ -0,0 +1,223 @@
+package report
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+	"tool-attendance/model"
+	"tool-attendance/utils/i18n"
+)
+
+// 明细表统计列数
+const detailStatColumns = 11
+
+// BuildDetail 生成月度考勤明细表：每人每天的上下班时间、时长、迟到和早退分钟数，统计口径见 Compute；
+// 另附一张异常打卡工作表，见 Anomalies
+func BuildDetail(year, month int, lang string) (*excelize.File, error) {
+	res, err := Compute(year, month)
+	if err != nil {
+		return nil, err
+	}
+
+	f := excelize.NewFile()
+
+	// 当月天数
+	totalDay := res.TotalDay
+
+	//--设置工作表名称
+	//根据给定的新旧工作表名称（大小写敏感）重命名工作表。工作表名称最多允许使用 31 个字符，
+	//此功能仅更改工作表的名称，而不会更新与单元格关联的公式或引用中的工作表名称。
+	//因此使用此功能重命名工作表后可能导致公式错误或参考引用问题。
+	sheetName := i18n.T(lang, "report.title", year, month)
+	_ = f.SetSheetName("Sheet1", sheetName) //设置工作表的名称
+
+	tableRecords := [][]interface{}{
+		{sheetName}, // 标题：2023年3月考勤记录
+		{i18n.T(lang, "report.serial"), i18n.T(lang, "report.name"), i18n.T(lang, "report.week")}, // head：序号-姓名-星期
+		{nil, nil, i18n.T(lang, "report.date")},                                                   // head：日期
+	}
+
+	// 日期星期
+	for i := 1; i <= totalDay; i++ {
+		tableRecords[1] = append(tableRecords[1], getWeek(lang, year, month, i)) // 星期
+		tableRecords[2] = append(tableRecords[2], i)                             // 日期
+	}
+
+	tableRecords[1] = append(tableRecords[1], i18n.T(lang, "report.summary", res.NeedWorkDay))
+	tableRecords[2] = append(tableRecords[2], []interface{}{
+		i18n.T(lang, "stat.attend"), i18n.T(lang, "stat.absent"), i18n.T(lang, "stat.late"),
+		i18n.T(lang, "stat.early"), i18n.T(lang, "stat.short"), i18n.T(lang, "stat.lack"),
+		i18n.T(lang, "stat.late_minutes"), i18n.T(lang, "stat.early_minutes"), i18n.T(lang, "stat.half_absent"),
+		i18n.T(lang, "stat.out_of_fence"), i18n.T(lang, "stat.remote"),
+	}...)
+
+	// 记录数据
+	for i, user := range res.Users {
+		// 上班：
+		onWorkRow := []interface{}{i + 1, user.Name(), i18n.T(lang, "report.onwork")}
+		// 下班
+		offWorkRow := []interface{}{nil, nil, i18n.T(lang, "report.offwork")}
+		// 时长
+		durationRow := []interface{}{nil, nil, i18n.T(lang, "report.duration")}
+		// 迟到
+		lateRow := []interface{}{nil, nil, i18n.T(lang, "report.late")}
+		// 早退
+		earlyRow := []interface{}{nil, nil, i18n.T(lang, "report.early")}
+
+		for _, day := range user.Days {
+			var (
+				onWork   = ""
+				offWork  = ""
+				duration = ""
+				late     = ""
+				early    = ""
+			)
+			if day.Present {
+				onWork, offWork, duration = noCardSymbol, noCardSymbol, unknownDurationSymbol
+				if !day.OnworkTime.IsZero() {
+					onWork = day.OnworkTime.In(user.Location).Format(formatTime)
+					late = noLateSymbol
+					if day.Late {
+						late = strconv.Itoa(day.LateMinutes)
+					}
+				}
+				if !day.OffworkTime.IsZero() {
+					offWork = day.OffworkTime.In(user.Location).Format(formatTime)
+					early = noEarlySymbol
+					if day.Early {
+						early = strconv.Itoa(day.EarlyMinutes)
+					}
+				}
+				if !day.OnworkTime.IsZero() && !day.OffworkTime.IsZero() {
+					duration = fmt.Sprintf("%.1f", day.Duration)
+				}
+			} else if day.Absent {
+				// 缺勤
+				onWork, offWork = noCardSymbol, noCardSymbol
+			}
+			onWorkRow = append(onWorkRow, onWork)
+			offWorkRow = append(offWorkRow, offWork)
+			durationRow = append(durationRow, duration)
+			lateRow = append(lateRow, late)
+			earlyRow = append(earlyRow, early)
+		}
+		st := user.Stat
+		onWorkRow = append(onWorkRow, st.WorkDay, st.AbsentDay, st.LateDay, st.EarlyDay, st.ShortDay, st.LackCardDay,
+			st.LateMinutes, st.EarlyMinutes, st.HalfAbsentDay, st.OutOfFenceDay, st.RemoteDay)
+		tableRecords = append(tableRecords, onWorkRow)
+		tableRecords = append(tableRecords, offWorkRow)
+		tableRecords = append(tableRecords, durationRow)
+		tableRecords = append(tableRecords, lateRow)
+		tableRecords = append(tableRecords, earlyRow)
+	}
+
+	// 图例
+	legendRow := []interface{}{nil, i18n.T(lang, "legend.title"), i18n.T(lang, "legend.card"), i18n.T(lang, "legend.no_card"),
+		i18n.T(lang, "legend.no_duration"), i18n.T(lang, "legend.late_early"), i18n.T(lang, "legend.rest"),
+		i18n.T(lang, "legend.fence")}
+	tableRecords = append(tableRecords, nil, legendRow)
+
+	for i, obj := range tableRecords {
+		//--根据行和列拼接单元格名称
+		name, _ := excelize.JoinCellName("A", i+1)
+
+		//--按行赋值
+		//根据给定的工作表名称（大小写敏感）、起始坐标和 slice 类型引用按行赋值。
+		//例如，在名为 Sheet1 的工作簿第 6 行上，以 B6 单元格作为起始坐标按行赋值：
+		//err := f.SetSheetRow("Sheet1", "B6", &[]interface{}{"1", nil, 2})
+		_ = f.SetSheetRow(sheetName, name, &obj)
+	}
+
+	for i := 1; i <= totalDay; i++ {
+		tableRecords[1] = append(tableRecords[1], getWeek(lang, year, month, i)) // 星期
+		tableRecords[2] = append(tableRecords[2], i)                             // 日期
+	}
+
+	//--单元格样式
+	//func (f *File) SetCellStyle(sheet, hcell, vcell string, styleID int) error
+	//根据给定的工作表名、单元格坐标区域和样式索引设置单元格的值
+	//。样式索引可以通过 NewStyle 函数获取。
+	//注意，在同一个坐标区域内的 diagonalDown 和 diagonalUp 需要保持颜色一致。
+	//SetCellStyle 将覆盖单元格的已有样式，而不会将样式与已有样式叠加或合并。
+	styleTitle, _ := getExcelStyle(f, cellStyleTitle)       // 标题样式
+	styleHead, _ := getExcelStyle(f, cellStyleHead)         // 表头样式
+	styleRecord, _ := getExcelStyle(f, cellStyleRecord)     // 数据记录样式
+	styleAbnormal, _ := getExcelStyle(f, cellStyleAbnormal) // 异常记录
+
+	// 默认样式
+	lastCel, _ := excelize.CoordinatesToCellName(3+totalDay+detailStatColumns, 3+len(res.Users)*5)
+	_ = f.SetCellStyle(sheetName, "A1", lastCel, styleRecord)
+
+	// 表头样式
+	lastHeadCel, _ := excelize.CoordinatesToCellName(3+totalDay+detailStatColumns, 3)
+	_ = f.SetCellStyle(sheetName, "A2", lastHeadCel, styleHead)
+
+	//设置列宽度
+	//func (f *File) SetColWidth(sheet, startcol, endcol string, width float64) error
+	//根据给定的工作表名称（大小写敏感）、列范围和宽度值设置单个或多个列的宽度。
+	_ = f.SetColWidth(sheetName, "A", "A", 3)                             // 序号列
+	_ = f.SetColWidth(sheetName, "B", "B", 7.5)                           // 姓名列
+	_ = f.SetColWidth(sheetName, "C", "C", 5)                             // 日期-星期列
+	_ = f.SetColWidth(sheetName, "D", calColumnTitle("D", totalDay-1), 8) // 数据列
+
+	//--合并单元格
+	//根据给定的工作表名（大小写敏感）和单元格坐标区域合并单元格。合并区域内仅保留左上角单元格的值，其他单元格的值将被忽略。
+	//例如，合并名为 Sheet1 的工作表上 D3:E9 区域内的单元格：
+	//err := f.MergeCell("Sheet1", "D3", "E9")
+	//如果给定的单元格坐标区域与已有的其他合并单元格相重叠，已有的合并单元格将会被删除。
+
+	// 标题
+	titleCel, _ := excelize.CoordinatesToCellName(3+totalDay+detailStatColumns, 1)
+	_ = f.SetCellStyle(sheetName, "A1", titleCel, styleTitle)
+	_ = f.MergeCell(sheetName, "A1", titleCel)
+
+	// heda-序号
+	_ = f.MergeCell(sheetName, "A2", "A3")
+
+	// heda-姓名
+	_ = f.MergeCell(sheetName, "B2", "B3")
+
+	// 统计
+	statCel1, _ := excelize.CoordinatesToCellName(1+3+totalDay, 2)
+	statCel2, _ := excelize.CoordinatesToCellName(3+totalDay+detailStatColumns, 2)
+	_ = f.MergeCell(sheetName, statCel1, statCel2)
+
+	// 记录
+	for i := range res.Users {
+		// 序号
+		serialNumCel1, _ := excelize.JoinCellName("A", 3+1+i*4+i)
+		serialNumCel2, _ := excelize.JoinCellName("A", 3+1+(i+1)*4+i)
+		_ = f.MergeCell(sheetName, serialNumCel1, serialNumCel2)
+
+		// 姓名
+		nameCel1, _ := excelize.JoinCellName("B", 3+1+i*4+i)
+		nameCel2, _ := excelize.JoinCellName("B", 3+1+(i+1)*4+i)
+		_ = f.MergeCell(sheetName, nameCel1, nameCel2)
+
+		// 统计：出勤、旷工、迟到、早退、时长不足、漏打卡、迟到分钟、早退分钟、半天旷工、围栏外、远程
+		for col := 1; col <= detailStatColumns; col++ {
+			statCel1, _ := excelize.CoordinatesToCellName(3+totalDay+col, 3+1+i*4+i)
+			statCel2, _ := excelize.CoordinatesToCellName(3+totalDay+col, 3+1+(i+1)*4+i)
+			_ = f.MergeCell(sheetName, statCel1, statCel2)
+		}
+	}
+
+	// 围栏外打卡的日期：上下班时间标红
+	for i, user := range res.Users {
+		for _, day := range user.Days {
+			if day.Place != model.PlaceOutOfFence {
+				continue
+			}
+			cel1, _ := excelize.CoordinatesToCellName(3+day.Day, 3+1+i*5)
+			cel2, _ := excelize.CoordinatesToCellName(3+day.Day, 3+2+i*5)
+			_ = f.SetCellStyle(sheetName, cel1, cel2, styleAbnormal)
+		}
+	}
+
+	if err = addAnomalySheet(f, year, month, lang); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,335 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+
+	"tool-attendance/model"
+	"tool-attendance/utils/i18n"
+)
+
+// 用户在两次计算结果中的变化
+const (
+	DiffAdded   = "added"   // 只出现在对比结果中
+	DiffRemoved = "removed" // 只出现在基准结果中
+	DiffChanged = "changed"
+)
+
+// ErrNoStoredVersion 没有与当前规则或日历不同的已保存版本，没有可对比的变化
+var ErrNoStoredVersion = errors.New("no stored rules or calendar differ from the current ones")
+
+// DiffResult 两次月度考勤计算结果的差异，只包含有变化的用户和日期
+type DiffResult struct {
+	Base   DiffSide   `json:"base"`
+	Target DiffSide   `json:"target"`
+	Users  []UserDiff `json:"users"`
+}
+
+// DiffSide 参与对比的一次计算
+type DiffSide struct {
+	Year  int    `json:"year"`
+	Month int    `json:"month"`
+	Rules *Rules `json:"rules,omitempty"` // 按指定规则重新计算时的规则，为空表示 Compute 的结果
+
+	Stored    bool   `json:"stored,omitempty"`     // 按之前保存的规则和日历计算：未关账月份的汇总版本或关账快照
+	VersionId int64  `json:"version_id,omitempty"` // 使用的汇总版本，见 model.SummaryVersion
+	Workdays  string `json:"workdays,omitempty"`   // 使用的日历，每天是否为工作日，1 为工作日
+}
+
+type UserDiff struct {
+	UserId string      `json:"user_id"`
+	Name   string      `json:"name"`
+	Status string      `json:"status"`
+	Stat   []FieldDiff `json:"stat,omitempty"` // 月度统计的变化
+	Days   []DayDiff   `json:"days,omitempty"`
+}
+
+// DayDiff 按日期序号对齐的单日变化，对比不同月份时第 n 天与第 n 天比较
+type DayDiff struct {
+	Day    int         `json:"day"`
+	Fields []FieldDiff `json:"fields"`
+}
+
+type FieldDiff struct {
+	Field  string      `json:"field"`
+	Base   interface{} `json:"base"`
+	Target interface{} `json:"target"`
+}
+
+// DiffPeriods 对比两个月份按当前规则的结果
+func DiffPeriods(baseYear, baseMonth, targetYear, targetMonth int) (*DiffResult, error) {
+	base, err := Compute(baseYear, baseMonth)
+	if err != nil {
+		return nil, err
+	}
+	target, err := Compute(targetYear, targetMonth)
+	if err != nil {
+		return nil, err
+	}
+	return diffMonth(base, target), nil
+}
+
+// DiffRules 对比某月现有的结果（已关账时为快照）与按 rules 从打卡记录重新计算的结果，用于修改规则配置前预览影响；
+// 修改规则或刷新日历之后查看变化用 DiffStored
+func DiffRules(year, month int, rules Rules) (*DiffResult, error) {
+	base, err := Compute(year, month)
+	if err != nil {
+		return nil, err
+	}
+	target, err := ComputeWithRules(year, month, rules)
+	if err != nil {
+		return nil, err
+	}
+	res := diffMonth(base, target)
+	res.Target.Rules = &rules
+	return res, nil
+}
+
+// DiffStored 对比某月按之前保存的规则和日历计算的结果与按当前规则和日历计算的结果，用于修改规则配置或刷新日历后查看变化。
+// 未关账月份的基准为规则或日历与现在不同的最近一个汇总版本，两边都从当前的打卡记录计算，只反映规则和日历的影响；
+// 已关账月份的基准为关账快照（关账后日历不再变化），对比按当前规则重新计算的结果
+func DiffStored(year, month int) (*DiffResult, error) {
+	rules := CurrentRules()
+	m, err := model.FindMonthClose(year, month)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if m != nil && m.Status == model.MonthClosed {
+		var closedRules Rules
+		if err = json.Unmarshal([]byte(m.Rules), &closedRules); err != nil {
+			return nil, fmt.Errorf("rules of closed month: %w", err)
+		}
+		if rulesHash(closedRules) == rulesHash(rules) {
+			return nil, ErrNoStoredVersion
+		}
+		base, err := loadSnapshot(year, month)
+		if err != nil {
+			return nil, err
+		}
+		target, err := ComputeWithRules(year, month, rules)
+		if err != nil {
+			return nil, err
+		}
+		res := diffMonth(base, target)
+		res.Base.Rules, res.Base.Stored = &closedRules, true
+		res.Target.Rules = &rules
+		return res, nil
+	}
+
+	d, err := loadMonth(year, month)
+	if err != nil {
+		return nil, err
+	}
+	_, workdays := newMonthResult(year, month, d.calendarMap)
+	hash, key := rulesHash(rules), workdaysKey(workdays)
+	versions, err := model.FindSummaryVersionList(year, month)
+	if err != nil {
+		return nil, err
+	}
+	var version *model.SummaryVersion
+	for i := range versions {
+		if versions[i].RulesHash != hash || versions[i].Workdays != key {
+			version = &versions[i]
+			break
+		}
+	}
+	if version == nil {
+		return nil, ErrNoStoredVersion
+	}
+	var storedRules Rules
+	if err = json.Unmarshal([]byte(version.Rules), &storedRules); err != nil {
+		return nil, fmt.Errorf("rules of summary version %d: %w", version.ID, err)
+	}
+	stored := *d
+	stored.calendarMap = calendarFromWorkdays(year, month, version.Workdays)
+	base, err := compute(year, month, &stored, storedRules)
+	if err != nil {
+		return nil, err
+	}
+	target, err := compute(year, month, d, rules)
+	if err != nil {
+		return nil, err
+	}
+	res := diffMonth(base, target)
+	res.Base.Rules, res.Base.Stored, res.Base.VersionId, res.Base.Workdays = &storedRules, true, version.ID, version.Workdays
+	res.Target.Rules, res.Target.Workdays = &rules, key
+	return res, nil
+}
+
+func diffMonth(base, target *MonthResult) *DiffResult {
+	res := &DiffResult{
+		Base:   DiffSide{Year: base.Year, Month: base.Month},
+		Target: DiffSide{Year: target.Year, Month: target.Month},
+		Users:  make([]UserDiff, 0),
+	}
+	baseUsers := make(map[string]UserResult, len(base.Users))
+	for _, u := range base.Users {
+		baseUsers[u.UserId] = u
+	}
+	seen := make(map[string]bool, len(target.Users))
+	for _, u := range target.Users {
+		seen[u.UserId] = true
+		b, ok := baseUsers[u.UserId]
+		if !ok {
+			res.Users = append(res.Users, diffUser(UserResult{}, u, DiffAdded))
+			continue
+		}
+		if d := diffUser(b, u, DiffChanged); len(d.Stat) > 0 || len(d.Days) > 0 {
+			res.Users = append(res.Users, d)
+		}
+	}
+	for _, u := range base.Users {
+		if !seen[u.UserId] {
+			res.Users = append(res.Users, diffUser(u, UserResult{}, DiffRemoved))
+		}
+	}
+	sort.SliceStable(res.Users, func(i, j int) bool { return res.Users[i].UserId < res.Users[j].UserId })
+	return res
+}
+
+func diffUser(base, target UserResult, status string) UserDiff {
+	d := UserDiff{UserId: target.UserId, Name: target.Name(), Status: status}
+	if status == DiffRemoved {
+		d.UserId, d.Name = base.UserId, base.Name()
+	}
+	d.Stat = diffFields(statFields(base.Stat), statFields(target.Stat))
+
+	n := len(base.Days)
+	if len(target.Days) > n {
+		n = len(target.Days)
+	}
+	for i := 0; i < n; i++ {
+		var b, t DayResult
+		if i < len(base.Days) {
+			b = base.Days[i]
+		}
+		if i < len(target.Days) {
+			t = target.Days[i]
+		}
+		if fields := diffFields(dayFields(b, base.Location), dayFields(t, target.Location)); len(fields) > 0 {
+			d.Days = append(d.Days, DayDiff{Day: i + 1, Fields: fields})
+		}
+	}
+	return d
+}
+
+// field 参与对比的字段，名称与 json 字段一致
+type field struct {
+	name  string
+	value interface{}
+}
+
+func statFields(s UserStat) []field {
+	return []field{
+		{"work_day", s.WorkDay}, {"absent_day", s.AbsentDay}, {"late_day", s.LateDay},
+		{"early_day", s.EarlyDay}, {"short_day", s.ShortDay}, {"lack_card_day", s.LackCardDay},
+		{"late_minutes", s.LateMinutes}, {"early_minutes", s.EarlyMinutes}, {"half_absent_day", s.HalfAbsentDay},
+		{"out_of_fence_day", s.OutOfFenceDay}, {"remote_day", s.RemoteDay},
+	}
+}
+
+// dayFields 打卡时间按员工所在时区取时分秒，便于对比不同月份
+func dayFields(d DayResult, loc *time.Location) []field {
+	clockOf := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		if loc != nil {
+			t = t.In(loc)
+		}
+		return t.Format(formatTime)
+	}
+	return []field{
+		{"workday", d.Workday}, {"onwork_time", clockOf(d.OnworkTime)}, {"offwork_time", clockOf(d.OffworkTime)},
+		{"present", d.Present}, {"absent", d.Absent}, {"late", d.Late}, {"early", d.Early},
+		{"short", d.Short}, {"lack_card", d.LackCard}, {"duration", d.Duration},
+		{"late_minutes", d.LateMinutes}, {"early_minutes", d.EarlyMinutes},
+		{"late_tier", d.LateTier}, {"early_tier", d.EarlyTier}, {"half_absent", d.HalfAbsent},
+		{"place", d.Place},
+	}
+}
+
+func diffFields(base, target []field) []FieldDiff {
+	var res []FieldDiff
+	for i := range base {
+		if base[i].value != target[i].value {
+			res = append(res, FieldDiff{Field: base[i].name, Base: base[i].value, Target: target[i].value})
+		}
+	}
+	return res
+}
+
+// DiffExcel 生成差异工作簿，每个变化一行，变化后的值高亮；新增、移除的用户整行着色
+func DiffExcel(res *DiffResult, lang string) (*excelize.File, error) {
+	f := excelize.NewFile()
+	sheetName := i18n.T(lang, "diff.title")
+	_ = f.SetSheetName("Sheet1", sheetName)
+
+	baseTitle := i18n.T(lang, "diff.side", res.Base.Year, res.Base.Month)
+	if res.Base.Stored {
+		baseTitle = i18n.T(lang, "diff.side_stored", res.Base.Year, res.Base.Month)
+	}
+	targetTitle := i18n.T(lang, "diff.side", res.Target.Year, res.Target.Month)
+	if res.Target.Rules != nil {
+		targetTitle = i18n.T(lang, "diff.side_rules", res.Target.Year, res.Target.Month)
+	}
+	head := []interface{}{
+		i18n.T(lang, "payroll.user_id"), i18n.T(lang, "report.name"), i18n.T(lang, "diff.status"),
+		i18n.T(lang, "report.date"), i18n.T(lang, "diff.field"), baseTitle, targetTitle,
+	}
+	_ = f.SetSheetRow(sheetName, "A1", &head)
+
+	styleHead, _ := getExcelStyle(f, cellStyleHead)
+	styleChanged, _ := f.NewStyle(&excelize.Style{Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFEB84"}}})
+	styleAdded, _ := f.NewStyle(&excelize.Style{Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"C6EFCE"}}})
+	styleRemoved, _ := f.NewStyle(&excelize.Style{Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}}})
+	_ = f.SetCellStyle(sheetName, "A1", cellName(len(head), 1), styleHead)
+
+	row := 2
+	write := func(u UserDiff, day interface{}, fd FieldDiff) {
+		values := []interface{}{u.UserId, u.Name, i18n.T(lang, "diff.status."+u.Status), day,
+			i18n.T(lang, "diff.field."+fd.Field), diffValue(lang, fd.Base), diffValue(lang, fd.Target)}
+		_ = f.SetSheetRow(sheetName, cellName(1, row), &values)
+		switch u.Status {
+		case DiffAdded:
+			_ = f.SetCellStyle(sheetName, cellName(1, row), cellName(len(values), row), styleAdded)
+		case DiffRemoved:
+			_ = f.SetCellStyle(sheetName, cellName(1, row), cellName(len(values), row), styleRemoved)
+		default:
+			_ = f.SetCellStyle(sheetName, cellName(len(values), row), cellName(len(values), row), styleChanged)
+		}
+		row++
+	}
+	for _, u := range res.Users {
+		for _, fd := range u.Stat {
+			write(u, i18n.T(lang, "diff.month"), fd)
+		}
+		for _, d := range u.Days {
+			for _, fd := range d.Fields {
+				write(u, d.Day, fd)
+			}
+		}
+	}
+	_ = f.SetColWidth(sheetName, "A", "E", 12)
+	_ = f.SetColWidth(sheetName, "F", "G", 16)
+	return f, nil
+}
+
+func diffValue(lang string, v interface{}) interface{} {
+	switch val := v.(type) {
+	case bool:
+		if val {
+			return i18n.T(lang, "diff.yes")
+		}
+		return i18n.T(lang, "diff.no")
+	case float64:
+		return fmt.Sprintf("%.1f", val)
+	}
+	return v
+}
@@ -0,0 +1,101 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"tool-attendance/model"
+	"tool-attendance/utils/i18n"
+)
+
+func TestDiffMonth(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2023, 5, day, hour, minute, 0, 0, loc)
+	}
+	base := &MonthResult{Year: 2023, Month: 5, Users: []UserResult{
+		{UserId: "u1", Username: "Alice", Location: loc, Stat: UserStat{WorkDay: 2}, Days: []DayResult{
+			{Day: 1, Workday: true, Present: true, OnworkTime: at(1, 9, 0)},
+			{Day: 2, Workday: true, Present: true, OnworkTime: at(2, 9, 40), Late: true, LateMinutes: 10},
+		}},
+		{UserId: "u2", Location: loc, Days: []DayResult{{Day: 1}, {Day: 2}}},
+		{UserId: "u3", Location: loc, Days: []DayResult{{Day: 1, Workday: true, Absent: true}, {Day: 2}}},
+	}}
+	// 规则调整：上班时间改为 10:00，5 月 1 日改为休息日
+	target := &MonthResult{Year: 2023, Month: 5, Users: []UserResult{
+		{UserId: "u1", Username: "Alice", Location: loc, Stat: UserStat{WorkDay: 1}, Days: []DayResult{
+			{Day: 1, Workday: false, OnworkTime: at(1, 9, 0)},
+			{Day: 2, Workday: true, Present: true, OnworkTime: at(2, 9, 40)},
+		}},
+		{UserId: "u2", Location: loc, Days: []DayResult{{Day: 1}, {Day: 2}}},
+		{UserId: "u4", Location: loc, Days: []DayResult{{Day: 1}, {Day: 2, Workday: true, Present: true}}},
+	}}
+
+	res := diffMonth(base, target)
+	if len(res.Users) != 3 || res.Users[0].UserId != "u1" || res.Users[1].UserId != "u3" || res.Users[2].UserId != "u4" {
+		t.Fatalf("users = %+v", res.Users)
+	}
+	u1 := res.Users[0]
+	if u1.Status != DiffChanged || u1.Name != "Alice" || len(u1.Stat) != 1 || u1.Stat[0].Field != "work_day" || u1.Stat[0].Base != 2 || u1.Stat[0].Target != 1 {
+		t.Fatalf("u1 stat = %+v", u1.Stat)
+	}
+	if len(u1.Days) != 2 || len(u1.Days[0].Fields) != 2 || u1.Days[0].Fields[0].Field != "workday" || u1.Days[0].Fields[1].Field != "present" {
+		t.Fatalf("u1 days = %+v", u1.Days)
+	}
+	if f := u1.Days[1].Fields; len(f) != 2 || f[0].Field != "late" || f[1].Field != "late_minutes" || f[1].Base != 10 || f[1].Target != 0 {
+		t.Fatalf("u1 day 2 = %+v", f)
+	}
+	if res.Users[1].Status != DiffRemoved || res.Users[2].Status != DiffAdded || len(res.Users[2].Days) != 1 {
+		t.Fatalf("u3/u4 = %+v", res.Users[1:])
+	}
+
+	f, err := DiffExcel(res, i18n.ZhCN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, _ := f.GetRows(i18n.T(i18n.ZhCN, "diff.title"))
+	// 表头 + u1 统计 1 行、日期 4 行 + u3 2 行 + u4 2 行
+	if len(rows) != 10 || rows[1][4] != "出勤天数" || rows[2][5] != "是" {
+		t.Fatalf("rows = %v", rows)
+	}
+}
+
+func TestDiffStoredCalendar(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+	at := func(day, hour int) time.Time { return time.Date(2023, 5, day, hour, 0, 0, 0, loc) }
+	calendarMap := map[string]model.Calendar{}
+	for day := 1; day <= 31; day++ {
+		calendarMap[at(day, 0).Format("20060102")] = model.Calendar{Workday: model.WorkDay}
+	}
+	_, workdays := newMonthResult(2023, 5, calendarMap)
+	key := workdaysKey(workdays)
+	// 刷新日历后 5 月 1 日改为休息日，按保存的版本还原之前的日历
+	calendarMap["20230501"] = model.Calendar{Workday: model.RestDay}
+	restored := calendarFromWorkdays(2023, 5, key)
+	if _, w := newMonthResult(2023, 5, restored); workdaysKey(w) != key {
+		t.Fatalf("restored workdays = %s, want %s", workdaysKey(w), key)
+	}
+
+	d := &monthData{
+		calendarMap: calendarMap,
+		userRecords: [][]model.Record{{{UserId: "u1", DaysDate: at(1, 0), OnworkTime: at(1, 9), OffworkTime: at(1, 18)}}},
+		zones:       &ZoneResolver{},
+		defaultLoc:  loc,
+	}
+	stored := *d
+	stored.calendarMap = restored
+	rules := Rules{OnWorkTime: "09:30", OffWorkTime: "18:00", MinHours: 8}
+	base, err := compute(2023, 5, &stored, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := compute(2023, 5, d, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := diffMonth(base, target)
+	if len(res.Users) != 1 || len(res.Users[0].Days) != 1 || res.Users[0].Days[0].Day != 1 || res.Users[0].Days[0].Fields[0].Field != "workday" {
+		t.Fatalf("diff = %+v", res.Users)
+	}
+}
@@ -0,0 +1,304 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"tool-attendance/utils/i18n"
+)
+
+const (
+	noLateSymbol          = ""  // 未迟到，迟到时显示迟到分钟数
+	noEarlySymbol         = ""  // 未早退，早退时显示早退分钟数
+	cardSymbol            = "√" // 正常打卡
+	noCardSymbol          = "×" // 未打卡
+	unknownDurationSymbol = "-" // 未知的工作时长
+)
+
+const (
+	formatDayTime = "2006-01-02"
+	formatTime    = "15:04:05"
+)
+
+const (
+	cellStyleTitle = iota
+	cellStyleHead
+	cellStyleRecord
+	cellStyleAbnormal
+)
+
+func getExcelStyle(f *excelize.File, style int) (int, error) {
+	switch style {
+	case cellStyleTitle:
+		// 标题样式
+		return f.NewStyle(&excelize.Style{
+			Border: []excelize.Border{
+				{Type: "left", Color: "000000", Style: 2},
+				{Type: "right", Color: "000000", Style: 2},
+				{Type: "top", Color: "000000", Style: 2},
+				{Type: "bottom", Color: "000000", Style: 2},
+			},
+			Font: &excelize.Font{
+				Bold:         true,
+				Italic:       false,
+				Underline:    "",
+				Family:       "",
+				Size:         18,
+				Strike:       false,
+				Color:        "",
+				ColorIndexed: 0,
+				ColorTheme:   nil,
+				ColorTint:    0,
+				VertAlign:    "",
+			},
+			Alignment: &excelize.Alignment{
+				Horizontal:      "center", //水平居中
+				Indent:          0,
+				JustifyLastLine: false,
+				ReadingOrder:    0,
+				RelativeIndent:  0,
+				ShrinkToFit:     false,
+				TextRotation:    0,
+				Vertical:        "center", //垂直居中
+				WrapText:        false,
+			},
+		})
+
+	case cellStyleHead:
+		// 表头样式
+		return f.NewStyle(&excelize.Style{
+			Border: []excelize.Border{
+				{Type: "left", Color: "000000", Style: 2},
+				{Type: "right", Color: "000000", Style: 2},
+				{Type: "top", Color: "000000", Style: 2},
+				{Type: "bottom", Color: "000000", Style: 2},
+			},
+			Fill: excelize.Fill{
+				Type:    "pattern",
+				Pattern: 1,
+				Color:   []string{"D1E9E9"},
+				Shading: 0,
+			},
+			Font: &excelize.Font{
+				Bold:         true,
+				Italic:       false,
+				Underline:    "",
+				Family:       "",
+				Size:         0,
+				Strike:       false,
+				Color:        "",
+				ColorIndexed: 0,
+				ColorTheme:   nil,
+				ColorTint:    0,
+				VertAlign:    "",
+			},
+			Alignment: &excelize.Alignment{
+				Horizontal:      "center", //水平居中
+				Indent:          0,
+				JustifyLastLine: false,
+				ReadingOrder:    0,
+				RelativeIndent:  0,
+				ShrinkToFit:     false,
+				TextRotation:    0,
+				Vertical:        "center", //垂直居中
+				WrapText:        false,
+			},
+		})
+	case cellStyleRecord:
+		// 数据记录样式
+		return f.NewStyle(&excelize.Style{
+			Border: []excelize.Border{
+				{Type: "left", Color: "000000", Style: 2},
+				{Type: "right", Color: "000000", Style: 2},
+				{Type: "top", Color: "000000", Style: 2},
+				{Type: "bottom", Color: "000000", Style: 2},
+			},
+			Font: &excelize.Font{
+				Bold:         false,
+				Italic:       false,
+				Underline:    "",
+				Family:       "",
+				Size:         0,
+				Strike:       false,
+				Color:        "",
+				ColorIndexed: 0,
+				ColorTheme:   nil,
+				ColorTint:    0,
+				VertAlign:    "",
+			},
+			Alignment: &excelize.Alignment{
+				Horizontal:      "center", //水平居中
+				Indent:          0,
+				JustifyLastLine: false,
+				ReadingOrder:    0,
+				RelativeIndent:  0,
+				ShrinkToFit:     false,
+				TextRotation:    0,
+				Vertical:        "center", //垂直居中
+				WrapText:        false,
+			},
+		})
+	case cellStyleAbnormal:
+		return f.NewStyle(&excelize.Style{
+			Border: []excelize.Border{
+				{Type: "left", Color: "000000", Style: 2},
+				{Type: "right", Color: "000000", Style: 2},
+				{Type: "top", Color: "000000", Style: 2},
+				{Type: "bottom", Color: "000000", Style: 2},
+			},
+			Font: &excelize.Font{
+				Bold:         false,
+				Italic:       false,
+				Underline:    "",
+				Family:       "",
+				Size:         0,
+				Strike:       false,
+				Color:        "E60000",
+				ColorIndexed: 0,
+				ColorTheme:   nil,
+				ColorTint:    0,
+				VertAlign:    "",
+			},
+			Alignment: &excelize.Alignment{
+				Horizontal:      "left", //水平居中
+				Indent:          0,
+				JustifyLastLine: false,
+				ReadingOrder:    0,
+				RelativeIndent:  0,
+				ShrinkToFit:     false,
+				TextRotation:    0,
+				Vertical:        "center", //垂直居中
+				WrapText:        false,
+			},
+		})
+	default:
+		return 0, errors.New("no find")
+	}
+}
+
+var (
+	weekday    = [7]int{7, 1, 2, 3, 4, 5, 6}
+	columnChar = []string{"", "A", "B", "C", "D", "E", "F", "G", "H", "I", "J", "K", "L", "M", "N", "O", "P", "Q", "R", "S", "T", "U", "V", "W", "X", "Y", "Z"}
+)
+
+// 计算表格表头列
+func calColumnTitle(s string, offset int) string {
+	num, _ := getNum(s)
+	return convertToTitle(num + offset)
+}
+func getNum(s string) (int, error) {
+	for i, v := range columnChar {
+		if v == s {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid s")
+}
+
+func convertToTitle(columnNumber int) string {
+	// 26个字母
+	const cntLetter = 26
+
+	// 结果
+	var result string
+	// 用于计算字母编码的切片
+	var ch []int
+
+	idx := columnNumber
+	for idx > 0 {
+		// 求余数
+		tail := idx % cntLetter
+
+		if tail == 0 {
+			// 整除无余数，则用 26 来计算编码
+			ch = append(ch, cntLetter)
+			// 先减去26，再取整数部分进行下一次循环
+			idx = (idx - cntLetter) / cntLetter
+		} else {
+			// 余数 用来计算编码
+			ch = append(ch, tail)
+			// 取整数部分进行下一次循环
+			idx = idx / cntLetter
+		}
+	}
+
+	// 循环切片，通过ASCII码计算出对应的字母后进行连接
+	for _, v := range ch {
+		result = string(rune(v+65-1)) + result
+	}
+
+	return result
+}
+
+// getWeek 根据指定日期获取星期
+func getWeek(lang string, year, month, day int) string {
+	var y, m, c int
+	if month >= 3 {
+		m = month
+		y = year % 100
+		c = year / 100
+	} else {
+		m = month + 12
+		y = (year - 1) % 100
+		c = (year - 1) / 100
+	}
+	week := y + (y / 4) + (c / 4) - 2*c + ((26 * (m + 1)) / 10) + day - 1
+	if week < 0 {
+		week = 7 - (-week)%7
+	} else {
+		week = week % 7
+	}
+	return i18n.T(lang, fmt.Sprintf("week.%d", weekday[week]))
+}
+
+// getYearMonthToDay 查询指定年份指定月份有多少天
+func getYearMonthToDay(year int, month int) int {
+	// 有31天的月份
+	day31 := map[int]struct{}{
+		1:  struct{}{},
+		3:  struct{}{},
+		5:  struct{}{},
+		7:  struct{}{},
+		8:  struct{}{},
+		10: struct{}{},
+		12: struct{}{},
+	}
+	if _, ok := day31[month]; ok {
+		return 31
+	}
+	// 有30天的月份
+	day30 := map[int]struct{}{
+		4:  struct{}{},
+		6:  struct{}{},
+		9:  struct{}{},
+		11: struct{}{},
+	}
+	if _, ok := day30[month]; ok {
+		return 30
+	}
+	// 计算是平年还是闰年
+	if (year%4 == 0 && year%100 != 0) || year%400 == 0 {
+		// 得出2月的天数
+		return 29
+	}
+	// 得出2月的天数
+	return 28
+}
+
+// getFirstDateOfMonth 获取传入的时间所在月份的第一天，即某月第一天的0点
+func getFirstDateOfMonth(d time.Time) time.Time {
+	d = d.AddDate(0, 0, -d.Day()+1)
+	return getZeroTime(d)
+}
+
+// getLastDateOfMonth 获取传入的时间所在月份的最后一天，即某月最后一天的24点
+func getLastDateOfMonth(d time.Time) time.Time {
+	return getFirstDateOfMonth(d).AddDate(0, 1, -1).Add(time.Hour*24 - time.Second*1)
+}
+
+// getZeroTime 获取某一天的0点时间
+func getZeroTime(d time.Time) time.Time {
+	return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, d.Location())
+}
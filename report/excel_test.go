@@ -0,0 +1,37 @@
+package report
+
+import (
+	"testing"
+
+	"tool-attendance/utils/i18n"
+)
+
+func TestConvertToTitle(t *testing.T) {
+	cases := map[int]string{1: "A", 26: "Z", 27: "AA", 52: "AZ", 53: "BA", 702: "ZZ", 703: "AAA"}
+	for n, want := range cases {
+		if got := convertToTitle(n); got != want {
+			t.Errorf("convertToTitle(%d) = %s, want %s", n, got, want)
+		}
+	}
+	if got := calColumnTitle("D", 30); got != "AH" {
+		t.Errorf("calColumnTitle got %s", got)
+	}
+}
+
+func TestGetYearMonthToDay(t *testing.T) {
+	cases := [][3]int{{2023, 1, 31}, {2023, 4, 30}, {2023, 2, 28}, {2024, 2, 29}, {1900, 2, 28}, {2000, 2, 29}}
+	for _, v := range cases {
+		if got := getYearMonthToDay(v[0], v[1]); got != v[2] {
+			t.Errorf("getYearMonthToDay(%d, %d) = %d, want %d", v[0], v[1], got, v[2])
+		}
+	}
+}
+
+func TestGetWeek(t *testing.T) {
+	if got := getWeek(i18n.ZhCN, 2023, 5, 4); got != "四" {
+		t.Errorf("got %s", got)
+	}
+	if got := getWeek(i18n.EnUS, 2023, 1, 1); got != "Sun" {
+		t.Errorf("got %s", got)
+	}
+}
@@ -0,0 +1,200 @@
+package report
+
+import (
+	"errors"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"tool-attendance/model"
+	"tool-attendance/utils/tz"
+)
+
+// 地球平均半径（米）
+const earthRadius = 6371000
+
+// PunchMeta 打卡设备上报的位置信息，都是可选的
+type PunchMeta struct {
+	Latitude  *float64
+	Longitude *float64
+	Bssid     string
+	Ip        string
+}
+
+// ClassifyPunch 按工作地点的围栏判定打卡地点，返回 office、out_of_fence 或 unknown。
+// 上报了坐标或 Wi-Fi 时只按这两项判定：工作地点已配置的项目都要满足（坐标在半径内、连接办公室 Wi-Fi），
+// 有一项不满足即在围栏外；IP 是上传设备的地址，不一定是员工所在的网络，只在没有上报坐标和 Wi-Fi 时才按办公室 IP 段判定。
+// 工作地点未配置围栏时按在办公室处理，配置了围栏但没有可比较的项目时为 unknown
+func ClassifyPunch(site model.Site, m PunchMeta) string {
+	hasCoord := m.Latitude != nil && m.Longitude != nil
+	checked := false
+	if site.Radius > 0 && hasCoord {
+		checked = true
+		if distance(site.Latitude, site.Longitude, *m.Latitude, *m.Longitude) > float64(site.Radius) {
+			return model.PlaceOutOfFence
+		}
+	}
+	if site.Bssids != "" && m.Bssid != "" {
+		checked = true
+		if !inBssids(site.Bssids, m.Bssid) {
+			return model.PlaceOutOfFence
+		}
+	}
+	if checked {
+		return model.PlaceOffice
+	}
+	if ip := net.ParseIP(m.Ip); site.IpRanges != "" && ip != nil && !hasCoord && m.Bssid == "" {
+		for _, v := range splitList(site.IpRanges) {
+			if ipInRange(ip, v) {
+				return model.PlaceOffice
+			}
+		}
+		return model.PlaceOutOfFence
+	}
+	if site.Radius > 0 || site.Bssids != "" || site.IpRanges != "" {
+		return model.PlaceUnknown
+	}
+	return model.PlaceOffice
+}
+
+// inBssids bssid 是否在逗号分隔的 BSSID 列表中
+func inBssids(list, bssid string) bool {
+	for _, v := range splitList(list) {
+		if normalizeBssid(v) == normalizeBssid(bssid) {
+			return true
+		}
+	}
+	return false
+}
+
+// PunchPlace 按员工所属工作地点判定一次打卡的地点，返回判定使用的工作地点；
+// 在围栏外且当天有已批准的远程办公时记为 remote
+func PunchPlace(userId string, day time.Time, m PunchMeta) (string, int64, error) {
+	e, err := model.FindEmployee(userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.PlaceOffice, 0, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	if e.SiteId == 0 {
+		return model.PlaceOffice, 0, nil
+	}
+	s, err := model.FindSite(e.SiteId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.PlaceOffice, 0, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	place := ClassifyPunch(*s, m)
+	if place == model.PlaceOutOfFence {
+		remote, err := model.HasApprovedLeave(userId, model.LeaveTypeRemote, day)
+		if err != nil {
+			return "", 0, err
+		}
+		if remote {
+			place = model.PlaceRemote
+		}
+	}
+	return place, s.ID, nil
+}
+
+// ReclassifyRemote 远程办公审批通过或撤销后，改判期间内围栏外的打卡并重新计算汇总
+func ReclassifyRemote(userId string, beginDay, endDay time.Time, approved bool) error {
+	from, to := model.PlaceOutOfFence, model.PlaceRemote
+	if !approved {
+		from, to = to, from
+	}
+	if err := model.UpdatePunchPlace(userId, beginDay, endDay, from, to); err != nil {
+		return err
+	}
+	for day := beginDay; !day.After(endDay); day = day.AddDate(0, 0, 1) {
+		if err := RefreshDaySummary(userId, day); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergePlace 合并同一天多次打卡的地点：有一次在围栏外即为围栏外，其次为远程、无法判断
+func mergePlace(a, b string) string {
+	rank := func(place string) int {
+		switch place {
+		case model.PlaceOutOfFence:
+			return 4
+		case model.PlaceRemote:
+			return 3
+		case model.PlaceUnknown:
+			return 2
+		case model.PlaceOffice:
+			return 1
+		}
+		return 0
+	}
+	if rank(b) > rank(a) {
+		return b
+	}
+	return a
+}
+
+// loadPlaces 时间段内每个用户每天的打卡地点，key 见 placeKey；userId 为空时查询所有用户
+func loadPlaces(userId string, beginDay, endDay time.Time) (map[string]string, error) {
+	rows, err := model.FindPunchPlaceList(userId, beginDay, endDay)
+	if err != nil {
+		return nil, err
+	}
+	places := make(map[string]string, len(rows))
+	for _, v := range rows {
+		key := placeKey(v.UserId, v.Day)
+		places[key] = mergePlace(places[key], v.Place)
+	}
+	return places, nil
+}
+
+func placeKey(userId string, day time.Time) string {
+	return userId + "|" + day.In(tz.Default()).Format(formatDayTime)
+}
+
+// distance 两个坐标间的球面距离（米）
+func distance(lat1, lng1, lat2, lng2 float64) float64 {
+	rad := func(d float64) float64 { return d * math.Pi / 180 }
+	dLat, dLng := rad(lat2-lat1), rad(lng2-lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(rad(lat1))*math.Cos(rad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+func splitList(s string) []string {
+	var res []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// normalizeBssid 统一 BSSID 的大小写和分隔符，如 AA-BB-CC-DD-EE-FF 与 aa:bb:cc:dd:ee:ff 相同
+func normalizeBssid(s string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), "-", ":")
+}
+
+// ipInRange ip 是否在 CIDR 段内，r 也可以是单个 IP
+func ipInRange(ip net.IP, r string) bool {
+	if _, n, err := net.ParseCIDR(r); err == nil {
+		return n.Contains(ip)
+	}
+	other := net.ParseIP(r)
+	return other != nil && other.Equal(ip)
+}
+
+// ValidIpRange 是否为合法的 CIDR 或单个 IP
+func ValidIpRange(r string) bool {
+	if _, _, err := net.ParseCIDR(r); err == nil {
+		return true
+	}
+	return net.ParseIP(r) != nil
+}
@@ -0,0 +1,104 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"tool-attendance/model"
+	"tool-attendance/utils/tz"
+)
+
+func TestClassifyPunch(t *testing.T) {
+	coord := func(v float64) *float64 { return &v }
+	site := model.Site{
+		Latitude: 31.2304, Longitude: 121.4737, Radius: 200,
+		Bssids:   "AA-BB-CC-DD-EE-FF, 11:22:33:44:55:66",
+		IpRanges: "10.1.0.0/16,203.0.113.7",
+	}
+	cases := []struct {
+		name string
+		site model.Site
+		meta PunchMeta
+		want string
+	}{
+		{"no meta", site, PunchMeta{}, model.PlaceUnknown},
+		{"no fence", model.Site{}, PunchMeta{Latitude: coord(0), Longitude: coord(0)}, model.PlaceOffice},
+		{"within radius", site, PunchMeta{Latitude: coord(31.2310), Longitude: coord(121.4740)}, model.PlaceOffice},
+		{"outside radius", site, PunchMeta{Latitude: coord(31.2400), Longitude: coord(121.4737)}, model.PlaceOutOfFence},
+		{"office wifi", site, PunchMeta{Bssid: "aa:bb:cc:dd:ee:ff"}, model.PlaceOffice},
+		{"office wifi within radius", site, PunchMeta{Latitude: coord(31.2310), Longitude: coord(121.4740), Bssid: "aa:bb:cc:dd:ee:ff"}, model.PlaceOffice},
+		{"office wifi outside radius", site, PunchMeta{Latitude: coord(31.2400), Longitude: coord(121.4737), Bssid: "aa:bb:cc:dd:ee:ff"}, model.PlaceOutOfFence},
+		{"other wifi within radius", site, PunchMeta{Latitude: coord(31.2310), Longitude: coord(121.4740), Bssid: "aa:bb:cc:dd:ee:00"}, model.PlaceOutOfFence},
+		{"other wifi", site, PunchMeta{Bssid: "aa:bb:cc:dd:ee:00"}, model.PlaceOutOfFence},
+		{"office cidr", site, PunchMeta{Ip: "10.1.2.3"}, model.PlaceOffice},
+		{"office ip", site, PunchMeta{Ip: "203.0.113.7"}, model.PlaceOffice},
+		{"other ip", site, PunchMeta{Ip: "198.51.100.1"}, model.PlaceOutOfFence},
+		// 上传设备在办公网络内，不能让围栏外的坐标或其他 Wi-Fi 变成在办公室
+		{"office ip outside radius", site, PunchMeta{Latitude: coord(31.2400), Longitude: coord(121.4737), Ip: "10.1.2.3"}, model.PlaceOutOfFence},
+		{"office ip other wifi", site, PunchMeta{Bssid: "aa:bb:cc:dd:ee:00", Ip: "10.1.2.3"}, model.PlaceOutOfFence},
+		// 上报了坐标但工作地点只配置了 IP 段，不按上传设备的 IP 判定
+		{"coord without radius", model.Site{IpRanges: "10.1.0.0/16"}, PunchMeta{Latitude: coord(0), Longitude: coord(0), Ip: "10.1.2.3"}, model.PlaceUnknown},
+		// 工作地点只配置了 Wi-Fi，上报的坐标无法比较
+		{"not comparable", model.Site{Bssids: "aa:bb:cc:dd:ee:ff"}, PunchMeta{Latitude: coord(0), Longitude: coord(0)}, model.PlaceUnknown},
+		{"no meta without fence", model.Site{}, PunchMeta{}, model.PlaceOffice},
+	}
+	for _, c := range cases {
+		if got := ClassifyPunch(c.site, c.meta); got != c.want {
+			t.Errorf("%s: place = %s, want %s", c.name, got, c.want)
+		}
+	}
+
+	if d := distance(31.2304, 121.4737, 31.2304, 121.4737); d != 0 {
+		t.Fatalf("distance to self = %v", d)
+	}
+	// 纬度 1 度约 111 公里
+	if d := distance(30, 120, 31, 120); d < 111000 || d > 111400 {
+		t.Fatalf("distance of 1 degree latitude = %v", d)
+	}
+	if !ValidIpRange("10.0.0.0/8") || !ValidIpRange("::1") || ValidIpRange("10.0.0.0/33") {
+		t.Fatal("ValidIpRange")
+	}
+}
+
+func TestComputePlaces(t *testing.T) {
+	loc := tz.Default()
+	at := func(day, hour int) time.Time { return time.Date(2023, 5, day, hour, 0, 0, 0, loc) }
+	calendarMap := map[string]model.Calendar{}
+	records := make([]model.Record, 0, 3)
+	for day := 2; day <= 4; day++ {
+		calendarMap[at(day, 0).Format("20060102")] = model.Calendar{Workday: model.WorkDay}
+		records = append(records, model.Record{UserId: "u1", DaysDate: at(day, 0), OnworkTime: at(day, 9), OffworkTime: at(day, 18)})
+	}
+	places := map[string]string{}
+	for _, v := range []model.PunchLog{
+		{UserId: "u1", Day: at(2, 0), Place: model.PlaceOffice},
+		{UserId: "u1", Day: at(3, 0), Place: model.PlaceOffice},
+		{UserId: "u1", Day: at(3, 0), Place: model.PlaceOutOfFence},
+		{UserId: "u1", Day: at(4, 0), Place: model.PlaceRemote},
+	} {
+		key := placeKey(v.UserId, v.Day)
+		places[key] = mergePlace(places[key], v.Place)
+	}
+	res, err := compute(2023, 5, &monthData{
+		calendarMap: calendarMap,
+		userRecords: [][]model.Record{records},
+		zones:       &ZoneResolver{},
+		places:      places,
+		defaultLoc:  loc,
+	}, Rules{OnWorkTime: "09:30", OffWorkTime: "18:00", MinHours: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := res.Users[0]
+	if u.Days[0].Place != "" || u.Days[1].Place != model.PlaceOffice || u.Days[2].Place != model.PlaceOutOfFence || u.Days[3].Place != model.PlaceRemote {
+		t.Fatalf("places = %q %q %q %q", u.Days[0].Place, u.Days[1].Place, u.Days[2].Place, u.Days[3].Place)
+	}
+	if u.Stat.OutOfFenceDay != 1 || u.Stat.RemoteDay != 1 {
+		t.Fatalf("stat = %+v", u.Stat)
+	}
+	// 汇总保留打卡地点
+	s := toSummary(records[1], u.Days[2], "")
+	if got := fromSummary(s, loc); got.Place != model.PlaceOutOfFence {
+		t.Fatalf("place from summary = %q", got.Place)
+	}
+}
@@ -0,0 +1,165 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"tool-attendance/utils/i18n"
+)
+
+// DefaultSlotMinutes 热力图默认的时段长度
+const DefaultSlotMinutes = 30
+
+var ErrInvalidSlot = errors.New("slot minutes must divide a day")
+
+// HeatmapResult 一个月内上下班打卡时间按星期和时段的分布，时间为员工所在时区
+type HeatmapResult struct {
+	Year        int      `json:"year"`
+	Month       int      `json:"month"`
+	SlotMinutes int      `json:"slot_minutes"`
+	Slots       []string `json:"slots"`     // 每个时段的开始时间，15:04
+	Arrival     [7][]int `json:"arrival"`   // [星期][时段] 上班打卡人次，星期一为 0
+	Departure   [7][]int `json:"departure"` // [星期][时段] 下班打卡人次，星期一为 0
+}
+
+// Heatmap 统计某月上下班打卡时间的分布，休息日的打卡同样计入，统计口径见 Compute
+func Heatmap(year, month, slotMinutes int) (*HeatmapResult, error) {
+	if !validSlot(slotMinutes) {
+		return nil, ErrInvalidSlot
+	}
+	res, err := Compute(year, month)
+	if err != nil {
+		return nil, err
+	}
+	return buildHeatmap(res, slotMinutes), nil
+}
+
+func validSlot(slotMinutes int) bool {
+	return slotMinutes > 0 && 24*60%slotMinutes == 0
+}
+
+func buildHeatmap(res *MonthResult, slotMinutes int) *HeatmapResult {
+	n := 24 * 60 / slotMinutes
+	h := &HeatmapResult{
+		Year:        res.Year,
+		Month:       res.Month,
+		SlotMinutes: slotMinutes,
+		Slots:       make([]string, n),
+	}
+	for i := range h.Slots {
+		h.Slots[i] = fmt.Sprintf("%02d:%02d", i*slotMinutes/60, i*slotMinutes%60)
+	}
+	for w := 0; w < 7; w++ {
+		h.Arrival[w] = make([]int, n)
+		h.Departure[w] = make([]int, n)
+	}
+	for _, user := range res.Users {
+		for _, day := range user.Days {
+			if !day.OnworkTime.IsZero() {
+				w, slot := heatmapCell(day.OnworkTime.In(user.Location), slotMinutes)
+				h.Arrival[w][slot]++
+			}
+			if !day.OffworkTime.IsZero() {
+				w, slot := heatmapCell(day.OffworkTime.In(user.Location), slotMinutes)
+				h.Departure[w][slot]++
+			}
+		}
+	}
+	return h
+}
+
+// heatmapCell 打卡时间所在的星期（星期一为 0）和时段
+func heatmapCell(t time.Time, slotMinutes int) (int, int) {
+	return (int(t.Weekday()) + 6) % 7, (t.Hour()*60 + t.Minute()) / slotMinutes
+}
+
+// HeatmapExcel 生成打卡时间热力图，上班和下班各一张表左右排列，行为时段、列为星期，按人次着色
+func HeatmapExcel(h *HeatmapResult, lang string) (*excelize.File, error) {
+	f := excelize.NewFile()
+	sheetName := i18n.T(lang, "heatmap.title", h.Year, h.Month)
+	_ = f.SetSheetName("Sheet1", sheetName)
+
+	styleTitle, _ := getExcelStyle(f, cellStyleTitle)
+	styleHead, _ := getExcelStyle(f, cellStyleHead)
+	styleRecord, _ := getExcelStyle(f, cellStyleRecord)
+
+	// 每张表占 8 列：时段 + 周一至周日，两张表之间空一列
+	const tableColumns = 8
+	lastCol := tableColumns*2 + 1
+	titleCel := cellName(lastCol, 1)
+	_ = f.SetCellValue(sheetName, "A1", sheetName)
+	_ = f.MergeCell(sheetName, "A1", titleCel)
+	_ = f.SetCellStyle(sheetName, "A1", titleCel, styleTitle)
+
+	tables := []struct {
+		title  string
+		counts [7][]int
+	}{
+		{i18n.T(lang, "heatmap.arrival"), h.Arrival},
+		{i18n.T(lang, "heatmap.departure"), h.Departure},
+	}
+	for t, table := range tables {
+		col := 1 + t*(tableColumns+1)
+
+		// 表名
+		nameCel := cellName(col, 2)
+		_ = f.SetCellValue(sheetName, nameCel, table.title)
+		_ = f.MergeCell(sheetName, nameCel, cellName(col+tableColumns-1, 2))
+
+		// 表头：时段-星期
+		head := []interface{}{i18n.T(lang, "heatmap.slot")}
+		for w := 1; w <= 7; w++ {
+			head = append(head, i18n.T(lang, fmt.Sprintf("week.%d", w)))
+		}
+		_ = f.SetSheetRow(sheetName, cellName(col, 3), &head)
+		_ = f.SetCellStyle(sheetName, nameCel, cellName(col+tableColumns-1, 3), styleHead)
+
+		// 数据
+		for i, slot := range h.Slots {
+			row := []interface{}{slot}
+			for w := 0; w < 7; w++ {
+				row = append(row, table.counts[w][i])
+			}
+			_ = f.SetSheetRow(sheetName, cellName(col, 4+i), &row)
+		}
+		lastRow := 3 + len(h.Slots)
+		_ = f.SetCellStyle(sheetName, cellName(col, 4), cellName(col+tableColumns-1, lastRow), styleRecord)
+
+		// 按人次着色，人次越多颜色越深
+		dataRange := cellName(col+1, 4) + ":" + cellName(col+tableColumns-1, lastRow)
+		if err := f.SetConditionalFormat(sheetName, dataRange, []excelize.ConditionalFormatOptions{{
+			Type:     "3_color_scale",
+			Criteria: "=",
+			MinType:  "min",
+			MidType:  "percentile",
+			MidValue: "50",
+			MaxType:  "max",
+			MinColor: "#FFFFFF",
+			MidColor: "#FFEB84",
+			MaxColor: "#F8696B",
+		}}); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		_ = f.SetColWidth(sheetName, convertToTitle(col), convertToTitle(col), 8)
+		_ = f.SetColWidth(sheetName, convertToTitle(col+1), convertToTitle(col+tableColumns-1), 6)
+	}
+	_ = f.SetColWidth(sheetName, convertToTitle(tableColumns+1), convertToTitle(tableColumns+1), 3)
+	return f, nil
+}
+
+func cellName(col, row int) string {
+	name, _ := excelize.CoordinatesToCellName(col, row)
+	return name
+}
+
+// BuildHeatmap 按默认时段长度生成打卡时间热力图，用于异步报表任务
+func BuildHeatmap(year, month int, lang string) (*excelize.File, error) {
+	h, err := Heatmap(year, month, DefaultSlotMinutes)
+	if err != nil {
+		return nil, err
+	}
+	return HeatmapExcel(h, lang)
+}
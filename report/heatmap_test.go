@@ -0,0 +1,55 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"tool-attendance/utils/i18n"
+)
+
+func TestBuildHeatmap(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2023, 5, day, hour, minute, 0, 0, loc)
+	}
+	res := &MonthResult{Year: 2023, Month: 5, TotalDay: 31, Users: []UserResult{
+		{UserId: "u1", Location: loc, Days: []DayResult{
+			{Day: 1, OnworkTime: at(1, 8, 59), OffworkTime: at(1, 18, 0)}, // 周一
+			{Day: 2, OnworkTime: at(2, 9, 0)},                             // 周二，漏打下班卡
+			{Day: 7, OffworkTime: at(7, 23, 59)},                          // 周日
+		}},
+		// 打卡时间按员工所在时区统计：UTC 01:10 为东八区 09:10
+		{UserId: "u2", Location: loc, Days: []DayResult{
+			{Day: 1, OnworkTime: time.Date(2023, 5, 1, 1, 10, 0, 0, time.UTC)},
+		}},
+	}}
+
+	h := buildHeatmap(res, 30)
+	if len(h.Slots) != 48 || h.Slots[17] != "08:30" || h.Slots[47] != "23:30" {
+		t.Fatalf("slots = %v", h.Slots)
+	}
+	if h.Arrival[0][17] != 1 || h.Arrival[0][18] != 1 || h.Arrival[1][18] != 1 {
+		t.Fatalf("arrival = %v", h.Arrival)
+	}
+	if h.Departure[0][36] != 1 || h.Departure[6][47] != 1 || h.Departure[1][36] != 0 {
+		t.Fatalf("departure = %v", h.Departure)
+	}
+
+	f, err := HeatmapExcel(h, i18n.ZhCN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	formats, err := f.GetConditionalFormats(f.GetSheetName(0))
+	if err != nil || len(formats["B4:H51"]) != 1 || len(formats["K4:Q51"]) != 1 {
+		t.Fatalf("conditional formats = %v, %v", formats, err)
+	}
+}
+
+func TestValidSlot(t *testing.T) {
+	for slot, want := range map[int]bool{0: false, 7: false, 15: true, 30: true, 60: true, 90: true, 1440: true} {
+		if got := validSlot(slot); got != want {
+			t.Errorf("validSlot(%d) = %v, want %v", slot, got, want)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"tool-attendance/config"
+	"tool-attendance/log"
+	"tool-attendance/model"
+	"tool-attendance/utils"
+	"tool-attendance/utils/aws_s3"
+	"tool-attendance/utils/gtimer"
+	"tool-attendance/utils/workpool"
+)
+
+// 报表类型
+const (
+	KindDetail  = "detail"  // 考勤明细
+	KindRecord  = "record"  // 考勤记录
+	KindPayroll = "payroll" // 薪资扣款
+	KindHeatmap = "heatmap" // 打卡时间热力图
+)
+
+type builder func(year, month int, lang string) (*excelize.File, error)
+
+var builders = map[string]builder{
+	KindDetail:  BuildDetail,
+	KindRecord:  BuildRecord,
+	KindPayroll: BuildPayroll,
+	KindHeatmap: BuildHeatmap,
+}
+
+// 过期报表文件的清理间隔
+const cleanInterval = 10 * time.Minute
+
+var (
+	// ErrNotArchived 报表未归档到对象存储
+	ErrNotArchived = errors.New("report is not archived")
+	// ErrJobBusy 排队的报表任务已达上限
+	ErrJobBusy = errors.New("too many report jobs in queue, try again later")
+)
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+var (
+	jobCfg    config.ReportConfig
+	jobPool   *workpool.Pool
+	jobCancel context.CancelFunc
+)
+
+func IsValidKind(kind string) bool {
+	_, ok := builders[kind]
+	return ok
+}
+
+// InitJobs 启动报表任务的 worker 和过期文件清理，并恢复服务重启前未完成的任务
+func InitJobs(cfg config.ReportConfig) error {
+	if err := os.MkdirAll(cfg.Dir, os.ModePerm); err != nil {
+		return err
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 100
+	}
+	jobCfg = cfg
+	jobPool = workpool.NewWithQueue(cfg.Workers, cfg.QueueSize)
+
+	var ctx context.Context
+	ctx, jobCancel = context.WithCancel(context.Background())
+	gtimer.SetInterval(cleanInterval, ctx, cleanExpiredJobs)
+
+	jobs, err := model.FindUnfinishedReportJobs()
+	if err != nil {
+		return err
+	}
+	for i := range jobs {
+		if !submit(&jobs[i]) {
+			newJobWorker(&jobs[i]).fail(ErrJobBusy.Error())
+		}
+	}
+	return nil
+}
+
+// StopJobs 停止接收任务并等待正在执行的任务结束
+func StopJobs() {
+	if jobPool == nil {
+		return
+	}
+	jobCancel()
+	jobPool.Shutdown()
+}
+
+// SubmitJob 创建报表任务，任务在后台 worker 中执行
+func SubmitJob(kind string, year, month int, lang, callbackUrl, createdBy string) (*model.ReportJob, error) {
+	job := &model.ReportJob{
+		ID:          utils.UUID(),
+		Kind:        kind,
+		Year:        year,
+		Month:       month,
+		Lang:        lang,
+		Status:      model.ReportJobPending,
+		CallbackUrl: callbackUrl,
+		CreatedBy:   createdBy,
+		CreatedAt:   time.Now(),
+	}
+	if err := model.CreateReportJob(job); err != nil {
+		return nil, err
+	}
+	if !submit(job) {
+		newJobWorker(job).fail(ErrJobBusy.Error())
+		return nil, ErrJobBusy
+	}
+	return job, nil
+}
+
+// submit 把任务放入有上限的队列，队列已满时返回 false，不阻塞请求
+func submit(job *model.ReportJob) bool {
+	return jobPool.TrySubmit(newJobWorker(job))
+}
+
+// DownloadUrl 报表文件的下载地址
+func DownloadUrl(id string) string {
+	return fmt.Sprintf("%s/api/v1/report/jobs/%s/download", strings.TrimRight(config.GetConfig().App.BaseUrl, "/"), id)
+}
+
+type jobWorker struct {
+	job *model.ReportJob
+}
+
+func newJobWorker(job *model.ReportJob) *jobWorker {
+	return &jobWorker{job: job}
+}
+
+func (w *jobWorker) Task() (err error) {
+	// 生成报表 panic 时标记任务失败，否则任务会一直处于 running 直到服务重启
+	defer func() {
+		if r := recover(); r != nil {
+			w.fail(fmt.Sprintf("panic: %v", r))
+			err = fmt.Errorf("report job %s panic: %v", w.job.ID, r)
+		}
+	}()
+
+	job := w.job
+	startedAt := time.Now()
+	job.Status, job.StartedAt = model.ReportJobRunning, &startedAt
+	if err := model.UpdateReportJob(job.ID, map[string]interface{}{
+		"status":     job.Status,
+		"started_at": startedAt,
+	}); err != nil {
+		return err
+	}
+
+	filePath, err := w.build()
+	if err != nil {
+		w.fail(err.Error())
+		return fmt.Errorf("report job %s: %w", job.ID, err)
+	}
+
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	expiredAt := finishedAt.Add(jobCfg.RetentionHours * time.Hour)
+	job.Status, job.FilePath, job.ExpiredAt = model.ReportJobSuccess, filePath, &expiredAt
+	if err = model.UpdateReportJob(job.ID, map[string]interface{}{
+		"status":      job.Status,
+		"file_path":   filePath,
+		"finished_at": finishedAt,
+		"expired_at":  expiredAt,
+	}); err != nil {
+		return err
+	}
+	archive(job)
+	go notifyCallback(job)
+	return nil
+}
+
+// fail 标记任务失败并回调
+func (w *jobWorker) fail(msg string) {
+	job := w.job
+	finishedAt := time.Now()
+	job.Status, job.Error, job.FinishedAt = model.ReportJobFailed, msg, &finishedAt
+	if err := model.UpdateReportJob(job.ID, map[string]interface{}{
+		"status":      job.Status,
+		"error":       job.Error,
+		"finished_at": finishedAt,
+	}); err != nil {
+		log.Log.Error("update report job err:", err)
+	}
+	go notifyCallback(job)
+}
+
+func (w *jobWorker) build() (string, error) {
+	build, ok := builders[w.job.Kind]
+	if !ok {
+		return "", fmt.Errorf("unknown report kind: %s", w.job.Kind)
+	}
+	f, err := build(w.job.Year, w.job.Month, w.job.Lang)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	filePath := filepath.Join(jobCfg.Dir, w.job.ID+".xlsx")
+	return filePath, f.SaveAs(filePath)
+}
+
+// archive 启用对象存储时把报表上传到 reports/年/月/ 下长期保存，失败不影响任务结果
+func archive(job *model.ReportJob) {
+	if aws_s3.GetStorage() == nil {
+		return
+	}
+	key := fmt.Sprintf("reports/%d/%02d/%s_%s.xlsx", job.Year, job.Month, job.Kind, job.ID)
+	if err := aws_s3.UploadLocalFile(key, job.FilePath, xlsxContentType); err != nil {
+		log.Log.WithAlarm().Errorf("archive report job %s err:%v", job.ID, err)
+		return
+	}
+	job.ObjectKey = key
+	if err := model.UpdateReportJob(job.ID, map[string]interface{}{"object_key": key}); err != nil {
+		log.Log.Error("update report job err:", err)
+	}
+}
+
+// FileName 报表下载时使用的文件名
+func FileName(job *model.ReportJob) string {
+	return fmt.Sprintf("attendance_%s_%d%02d.xlsx", job.Kind, job.Year, job.Month)
+}
+
+// PresignUrl 生成已归档报表的限时下载链接，expire 为 0 时使用配置的默认有效期
+func PresignUrl(job *model.ReportJob, expire time.Duration) (string, time.Time, error) {
+	if job.ObjectKey == "" || aws_s3.GetStorage() == nil {
+		return "", time.Time{}, ErrNotArchived
+	}
+	if expire <= 0 {
+		expire = config.GetConfig().S3.PresignExpire * time.Minute
+	}
+	u, err := aws_s3.PresignGetObject(job.ObjectKey, FileName(job), expire)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return u, time.Now().Add(expire), nil
+}
+
+// notifyCallback 任务结束后回调客户端
+func notifyCallback(job *model.ReportJob) {
+	if job.CallbackUrl == "" {
+		return
+	}
+	payload := map[string]interface{}{
+		"job_id": job.ID,
+		"status": job.Status,
+		"error":  job.Error,
+	}
+	if job.Status == model.ReportJobSuccess {
+		payload["download_url"] = DownloadUrl(job.ID)
+	}
+	code, err := postCallback(job.CallbackUrl, payload)
+	if err != nil || code != 200 {
+		log.Log.Errorf("report job %s callback %s failed, code:%d, err:%v", job.ID, job.CallbackUrl, code, err)
+	}
+}
+
+// cleanExpiredJobs 删除超过保留时长的报表文件
+func cleanExpiredJobs() {
+	jobs, err := model.FindExpiredReportJobs(time.Now())
+	if err != nil {
+		log.Log.Error("find expired report jobs err:", err)
+		return
+	}
+	for _, v := range jobs {
+		if err = os.Remove(v.FilePath); err != nil && !os.IsNotExist(err) {
+			log.Log.Errorf("remove report file %s err:%v", v.FilePath, err)
+			continue
+		}
+		err = model.UpdateReportJob(v.ID, map[string]interface{}{
+			"status":    model.ReportJobExpired,
+			"file_path": "",
+		})
+		if err != nil {
+			log.Log.Error("update report job err:", err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package report
+
+import (
+	"sort"
+	"time"
+
+	"tool-attendance/model"
+	"tool-attendance/utils/tz"
+)
+
+// monthData 生成月度报表需要的数据
+type monthData struct {
+	calendarMap map[string]model.Calendar
+	userRecords [][]model.Record // 内部的每个数组是单个用户的记录
+	zones       *ZoneResolver
+	places      map[string]string // 打卡地点，见 loadPlaces
+	defaultLoc  *time.Location
+}
+
+func loadMonth(year, month int) (*monthData, error) {
+	defaultLoc := tz.Default()
+
+	// 获取打卡记录
+	rt := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, defaultLoc)
+	firstDate := getFirstDateOfMonth(rt)
+	lastDate := getLastDateOfMonth(rt)
+	recordList, err := model.FindRecordList(firstDate, lastDate)
+	if err != nil {
+		return nil, err
+	}
+
+	// 获取日历
+	calendarMap, err := loadCalendar(year, month)
+	if err != nil {
+		return nil, err
+	}
+
+	// 打卡地点
+	places, err := loadPlaces("", firstDate, lastDate)
+	if err != nil {
+		return nil, err
+	}
+
+	// 用户时区
+	zones, err := NewZoneResolver()
+	if err != nil {
+		return nil, err
+	}
+
+	// 排序
+	sort.Sort(model.RecordList(recordList))
+
+	// 整理记录
+	allRecordList := make([][]model.Record, 0, 50)
+	allRecordMap := make(map[string]int, 50)
+	for _, v := range recordList {
+		if index, ok := allRecordMap[v.UserId]; !ok {
+			allRecordList = append(allRecordList, []model.Record{v})
+			allRecordMap[v.UserId] = len(allRecordList) - 1
+		} else {
+			allRecordList[index] = append(allRecordList[index], v)
+		}
+	}
+
+	return &monthData{
+		calendarMap: calendarMap,
+		userRecords: allRecordList,
+		zones:       zones,
+		places:      places,
+		defaultLoc:  defaultLoc,
+	}, nil
+}
+
+// LastMonth 上个月的年份和月份
+func LastMonth(now time.Time) (int, int) {
+	t := getFirstDateOfMonth(now).AddDate(0, -1, 0)
+	return t.Year(), int(t.Month())
+}
@@ -0,0 +1,312 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"tool-attendance/config"
+	"tool-attendance/model"
+	"tool-attendance/utils/i18n"
+	"tool-attendance/utils/tz"
+)
+
+// 薪资导出字段
+const (
+	PayrollUserId          = "user_id"
+	PayrollName            = "name"
+	PayrollWorkDay         = "work_day"
+	PayrollAbsentDay       = "absent_day"
+	PayrollHalfAbsentDay   = "half_absent_day"
+	PayrollLateCount       = "late_count"
+	PayrollLateMinutes     = "late_minutes"
+	PayrollEarlyCount      = "early_count"
+	PayrollEarlyMinutes    = "early_minutes"
+	PayrollUnpaidLeaveDays = "unpaid_leave_days"
+	PayrollLatePenalty     = "late_penalty"
+	PayrollEarlyPenalty    = "early_penalty"
+	PayrollTotalPenalty    = "total_penalty"
+)
+
+var PayrollFields = []string{
+	PayrollUserId, PayrollName, PayrollWorkDay, PayrollAbsentDay, PayrollHalfAbsentDay,
+	PayrollLateCount, PayrollLateMinutes, PayrollEarlyCount, PayrollEarlyMinutes,
+	PayrollUnpaidLeaveDays, PayrollLatePenalty, PayrollEarlyPenalty, PayrollTotalPenalty,
+}
+
+// 分钟数取整方式
+const (
+	RoundNone    = "none"
+	RoundUp      = "up"
+	RoundDown    = "down"
+	RoundNearest = "nearest"
+)
+
+// PayrollRow 用户当月的扣款数据，分钟数为按天取整后的合计
+type PayrollRow struct {
+	UserId          string  `json:"user_id"`
+	Name            string  `json:"name"`
+	WorkDay         int     `json:"work_day"`
+	AbsentDay       int     `json:"absent_day"`
+	HalfAbsentDay   int     `json:"half_absent_day"` // 迟到、早退按半天旷工处理的天数，见规则 tiers
+	LateCount       int     `json:"late_count"`
+	LateMinutes     int     `json:"late_minutes"`
+	EarlyCount      int     `json:"early_count"`
+	EarlyMinutes    int     `json:"early_minutes"`
+	UnpaidLeaveDays float64 `json:"unpaid_leave_days"` // 当月工作日中已批准的事假天数，半天假按 0.5 天计，没有事假时为 0
+	LatePenalty     float64 `json:"late_penalty"`
+	EarlyPenalty    float64 `json:"early_penalty"`
+	TotalPenalty    float64 `json:"total_penalty"`
+}
+
+func (r *PayrollRow) value(field string) interface{} {
+	switch field {
+	case PayrollUserId:
+		return r.UserId
+	case PayrollName:
+		return r.Name
+	case PayrollWorkDay:
+		return r.WorkDay
+	case PayrollAbsentDay:
+		return r.AbsentDay
+	case PayrollHalfAbsentDay:
+		return r.HalfAbsentDay
+	case PayrollLateCount:
+		return r.LateCount
+	case PayrollLateMinutes:
+		return r.LateMinutes
+	case PayrollEarlyCount:
+		return r.EarlyCount
+	case PayrollEarlyMinutes:
+		return r.EarlyMinutes
+	case PayrollUnpaidLeaveDays:
+		return r.UnpaidLeaveDays
+	case PayrollLatePenalty:
+		return r.LatePenalty
+	case PayrollEarlyPenalty:
+		return r.EarlyPenalty
+	case PayrollTotalPenalty:
+		return r.TotalPenalty
+	}
+	return nil
+}
+
+// Payroll 按配置计算月度扣款数据
+func Payroll(year, month int, cfg config.PayrollConfig) ([]PayrollRow, error) {
+	res, err := Compute(year, month)
+	if err != nil {
+		return nil, err
+	}
+	first := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, tz.Default())
+	leaves, err := model.FindApprovedLeaveList(model.LeaveTypeUnpaid, first, getLastDateOfMonth(first))
+	if err != nil {
+		return nil, err
+	}
+	unpaid := make(map[string][]model.LeaveRequest)
+	for _, v := range leaves {
+		unpaid[v.UserId] = append(unpaid[v.UserId], v)
+	}
+	return payrollRows(res, cfg, unpaid), nil
+}
+
+// payrollRows unpaid 为用户当月已批准的事假
+func payrollRows(res *MonthResult, cfg config.PayrollConfig, unpaid map[string][]model.LeaveRequest) []PayrollRow {
+	tiers := sortTiers(cfg.Tiers)
+	rows := make([]PayrollRow, 0, len(res.Users))
+	for _, u := range res.Users {
+		row := PayrollRow{
+			UserId:        u.UserId,
+			Name:          u.Name(),
+			WorkDay:       u.Stat.WorkDay,
+			AbsentDay:     u.Stat.AbsentDay,
+			HalfAbsentDay: u.Stat.HalfAbsentDay,
+		}
+		row.UnpaidLeaveDays = leaveDays(res.Year, res.Month, u.Days, unpaid[u.UserId])
+		for _, d := range u.Days {
+			if d.Late {
+				minutes := roundMinutes(d.LateMinutes, cfg.Rounding, cfg.RoundUnit)
+				row.LateCount++
+				row.LateMinutes += minutes
+				row.LatePenalty += penalty(minutes, tiers)
+			}
+			if d.Early {
+				minutes := roundMinutes(d.EarlyMinutes, cfg.Rounding, cfg.RoundUnit)
+				row.EarlyCount++
+				row.EarlyMinutes += minutes
+				row.EarlyPenalty += penalty(minutes, tiers)
+			}
+		}
+		row.LatePenalty = roundAmount(row.LatePenalty, cfg.Decimals)
+		row.EarlyPenalty = roundAmount(row.EarlyPenalty, cfg.Decimals)
+		row.TotalPenalty = roundAmount(row.LatePenalty+row.EarlyPenalty, cfg.Decimals)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// leaveDays 请假覆盖的当月工作日天数，半天假按 0.5 天计
+func leaveDays(year, month int, days []DayResult, leaves []model.LeaveRequest) float64 {
+	loc := tz.Default()
+	var n float64
+	for _, l := range leaves {
+		begin, end := l.StartDate.In(loc).Format(formatDayTime), l.EndDate.In(loc).Format(formatDayTime)
+		for _, d := range days {
+			day := fmt.Sprintf("%d-%02d-%02d", year, month, d.Day)
+			if !d.Workday || day < begin || day > end {
+				continue
+			}
+			if l.HalfDay {
+				n += 0.5
+			} else {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// roundMinutes 按单位取整分钟数
+func roundMinutes(minutes int, mode string, unit int) int {
+	if unit <= 1 || minutes%unit == 0 {
+		return minutes
+	}
+	switch mode {
+	case RoundUp:
+		return (minutes/unit + 1) * unit
+	case RoundDown:
+		return minutes / unit * unit
+	case RoundNearest:
+		return (minutes + unit/2) / unit * unit
+	}
+	return minutes
+}
+
+// sortTiers 按上限从小到大排列，不设上限的档位放在最后
+func sortTiers(tiers []config.PenaltyTier) []config.PenaltyTier {
+	sorted := append([]config.PenaltyTier(nil), tiers...)
+	sortByMaxMinutes(sorted, func(i int) int { return sorted[i].MaxMinutes })
+	return sorted
+}
+
+// penalty 单次迟到或早退的扣款，没有匹配的档位时不扣款
+func penalty(minutes int, tiers []config.PenaltyTier) float64 {
+	for _, t := range tiers {
+		if t.MaxMinutes == 0 || minutes <= t.MaxMinutes {
+			return t.Amount
+		}
+	}
+	return 0
+}
+
+func roundAmount(v float64, decimals int) float64 {
+	p := math.Pow10(decimals)
+	return math.Round(v*p) / p
+}
+
+// CheckPayrollConfig 校验薪资导出配置，启动时调用，避免导出时才发现配置错误
+func CheckPayrollConfig(cfg config.PayrollConfig) error {
+	if _, _, err := payrollColumns(cfg, i18n.ZhCN); err != nil {
+		return err
+	}
+	switch cfg.Rounding {
+	case "", RoundNone, RoundUp, RoundDown, RoundNearest:
+	default:
+		return fmt.Errorf("unknown payroll rounding: %s", cfg.Rounding)
+	}
+	for _, t := range cfg.Tiers {
+		if t.MaxMinutes < 0 {
+			return fmt.Errorf("payroll tier max_minutes must not be negative: %d", t.MaxMinutes)
+		}
+	}
+	return nil
+}
+
+// payrollColumns 导出的字段和表头
+func payrollColumns(cfg config.PayrollConfig, lang string) ([]string, []string, error) {
+	if len(cfg.Columns) == 0 {
+		titles := make([]string, 0, len(PayrollFields))
+		for _, f := range PayrollFields {
+			titles = append(titles, i18n.T(lang, "payroll."+f))
+		}
+		return PayrollFields, titles, nil
+	}
+	fields := make([]string, 0, len(cfg.Columns))
+	titles := make([]string, 0, len(cfg.Columns))
+	for _, c := range cfg.Columns {
+		if (&PayrollRow{}).value(c.Field) == nil {
+			return nil, nil, fmt.Errorf("unknown payroll field: %s", c.Field)
+		}
+		title := c.Title
+		if title == "" {
+			title = i18n.T(lang, "payroll."+c.Field)
+		}
+		fields = append(fields, c.Field)
+		titles = append(titles, title)
+	}
+	return fields, titles, nil
+}
+
+// WritePayrollCsv 按配置的列输出 csv
+func WritePayrollCsv(w io.Writer, rows []PayrollRow, cfg config.PayrollConfig, lang string) error {
+	fields, titles, err := payrollColumns(cfg, lang)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err = cw.Write(titles); err != nil {
+		return err
+	}
+	for i := range rows {
+		record := make([]string, 0, len(fields))
+		for _, f := range fields {
+			record = append(record, fmt.Sprint(rows[i].value(f)))
+		}
+		if err = cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// PayrollExcel 按配置的列生成 excel
+func PayrollExcel(year, month int, rows []PayrollRow, cfg config.PayrollConfig, lang string) (*excelize.File, error) {
+	fields, titles, err := payrollColumns(cfg, lang)
+	if err != nil {
+		return nil, err
+	}
+	f := excelize.NewFile()
+	sheetName := i18n.T(lang, "payroll.title", year, month)
+	_ = f.SetSheetName("Sheet1", sheetName)
+	head := make([]interface{}, 0, len(titles))
+	for _, t := range titles {
+		head = append(head, t)
+	}
+	_ = f.SetSheetRow(sheetName, "A1", &head)
+	for i := range rows {
+		values := make([]interface{}, 0, len(fields))
+		for _, field := range fields {
+			values = append(values, rows[i].value(field))
+		}
+		cell, _ := excelize.JoinCellName("A", i+2)
+		_ = f.SetSheetRow(sheetName, cell, &values)
+	}
+	styleHead, _ := getExcelStyle(f, cellStyleHead)
+	lastHeadCel, _ := excelize.CoordinatesToCellName(len(fields), 1)
+	_ = f.SetCellStyle(sheetName, "A1", lastHeadCel, styleHead)
+	_ = f.SetColWidth(sheetName, "A", convertToTitle(len(fields)), 14)
+	return f, nil
+}
+
+// BuildPayroll 按配置文件生成薪资扣款表，用于异步报表任务
+func BuildPayroll(year, month int, lang string) (*excelize.File, error) {
+	cfg := config.GetConfig().Payroll
+	rows, err := Payroll(year, month, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return PayrollExcel(year, month, rows, cfg, lang)
+}
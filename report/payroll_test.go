@@ -0,0 +1,114 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"tool-attendance/config"
+	"tool-attendance/model"
+	"tool-attendance/utils/i18n"
+	"tool-attendance/utils/tz"
+)
+
+func TestRoundMinutes(t *testing.T) {
+	cases := []struct {
+		minutes int
+		mode    string
+		unit    int
+		want    int
+	}{
+		{7, RoundNone, 15, 7},
+		{7, RoundUp, 15, 15},
+		{7, RoundDown, 15, 0},
+		{7, RoundNearest, 15, 0},
+		{8, RoundNearest, 15, 15},
+		{30, RoundUp, 15, 30},
+		{31, RoundUp, 1, 31},
+	}
+	for _, c := range cases {
+		if got := roundMinutes(c.minutes, c.mode, c.unit); got != c.want {
+			t.Errorf("roundMinutes(%d, %s, %d) = %d, want %d", c.minutes, c.mode, c.unit, got, c.want)
+		}
+	}
+}
+
+func TestPayrollRows(t *testing.T) {
+	cfg := config.PayrollConfig{
+		Rounding:  RoundUp,
+		RoundUnit: 5,
+		Decimals:  2,
+		Tiers: []config.PenaltyTier{
+			{MaxMinutes: 0, Amount: 100},
+			{MaxMinutes: 30, Amount: 50},
+			{MaxMinutes: 10, Amount: 20},
+		},
+	}
+	res := &MonthResult{Users: []UserResult{{
+		UserId:    "u1",
+		Firstname: "张三",
+		Stat:      UserStat{WorkDay: 3, AbsentDay: 1},
+		Days: []DayResult{
+			{Day: 1, Late: true, LateMinutes: 3},
+			{Day: 2, Late: true, LateMinutes: 12, Early: true, EarlyMinutes: 45},
+			{Day: 3},
+		},
+	}}}
+	rows := payrollRows(res, cfg, nil)
+	if len(rows) != 1 {
+		t.Fatalf("rows = %d", len(rows))
+	}
+	want := PayrollRow{
+		UserId: "u1", Name: "张三", WorkDay: 3, AbsentDay: 1,
+		LateCount: 2, LateMinutes: 20, EarlyCount: 1, EarlyMinutes: 45,
+		LatePenalty: 70, EarlyPenalty: 100, TotalPenalty: 170,
+	}
+	if rows[0] != want {
+		t.Fatalf("row = %+v, want %+v", rows[0], want)
+	}
+
+	var buf bytes.Buffer
+	cfg.Columns = []config.PayrollColumn{{Field: PayrollUserId, Title: "EmpNo"}, {Field: PayrollTotalPenalty}}
+	if err := WritePayrollCsv(&buf, rows, cfg, i18n.ZhCN); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "EmpNo,扣款合计\nu1,170\n" {
+		t.Fatalf("csv = %q", got)
+	}
+
+	cfg.Columns = []config.PayrollColumn{{Field: "salary"}}
+	if err := WritePayrollCsv(&buf, rows, cfg, i18n.ZhCN); err == nil {
+		t.Fatal("unknown field accepted")
+	}
+}
+
+func TestLeaveDays(t *testing.T) {
+	loc := tz.Default()
+	days := []DayResult{{Day: 1, Workday: true}, {Day: 2, Workday: true}, {Day: 3}, {Day: 4, Workday: true}}
+	leaves := []model.LeaveRequest{
+		{StartDate: time.Date(2023, 4, 28, 0, 0, 0, 0, loc), EndDate: time.Date(2023, 5, 3, 0, 0, 0, 0, loc)},
+		{StartDate: time.Date(2023, 5, 4, 0, 0, 0, 0, loc), EndDate: time.Date(2023, 5, 4, 0, 0, 0, 0, loc), HalfDay: true},
+	}
+	// 5 月 1、2 日为工作日，3 日休息，4 日半天
+	if got := leaveDays(2023, 5, days, leaves); got != 2.5 {
+		t.Errorf("leaveDays = %v", got)
+	}
+}
+
+func TestCheckPayrollConfig(t *testing.T) {
+	cases := []struct {
+		cfg config.PayrollConfig
+		ok  bool
+	}{
+		{config.PayrollConfig{}, true},
+		{config.PayrollConfig{Columns: []config.PayrollColumn{{Field: PayrollUserId}, {Field: PayrollTotalPenalty, Title: "扣款"}}, Rounding: RoundUp}, true},
+		{config.PayrollConfig{Columns: []config.PayrollColumn{{Field: "salary"}}}, false},
+		{config.PayrollConfig{Rounding: "ceil"}, false},
+		{config.PayrollConfig{Tiers: []config.PenaltyTier{{MaxMinutes: -1}}}, false},
+	}
+	for i, c := range cases {
+		if err := CheckPayrollConfig(c.cfg); (err == nil) != c.ok {
+			t.Fatalf("case %d: err = %v, want ok %v", i, err, c.ok)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package report
+
+import (
+	"github.com/xuri/excelize/v2"
+	"tool-attendance/utils/i18n"
+)
+
+// BuildRecord 生成月度考勤记录表：每人每天是否打卡及当月统计，统计口径见 Compute
+func BuildRecord(year, month int, lang string) (*excelize.File, error) {
+	res, err := Compute(year, month)
+	if err != nil {
+		return nil, err
+	}
+
+	f := excelize.NewFile()
+
+	// 当月天数
+	totalDay := res.TotalDay
+
+	//--设置工作表名称
+	//根据给定的新旧工作表名称（大小写敏感）重命名工作表。工作表名称最多允许使用 31 个字符，
+	//此功能仅更改工作表的名称，而不会更新与单元格关联的公式或引用中的工作表名称。
+	//因此使用此功能重命名工作表后可能导致公式错误或参考引用问题。
+	sheetName := i18n.T(lang, "report.title", year, month)
+	_ = f.SetSheetName("Sheet1", sheetName) //设置工作表的名称
+
+	tableRecords := [][]interface{}{
+		{sheetName}, // 标题：2023年3月考勤记录
+		{i18n.T(lang, "report.serial"), i18n.T(lang, "report.name"), i18n.T(lang, "report.week")}, // head：序号-姓名-星期
+		{nil, nil, i18n.T(lang, "report.date")},                                                   // head：日期
+	}
+
+	// 日期星期
+	for i := 1; i <= totalDay; i++ {
+		tableRecords[1] = append(tableRecords[1], getWeek(lang, year, month, i)) // 星期
+		tableRecords[2] = append(tableRecords[2], i)                             // 日期
+	}
+
+	tableRecords[1] = append(tableRecords[1], i18n.T(lang, "report.summary", res.NeedWorkDay))
+	tableRecords[2] = append(tableRecords[2], []interface{}{
+		i18n.T(lang, "stat.attend"), i18n.T(lang, "stat.absent"), i18n.T(lang, "stat.late"),
+		i18n.T(lang, "stat.early"), i18n.T(lang, "stat.short"), i18n.T(lang, "stat.lack"),
+	}...)
+
+	// 记录数据
+	for i, user := range res.Users {
+		// 上班：
+		onWorkRow := []interface{}{i + 1, user.Firstname, i18n.T(lang, "report.onwork")}
+		// 下班
+		offWorkRow := []interface{}{nil, nil, i18n.T(lang, "report.offwork")}
+
+		for _, day := range user.Days {
+			var (
+				onWork  = ""
+				offWork = ""
+			)
+			if day.Present {
+				onWork, offWork = noCardSymbol, noCardSymbol
+				if !day.OnworkTime.IsZero() {
+					onWork = cardSymbol
+				}
+				if !day.OffworkTime.IsZero() {
+					offWork = cardSymbol
+				}
+			} else if day.Absent {
+				// 缺勤
+				onWork, offWork = noCardSymbol, noCardSymbol
+			}
+			onWorkRow = append(onWorkRow, onWork)
+			offWorkRow = append(offWorkRow, offWork)
+		}
+		st := user.Stat
+		onWorkRow = append(onWorkRow, st.WorkDay, st.AbsentDay, st.LateDay, st.EarlyDay, st.ShortDay, st.LackCardDay)
+		tableRecords = append(tableRecords, onWorkRow)
+		tableRecords = append(tableRecords, offWorkRow)
+	}
+
+	// 图例
+	legendRow := []interface{}{nil, i18n.T(lang, "legend.title"), i18n.T(lang, "legend.card"), i18n.T(lang, "legend.no_card"), i18n.T(lang, "legend.rest")}
+	tableRecords = append(tableRecords, nil, legendRow)
+
+	for i, obj := range tableRecords {
+		//--根据行和列拼接单元格名称
+		name, _ := excelize.JoinCellName("A", i+1)
+
+		//--按行赋值
+		//根据给定的工作表名称（大小写敏感）、起始坐标和 slice 类型引用按行赋值。
+		//例如，在名为 Sheet1 的工作簿第 6 行上，以 B6 单元格作为起始坐标按行赋值：
+		//err := f.SetSheetRow("Sheet1", "B6", &[]interface{}{"1", nil, 2})
+		_ = f.SetSheetRow(sheetName, name, &obj)
+	}
+
+	for i := 1; i <= totalDay; i++ {
+		tableRecords[1] = append(tableRecords[1], getWeek(lang, year, month, i)) // 星期
+		tableRecords[2] = append(tableRecords[2], i)                             // 日期
+	}
+
+	//--单元格样式
+	//func (f *File) SetCellStyle(sheet, hcell, vcell string, styleID int) error
+	//根据给定的工作表名、单元格坐标区域和样式索引设置单元格的值
+	//。样式索引可以通过 NewStyle 函数获取。
+	//注意，在同一个坐标区域内的 diagonalDown 和 diagonalUp 需要保持颜色一致。
+	//SetCellStyle 将覆盖单元格的已有样式，而不会将样式与已有样式叠加或合并。
+	styleTitle, _ := getExcelStyle(f, cellStyleTitle)       // 标题样式
+	styleHead, _ := getExcelStyle(f, cellStyleHead)         // 表头样式
+	styleRecord, _ := getExcelStyle(f, cellStyleRecord)     // 数据记录样式
+	styleAbnormal, _ := getExcelStyle(f, cellStyleAbnormal) // 异常记录
+	_ = styleAbnormal
+
+	// 默认样式
+	lastCel, _ := excelize.CoordinatesToCellName(3+totalDay+6, 3+len(res.Users)*2)
+	_ = f.SetCellStyle(sheetName, "A1", lastCel, styleRecord)
+
+	// 表头样式
+	lastHeadCel, _ := excelize.CoordinatesToCellName(3+totalDay+6, 3)
+	_ = f.SetCellStyle(sheetName, "A2", lastHeadCel, styleHead)
+
+	//设置列宽度
+	//func (f *File) SetColWidth(sheet, startcol, endcol string, width float64) error
+	//根据给定的工作表名称（大小写敏感）、列范围和宽度值设置单个或多个列的宽度。
+	_ = f.SetColWidth(sheetName, "A", "A", 5)                             // 序号列
+	_ = f.SetColWidth(sheetName, "B", "B", 10)                            // 姓名列
+	_ = f.SetColWidth(sheetName, "C", "C", 5)                             // 日期-星期列
+	_ = f.SetColWidth(sheetName, "D", calColumnTitle("D", totalDay-1), 4) // 数据列
+
+	//--合并单元格
+	//根据给定的工作表名（大小写敏感）和单元格坐标区域合并单元格。合并区域内仅保留左上角单元格的值，其他单元格的值将被忽略。
+	//例如，合并名为 Sheet1 的工作表上 D3:E9 区域内的单元格：
+	//err := f.MergeCell("Sheet1", "D3", "E9")
+	//如果给定的单元格坐标区域与已有的其他合并单元格相重叠，已有的合并单元格将会被删除。
+
+	// 标题
+	titleCel, _ := excelize.CoordinatesToCellName(3+totalDay+6, 1)
+	_ = f.SetCellStyle(sheetName, "A1", titleCel, styleTitle)
+	_ = f.MergeCell(sheetName, "A1", titleCel)
+
+	// heda-序号
+	_ = f.MergeCell(sheetName, "A2", "A3")
+
+	// heda-姓名
+	_ = f.MergeCell(sheetName, "B2", "B3")
+
+	// 统计
+	statCel1, _ := excelize.CoordinatesToCellName(1+3+totalDay, 2)
+	statCel2, _ := excelize.CoordinatesToCellName(1+3+totalDay+5, 2)
+	_ = f.MergeCell(sheetName, statCel1, statCel2)
+
+	// 记录
+	for i := range res.Users {
+		// 序号
+		serialNumCel1, _ := excelize.JoinCellName("A", 3+1+i*1+i)
+		serialNumCel2, _ := excelize.JoinCellName("A", 3+1+(i+1)*1+i)
+		_ = f.MergeCell(sheetName, serialNumCel1, serialNumCel2)
+
+		// 姓名
+		nameCel1, _ := excelize.JoinCellName("B", 3+1+i*1+i)
+		nameCel2, _ := excelize.JoinCellName("B", 3+1+(i+1)*1+i)
+		_ = f.MergeCell(sheetName, nameCel1, nameCel2)
+
+		// 出勤
+		attCel1, _ := excelize.CoordinatesToCellName(3+totalDay+1, 3+1+i*1+i)
+		attCel2, _ := excelize.CoordinatesToCellName(3+totalDay+1, 3+1+(i+1)*1+i)
+		_ = f.MergeCell(sheetName, attCel1, attCel2)
+
+		// 旷工
+		absentCel1, _ := excelize.CoordinatesToCellName(3+totalDay+2, 3+1+i*1+i)
+		absentCel2, _ := excelize.CoordinatesToCellName(3+totalDay+2, 3+1+(i+1)*1+i)
+		_ = f.MergeCell(sheetName, absentCel1, absentCel2)
+
+		// 迟到
+		lateCel1, _ := excelize.CoordinatesToCellName(3+totalDay+3, 3+1+i*1+i)
+		lateCel2, _ := excelize.CoordinatesToCellName(3+totalDay+3, 3+1+(i+1)*1+i)
+		_ = f.MergeCell(sheetName, lateCel1, lateCel2)
+
+		// 早退
+		earlyCel1, _ := excelize.CoordinatesToCellName(3+totalDay+4, 3+1+i*1+i)
+		earlyCel2, _ := excelize.CoordinatesToCellName(3+totalDay+4, 3+1+(i+1)*1+i)
+		_ = f.MergeCell(sheetName, earlyCel1, earlyCel2)
+
+		// 时长不足
+		shortCel1, _ := excelize.CoordinatesToCellName(3+totalDay+5, 3+1+i*1+i)
+		shortCel2, _ := excelize.CoordinatesToCellName(3+totalDay+5, 3+1+(i+1)*1+i)
+		_ = f.MergeCell(sheetName, shortCel1, shortCel2)
+
+		// 漏打卡
+		missedCel1, _ := excelize.CoordinatesToCellName(3+totalDay+6, 3+1+i*1+i)
+		missedCel2, _ := excelize.CoordinatesToCellName(3+totalDay+6, 3+1+(i+1)*1+i)
+		_ = f.MergeCell(sheetName, missedCel1, missedCel2)
+	}
+
+	return f, nil
+}
@@ -0,0 +1,379 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"tool-attendance/log"
+	"tool-attendance/model"
+	"tool-attendance/utils/tz"
+	"tool-attendance/utils/workpool"
+)
+
+// 等待后台重新计算的任务数上限
+const rebuildQueueSize = 100
+
+var rebuildPool *workpool.Pool
+
+// 每日汇总（model.DailySummary）在打卡时按天增量更新，日历刷新、员工或工作地点时区变化时按月或按用户重新计算。
+// 打卡合并、远程办公改判地点和时区变化时在写入时把汇总标记为 dirty，读取时只查汇总表：
+// 有 dirty 的汇总、规则或日历与汇总不一致、或该月还没有整月计算过（model.SummaryVersion）时重新计算整月。
+// 读取时不再比对打卡记录，外部同步或直接改库修正的打卡记录需要调用重新计算接口；规则是全局的，没有按员工的班次，
+// 以后增加班次或补卡审批时，需要在变更后调用 RefreshDaySummary 或 RebuildUserSummary
+
+// rulesHash 考勤规则的指纹，用于判断汇总是否按当前规则计算
+func rulesHash(rules Rules) string {
+	b, _ := json.Marshal(rules)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+func toSummary(record model.Record, day DayResult, hash string) model.DailySummary {
+	return model.DailySummary{
+		UserId:       record.UserId,
+		Day:          record.DaysDate,
+		Firstname:    record.Firstname,
+		Username:     record.Username,
+		Workday:      day.Workday,
+		Present:      day.Present,
+		Late:         day.Late,
+		Early:        day.Early,
+		Short:        day.Short,
+		LackCard:     day.LackCard,
+		LateMinutes:  day.LateMinutes,
+		EarlyMinutes: day.EarlyMinutes,
+		LateTier:     day.LateTier,
+		EarlyTier:    day.EarlyTier,
+		HalfAbsent:   day.HalfAbsent,
+		Duration:     day.Duration,
+		OnworkTime:   record.OnworkTime,
+		OffworkTime:  record.OffworkTime,
+		Place:        day.Place,
+		RulesHash:    hash,
+		UpdatedAt:    time.Now(),
+	}
+}
+
+func fromSummary(s model.DailySummary, loc *time.Location) DayResult {
+	return DayResult{
+		Day:          s.Day.In(loc).Day(),
+		Workday:      s.Workday,
+		OnworkTime:   s.OnworkTime,
+		OffworkTime:  s.OffworkTime,
+		Present:      s.Present,
+		Late:         s.Late,
+		Early:        s.Early,
+		Short:        s.Short,
+		LackCard:     s.LackCard,
+		Duration:     s.Duration,
+		LateMinutes:  s.LateMinutes,
+		EarlyMinutes: s.EarlyMinutes,
+		LateTier:     s.LateTier,
+		EarlyTier:    s.EarlyTier,
+		HalfAbsent:   s.HalfAbsent,
+		Place:        s.Place,
+	}
+}
+
+// RefreshDaySummary 重新计算用户某天（Record.DaysDate）的汇总，打卡记录变化后调用
+func RefreshDaySummary(userId string, day time.Time) error {
+	record, err := model.FindRecordByUserDay(userId, day)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.DeleteDailySummary(userId, day)
+	}
+	if err != nil {
+		return err
+	}
+	rules := CurrentRules()
+	r, err := parseRules(rules)
+	if err != nil {
+		return err
+	}
+	d := day.In(tz.Default())
+	year, month := d.Year(), int(d.Month())
+	calendarMap, err := loadCalendar(year, month)
+	if err != nil {
+		return err
+	}
+	loc, err := UserLocation(userId)
+	if err != nil {
+		return err
+	}
+	places, err := loadPlaces(userId, day, day)
+	if err != nil {
+		return err
+	}
+	workday := calendarMap[fmt.Sprintf("%d%02d%02d", year, month, d.Day())].Workday == model.WorkDay
+	result := classifyDay(year, month, d.Day(), workday, *record, loc, r)
+	result.Place = places[placeKey(userId, day)]
+	s := toSummary(*record, result, rulesHash(rules))
+	return model.UpsertDailySummary(&s)
+}
+
+// RebuildSummary 按当前规则重新计算整月的汇总
+func RebuildSummary(year, month int) error {
+	d, err := loadMonth(year, month)
+	if err != nil {
+		return err
+	}
+	rules := CurrentRules()
+	r, err := parseRules(rules)
+	if err != nil {
+		return err
+	}
+	hash := rulesHash(rules)
+	list := make([]model.DailySummary, 0, len(d.userRecords)*20)
+	for _, userRecordList := range d.userRecords {
+		loc := d.zones.Location(userRecordList[0].UserId)
+		for _, v := range userRecordList {
+			i := v.DaysDate.In(d.defaultLoc).Day()
+			workday := d.calendarMap[fmt.Sprintf("%d%02d%02d", year, month, i)].Workday == model.WorkDay
+			day := classifyDay(year, month, i, workday, v, loc, r)
+			day.Place = d.places[placeKey(v.UserId, v.DaysDate)]
+			list = append(list, toSummary(v, day, hash))
+		}
+	}
+	rt := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, d.defaultLoc)
+	if err = model.ReplaceDailySummary("", getFirstDateOfMonth(rt), getLastDateOfMonth(rt), list); err != nil {
+		return err
+	}
+	_, workdays := newMonthResult(year, month, d.calendarMap)
+	return saveSummaryVersion(year, month, rules, workdays)
+}
+
+// saveSummaryVersion 记录整月计算使用的规则和日历，与最近一次相同时不重复记录
+func saveSummaryVersion(year, month int, rules Rules, workdays []bool) error {
+	hash, key := rulesHash(rules), workdaysKey(workdays)
+	v, err := model.FindLatestSummaryVersion(year, month)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if v != nil && v.RulesHash == hash && v.Workdays == key {
+		return nil
+	}
+	rulesJson, _ := json.Marshal(rules)
+	return model.CreateSummaryVersion(&model.SummaryVersion{
+		Year:      year,
+		Month:     month,
+		RulesHash: hash,
+		Rules:     string(rulesJson),
+		Workdays:  key,
+		CreatedAt: time.Now(),
+	})
+}
+
+// workdaysKey 按天拼接是否为工作日，workdays 下标为日期
+func workdaysKey(workdays []bool) string {
+	b := make([]byte, 0, len(workdays))
+	for _, v := range workdays[1:] {
+		if v {
+			b = append(b, '1')
+		} else {
+			b = append(b, '0')
+		}
+	}
+	return string(b)
+}
+
+// calendarFromWorkdays 按 workdaysKey 的结果还原日历，只包含是否为工作日
+func calendarFromWorkdays(year, month int, key string) map[string]model.Calendar {
+	calendarMap := make(map[string]model.Calendar, len(key))
+	for i, v := range key {
+		workday := uint8(model.RestDay)
+		if v == '1' {
+			workday = model.WorkDay
+		}
+		calendarMap[fmt.Sprintf("%d%02d%02d", year, month, i+1)] = model.Calendar{Workday: workday}
+	}
+	return calendarMap
+}
+
+// RebuildUserSummary 按用户当前的时区重新计算该用户所有的汇总
+func RebuildUserSummary(userId string) error {
+	records, err := model.FindRecordListByUser(userId)
+	if err != nil || len(records) == 0 {
+		return err
+	}
+	rules := CurrentRules()
+	r, err := parseRules(rules)
+	if err != nil {
+		return err
+	}
+	loc, err := UserLocation(userId)
+	if err != nil {
+		return err
+	}
+	places, err := loadPlaces(userId, records[0].DaysDate, records[len(records)-1].DaysDate)
+	if err != nil {
+		return err
+	}
+	hash := rulesHash(rules)
+	defaultLoc := tz.Default()
+	calendars := make(map[string]map[string]model.Calendar)
+	list := make([]model.DailySummary, 0, len(records))
+	for _, v := range records {
+		d := v.DaysDate.In(defaultLoc)
+		year, month := d.Year(), int(d.Month())
+		ym := fmt.Sprintf("%d%02d", year, month)
+		calendarMap, ok := calendars[ym]
+		if !ok {
+			if calendarMap, err = loadCalendar(year, month); err != nil {
+				return err
+			}
+			calendars[ym] = calendarMap
+		}
+		workday := calendarMap[fmt.Sprintf("%s%02d", ym, d.Day())].Workday == model.WorkDay
+		day := classifyDay(year, month, d.Day(), workday, v, loc, r)
+		day.Place = places[placeKey(userId, v.DaysDate)]
+		list = append(list, toSummary(v, day, hash))
+	}
+	return model.ReplaceDailySummary(userId, records[0].DaysDate, records[len(records)-1].DaysDate, list)
+}
+
+// RebuildUserSummaryAsync 在后台重新计算用户的汇总，用于员工或工作地点时区变化后；
+// 先把汇总标记为 dirty，后台计算失败或被放弃时读取报表也会重新计算
+func RebuildUserSummaryAsync(userIds ...string) {
+	if err := model.MarkUserSummaryDirty(userIds...); err != nil {
+		log.Log.WithAlarm().Errorf("mark daily summary of %v dirty err:%v", userIds, err)
+	}
+	submitRebuild(fmt.Sprintf("users %v", userIds), func() {
+		for _, id := range userIds {
+			if err := RebuildUserSummary(id); err != nil {
+				log.Log.WithAlarm().Errorf("rebuild daily summary of %s err:%v", id, err)
+			}
+		}
+	})
+}
+
+// InitSummary 启动后台重新计算汇总的 worker
+func InitSummary() {
+	rebuildPool = workpool.NewWithQueue(1, rebuildQueueSize)
+}
+
+// StopSummary 停止接收重新计算任务并等待正在执行的任务结束
+func StopSummary() {
+	if rebuildPool == nil {
+		return
+	}
+	rebuildPool.Shutdown()
+}
+
+// rebuildWorker 后台重新计算汇总的任务，错误在任务中记录
+type rebuildWorker func()
+
+func (w rebuildWorker) Task() error {
+	w()
+	return nil
+}
+
+// submitRebuild 在后台执行重新计算，未启动 worker 时（如命令行）直接执行；
+// 排队已满或已停止时放弃，汇总已标记 dirty 或与日历不一致，读取时会重新计算
+func submitRebuild(name string, fn func()) {
+	if rebuildPool == nil {
+		fn()
+		return
+	}
+	if !rebuildPool.TrySubmit(rebuildWorker(fn)) {
+		log.Log.WithAlarm().Errorf("rebuild queue is full or stopped, skip daily summary of %s", name)
+	}
+}
+
+// rebuildYearSummary 重新计算一年中已经开始的月份，用于日历更新后
+func rebuildYearSummary(year int) {
+	now := time.Now().In(tz.Default())
+	for m := 1; m <= 12; m++ {
+		if year > now.Year() || year == now.Year() && m > int(now.Month()) {
+			return
+		}
+		if err := RebuildSummary(year, m); err != nil {
+			log.Log.WithAlarm().Errorf("rebuild daily summary of %d-%02d err:%v", year, m, err)
+		}
+	}
+}
+
+// computeFromSummary 从每日汇总组装月度考勤，只读取汇总表；汇总待重新计算、与规则或日历不一致时先重新计算整月
+func computeFromSummary(year, month int) (*MonthResult, error) {
+	defaultLoc := tz.Default()
+	rt := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, defaultLoc)
+	firstDate, lastDate := getFirstDateOfMonth(rt), getLastDateOfMonth(rt)
+
+	calendarMap, err := loadCalendar(year, month)
+	if err != nil {
+		return nil, err
+	}
+	res, workdays := newMonthResult(year, month, calendarMap)
+
+	rows, err := model.FindDailySummaryList(firstDate, lastDate)
+	if err != nil {
+		return nil, err
+	}
+	version, err := model.FindLatestSummaryVersion(year, month)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if version == nil || summaryStale(rows, rulesHash(CurrentRules()), workdays, defaultLoc) {
+		if err = RebuildSummary(year, month); err != nil {
+			return nil, err
+		}
+		if rows, err = model.FindDailySummaryList(firstDate, lastDate); err != nil {
+			return nil, err
+		}
+	}
+
+	zones, err := NewZoneResolver()
+	if err != nil {
+		return nil, err
+	}
+	res.Users = assembleSummary(res.TotalDay, workdays, rows, zones, defaultLoc)
+	return res, nil
+}
+
+// summaryStale 汇总是否需要重新计算：有待重新计算的汇总，或规则、日历变化
+func summaryStale(rows []model.DailySummary, hash string, workdays []bool, defaultLoc *time.Location) bool {
+	for _, v := range rows {
+		if v.Dirty || v.RulesHash != hash || v.Workday != workdays[v.Day.In(defaultLoc).Day()] {
+			return true
+		}
+	}
+	return false
+}
+
+// assembleSummary 按用户组装汇总，rows 需按用户和日期排序
+func assembleSummary(totalDay int, workdays []bool, rows []model.DailySummary, zones *ZoneResolver, defaultLoc *time.Location) []UserResult {
+	users := make([]UserResult, 0, 50)
+	for start := 0; start < len(rows); {
+		end := start
+		for end < len(rows) && rows[end].UserId == rows[start].UserId {
+			end++
+		}
+		last := rows[end-1]
+		user := UserResult{
+			UserId:    last.UserId,
+			Firstname: last.Firstname,
+			Username:  last.Username,
+			Location:  zones.Location(last.UserId),
+			Days:      make([]DayResult, totalDay),
+		}
+		days := make(map[int]DayResult, end-start)
+		for _, v := range rows[start:end] {
+			day := fromSummary(v, defaultLoc)
+			days[day.Day] = day
+		}
+		for i := 1; i <= totalDay; i++ {
+			if day, ok := days[i]; ok {
+				user.add(day)
+			} else {
+				user.add(noRecordDay(i, workdays[i]))
+			}
+		}
+		users = append(users, user)
+		start = end
+	}
+	return users
+}
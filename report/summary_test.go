@@ -0,0 +1,86 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"tool-attendance/model"
+	"tool-attendance/utils/tz"
+)
+
+func TestAssembleSummary(t *testing.T) {
+	if err := tz.Init("Asia/Shanghai"); err != nil {
+		t.Fatal(err)
+	}
+	loc := tz.Default()
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2023, 5, day, hour, minute, 0, 0, loc)
+	}
+	records := []model.Record{
+		{UserId: "u1", DaysDate: at(1, 0, 0), OnworkTime: at(1, 10, 0), OffworkTime: at(1, 12, 0)},
+		{UserId: "u1", DaysDate: at(2, 0, 0), OnworkTime: at(2, 9, 50), OffworkTime: at(2, 17, 45)},
+		{UserId: "u1", DaysDate: at(3, 0, 0), OnworkTime: at(3, 9, 0)},
+		{UserId: "u2", Firstname: "李四", DaysDate: at(2, 0, 0), OnworkTime: at(2, 9, 0), OffworkTime: at(2, 19, 0)},
+	}
+	calendarMap := map[string]model.Calendar{"20230501": {Workday: model.RestDay}}
+	for _, d := range []string{"20230502", "20230503", "20230504"} {
+		calendarMap[d] = model.Calendar{Workday: model.WorkDay}
+	}
+	rules := Rules{OnWorkTime: "09:30", OffWorkTime: "18:00", MinHours: 9}
+	want, err := compute(2023, 5, &monthData{
+		calendarMap: calendarMap,
+		userRecords: [][]model.Record{records[:3], records[3:]},
+		zones:       &ZoneResolver{},
+		defaultLoc:  loc,
+	}, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, _ := parseRules(rules)
+	_, workdays := newMonthResult(2023, 5, calendarMap)
+	hash := rulesHash(rules)
+	rows := make([]model.DailySummary, 0, len(records))
+	for _, v := range records {
+		i := v.DaysDate.Day()
+		rows = append(rows, toSummary(v, classifyDay(2023, 5, i, workdays[i], v, loc, r), hash))
+	}
+	if summaryStale(rows, hash, workdays, loc) {
+		t.Fatal("fresh summary is stale")
+	}
+	got := assembleSummary(31, workdays, rows, &ZoneResolver{}, loc)
+	if len(got) != len(want.Users) {
+		t.Fatalf("users = %d, want %d", len(got), len(want.Users))
+	}
+	for i := range got {
+		if got[i].UserId != want.Users[i].UserId || got[i].Stat != want.Users[i].Stat {
+			t.Fatalf("user %d = %+v, want %+v", i, got[i].Stat, want.Users[i].Stat)
+		}
+		for d := range got[i].Days {
+			g, w := got[i].Days[d], want.Users[i].Days[d]
+			if g.Day != w.Day || g.Present != w.Present || g.Absent != w.Absent || g.Late != w.Late ||
+				g.LateMinutes != w.LateMinutes || g.EarlyMinutes != w.EarlyMinutes || g.LackCard != w.LackCard {
+				t.Fatalf("user %d day %d = %+v, want %+v", i, d+1, g, w)
+			}
+		}
+	}
+	if day := got[0].Days[1]; day.LateMinutes != 20 || day.EarlyMinutes != 15 {
+		t.Fatalf("minutes = %+v", day)
+	}
+
+	if !summaryStale(rows, rulesHash(Rules{OnWorkTime: "10:00"}), workdays, loc) {
+		t.Fatal("rules change not detected")
+	}
+	rows[2].Dirty = true
+	if !summaryStale(rows, hash, workdays, loc) {
+		t.Fatal("dirty row not detected")
+	}
+	rows[2].Dirty = false
+	if key := workdaysKey(workdays); len(key) != 31 || key[:4] != "0111" {
+		t.Fatalf("workdays key = %s", key)
+	}
+	workdays[1] = true
+	if !summaryStale(rows, hash, workdays, loc) {
+		t.Fatal("calendar change not detected")
+	}
+}
@@ -0,0 +1,337 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"tool-attendance/model"
+	"tool-attendance/utils/tz"
+)
+
+// 时间粒度
+const (
+	GranularityDay   = "day"
+	GranularityWeek  = "week" // 周一至周日
+	GranularityMonth = "month"
+)
+
+// 对比方式
+const (
+	CompareNone     = ""
+	ComparePrevious = "previous" // 紧邻的上一个同长度区间
+	CompareYear     = "year"     // 去年同期
+)
+
+// TeamAll 所有团队合计的序列
+const TeamAll = "*"
+
+var ErrInvalidGranularity = errors.New("invalid granularity")
+
+// TrendPoint 一个时间段内的考勤指标，只统计工作日
+type TrendPoint struct {
+	Period         string      `json:"period"` // 2006-01-02 / 2006-W01 / 2006-01
+	Begin          string      `json:"begin"`
+	End            string      `json:"end"`
+	ExpectedDays   int         `json:"expected_days"`   // 应出勤人天
+	PresentDays    int         `json:"present_days"`    // 出勤人天
+	AttendanceRate float64     `json:"attendance_rate"` // 出勤率
+	AvgArrival     string      `json:"avg_arrival"`     // 平均上班打卡时间（员工所在时区），15:04
+	AvgDuration    float64     `json:"avg_duration"`    // 平均工作时长（小时）
+	LateCount      int         `json:"late_count"`
+	EarlyCount     int         `json:"early_count"`
+	AbsentCount    int         `json:"absent_count"`
+	LackCardCount  int         `json:"lack_card_count"`
+	AnomalyCount   int         `json:"anomaly_count"`      // 迟到、早退、旷工、漏打卡合计
+	Previous       *TrendPoint `json:"previous,omitempty"` // 对比区间中对应的时间段
+
+	arrivalSum, durationSum float64
+	arrivalN, durationN     int
+}
+
+func (p *TrendPoint) add(day DayResult, loc *time.Location) {
+	if !day.Workday {
+		return
+	}
+	p.ExpectedDays++
+	if day.Present {
+		p.PresentDays++
+	}
+	if !day.OnworkTime.IsZero() {
+		t := day.OnworkTime.In(loc)
+		p.arrivalSum += float64(t.Hour()*60 + t.Minute())
+		p.arrivalN++
+	}
+	if !day.OnworkTime.IsZero() && !day.OffworkTime.IsZero() {
+		p.durationSum += day.Duration
+		p.durationN++
+	}
+	if day.Late {
+		p.LateCount++
+	}
+	if day.Early {
+		p.EarlyCount++
+	}
+	if day.Absent {
+		p.AbsentCount++
+	}
+	if day.LackCard {
+		p.LackCardCount++
+	}
+}
+
+func (p *TrendPoint) merge(o *TrendPoint) {
+	p.ExpectedDays += o.ExpectedDays
+	p.PresentDays += o.PresentDays
+	p.LateCount += o.LateCount
+	p.EarlyCount += o.EarlyCount
+	p.AbsentCount += o.AbsentCount
+	p.LackCardCount += o.LackCardCount
+	p.arrivalSum += o.arrivalSum
+	p.arrivalN += o.arrivalN
+	p.durationSum += o.durationSum
+	p.durationN += o.durationN
+}
+
+// finish 计算比率和平均值
+func (p *TrendPoint) finish() {
+	p.AnomalyCount = p.LateCount + p.EarlyCount + p.AbsentCount + p.LackCardCount
+	if p.ExpectedDays > 0 {
+		p.AttendanceRate = math.Round(float64(p.PresentDays)/float64(p.ExpectedDays)*10000) / 10000
+	}
+	if p.arrivalN > 0 {
+		m := int(math.Round(p.arrivalSum / float64(p.arrivalN)))
+		p.AvgArrival = fmt.Sprintf("%02d:%02d", m/60, m%60)
+	}
+	if p.durationN > 0 {
+		p.AvgDuration = math.Round(p.durationSum/float64(p.durationN)*100) / 100
+	}
+}
+
+// TrendSeries 单个团队的时间序列
+type TrendSeries struct {
+	Team   string       `json:"team"` // TeamAll 为所有团队合计，空字符串为未分配团队的员工
+	Points []TrendPoint `json:"points"`
+	Total  TrendPoint   `json:"total"`
+}
+
+type TrendResult struct {
+	Granularity string        `json:"granularity"`
+	Compare     string        `json:"compare"`
+	Begin       string        `json:"begin"`
+	End         string        `json:"end"`
+	Series      []TrendSeries `json:"series"`
+}
+
+// Trend 统计 [begin, end] 内各团队的考勤趋势，team 为空时返回所有团队及合计，compare 不为空时附带对比区间的数据
+func Trend(begin, end time.Time, granularity, team, compare string) (*TrendResult, error) {
+	if _, err := periodKey(begin, granularity); err != nil {
+		return nil, err
+	}
+	employees, err := model.FindEmployeeMap()
+	if err != nil {
+		return nil, err
+	}
+	// 当前区间只统计到今天，对比区间按截断后的区间计算，两边时长一致
+	if today := getZeroTime(time.Now().In(tz.Default())); getZeroTime(end.In(tz.Default())).After(today) {
+		end = today
+	}
+
+	res := &TrendResult{
+		Granularity: granularity,
+		Compare:     compare,
+		Begin:       begin.Format(formatDayTime),
+		End:         end.Format(formatDayTime),
+	}
+	if res.Series, err = trendSeries(begin, end, granularity, team, employees); err != nil {
+		return nil, err
+	}
+	if compare == CompareNone {
+		return res, nil
+	}
+	prevBegin, prevEnd := previousRange(begin, end, granularity, compare)
+	prev, err := trendSeries(prevBegin, prevEnd, granularity, team, employees)
+	if err != nil {
+		return nil, err
+	}
+	pairSeries(res.Series, prev)
+	return res, nil
+}
+
+// previousRange 对比区间：去年同期，或紧邻的上一个同长度区间（按月统计时按月数平移，
+// end 不是月末时对比区间也截止到同一日）
+func previousRange(begin, end time.Time, granularity, compare string) (time.Time, time.Time) {
+	if compare == CompareYear {
+		return begin.AddDate(-1, 0, 0), end.AddDate(-1, 0, 0)
+	}
+	if granularity == GranularityMonth {
+		months := (end.Year()-begin.Year())*12 + int(end.Month()-begin.Month()) + 1
+		prevEnd := getLastDateOfMonth(end.AddDate(0, -months, 1-end.Day()))
+		if end.Day() < getLastDateOfMonth(end).Day() && end.Day() < prevEnd.Day() {
+			prevEnd = getFirstDateOfMonth(prevEnd).AddDate(0, 0, end.Day()-1)
+		}
+		return begin.AddDate(0, -months, 0), prevEnd
+	}
+	days := int(end.Sub(begin).Hours()/24+0.5) + 1
+	return begin.AddDate(0, 0, -days), begin.AddDate(0, 0, -1)
+}
+
+// trendSeries 统计到今天为止，之后的日期还没有考勤数据，不计入应出勤
+func trendSeries(begin, end time.Time, granularity, team string, employees map[string]model.Employee) ([]TrendSeries, error) {
+	loc := tz.Default()
+	begin, end = getZeroTime(begin.In(loc)), getZeroTime(end.In(loc))
+	if today := getZeroTime(time.Now().In(loc)); end.After(today) {
+		end = today
+	}
+	teams := make(map[string]string, len(employees))
+	for id, e := range employees {
+		teams[id] = e.Team
+	}
+	results := make([]*MonthResult, 0, 12)
+	for m := getFirstDateOfMonth(begin); !m.After(end); m = m.AddDate(0, 1, 0) {
+		res, err := Compute(m.Year(), int(m.Month()))
+		if err != nil {
+			return nil, err
+		}
+		calendarMap, err := loadCalendar(m.Year(), int(m.Month()))
+		if err != nil {
+			return nil, err
+		}
+		_, workdays := newMonthResult(m.Year(), int(m.Month()), calendarMap)
+		results = append(results, withAbsentEmployees(res, employees, workdays, loc))
+	}
+	return buildTrend(begin, end, granularity, team, teams, results, loc), nil
+}
+
+// withAbsentEmployees 补上当月没有任何打卡记录的员工，在职期间的工作日都算旷工，应出勤人天按员工表统计
+func withAbsentEmployees(res *MonthResult, employees map[string]model.Employee, workdays []bool, loc *time.Location) *MonthResult {
+	seen := make(map[string]bool, len(res.Users))
+	for _, u := range res.Users {
+		seen[u.UserId] = true
+	}
+	out := *res
+	out.Users = append([]UserResult(nil), res.Users...)
+	for id, e := range employees {
+		if seen[id] {
+			continue
+		}
+		user := UserResult{UserId: id, Firstname: e.Name, Location: loc, Days: make([]DayResult, 0, res.TotalDay)}
+		for i := 1; i <= res.TotalDay; i++ {
+			day := time.Date(res.Year, time.Month(res.Month), i, 0, 0, 0, 0, loc).Format(formatDayTime)
+			user.Days = append(user.Days, noRecordDay(i, workdays[i] && inService(day, e, loc)))
+		}
+		out.Users = append(out.Users, user)
+	}
+	return &out
+}
+
+// buildTrend 按团队和时间段汇总月度考勤结果，begin、end 为 loc 中的零点
+func buildTrend(begin, end time.Time, granularity, team string, teams map[string]string, results []*MonthResult, loc *time.Location) []TrendSeries {
+	// 时间段
+	var template []TrendPoint
+	index := make(map[string]int)
+	for d := begin; !d.After(end); d = d.AddDate(0, 0, 1) {
+		key, _ := periodKey(d, granularity)
+		i, ok := index[key]
+		if !ok {
+			i = len(template)
+			index[key] = i
+			template = append(template, TrendPoint{Period: key, Begin: d.Format(formatDayTime)})
+		}
+		template[i].End = d.Format(formatDayTime)
+	}
+	newPoints := func() []TrendPoint {
+		return append([]TrendPoint(nil), template...)
+	}
+
+	series := map[string][]TrendPoint{}
+	if team == "" {
+		series[TeamAll] = newPoints()
+	}
+	for _, res := range results {
+		for _, u := range res.Users {
+			userTeam := teams[u.UserId]
+			if team != "" && userTeam != team {
+				continue
+			}
+			points, ok := series[userTeam]
+			if !ok {
+				points = newPoints()
+				series[userTeam] = points
+			}
+			for _, day := range u.Days {
+				date := time.Date(res.Year, time.Month(res.Month), day.Day, 0, 0, 0, 0, loc)
+				if date.Before(begin) || date.After(end) {
+					continue
+				}
+				key, _ := periodKey(date, granularity)
+				points[index[key]].add(day, u.Location)
+				if team == "" {
+					series[TeamAll][index[key]].add(day, u.Location)
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(series))
+	for k := range series {
+		names = append(names, k)
+	}
+	// 合计排在最前，其余按团队名排列
+	sort.Slice(names, func(i, j int) bool {
+		if names[i] == TeamAll || names[j] == TeamAll {
+			return names[i] == TeamAll
+		}
+		return names[i] < names[j]
+	})
+	list := make([]TrendSeries, 0, len(names))
+	for _, name := range names {
+		s := TrendSeries{Team: name, Points: series[name]}
+		s.Total.Period, s.Total.Begin, s.Total.End = "total", begin.Format(formatDayTime), end.Format(formatDayTime)
+		for i := range s.Points {
+			s.Total.merge(&s.Points[i])
+			s.Points[i].finish()
+		}
+		s.Total.finish()
+		list = append(list, s)
+	}
+	return list
+}
+
+// pairSeries 按团队和时间段的顺序关联对比区间的数据
+func pairSeries(cur, prev []TrendSeries) {
+	prevMap := make(map[string]*TrendSeries, len(prev))
+	for i := range prev {
+		prevMap[prev[i].Team] = &prev[i]
+	}
+	for i := range cur {
+		p, ok := prevMap[cur[i].Team]
+		if !ok {
+			continue
+		}
+		total := p.Total
+		cur[i].Total.Previous = &total
+		for j := range cur[i].Points {
+			if j < len(p.Points) {
+				point := p.Points[j]
+				cur[i].Points[j].Previous = &point
+			}
+		}
+	}
+}
+
+// periodKey 日期所在时间段的标识
+func periodKey(d time.Time, granularity string) (string, error) {
+	switch granularity {
+	case GranularityDay:
+		return d.Format(formatDayTime), nil
+	case GranularityWeek:
+		year, week := d.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week), nil
+	case GranularityMonth:
+		return d.Format("2006-01"), nil
+	}
+	return "", ErrInvalidGranularity
+}
@@ -0,0 +1,105 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"tool-attendance/model"
+)
+
+func TestBuildTrend(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2023, 5, day, hour, minute, 0, 0, loc)
+	}
+	res := &MonthResult{Year: 2023, Month: 5, TotalDay: 31, Users: []UserResult{
+		{UserId: "u1", Location: loc, Days: []DayResult{
+			{Day: 1, Workday: false, Present: false},
+			{Day: 2, Workday: true, Present: true, OnworkTime: at(2, 9, 0), OffworkTime: at(2, 18, 0), Duration: 9},
+			{Day: 8, Workday: true, Present: true, Late: true, OnworkTime: at(8, 10, 0), OffworkTime: at(8, 18, 0), Duration: 8},
+		}},
+		{UserId: "u2", Location: loc, Days: []DayResult{
+			{Day: 2, Workday: true, Absent: true},
+			{Day: 9, Workday: true, Present: true, LackCard: true, OnworkTime: at(9, 9, 30)},
+		}},
+	}}
+	teams := map[string]string{"u1": "dev"}
+
+	series := buildTrend(at(1, 0, 0), at(14, 0, 0), GranularityWeek, "", teams, []*MonthResult{res}, loc)
+	if len(series) != 3 || series[0].Team != TeamAll || series[1].Team != "" || series[2].Team != "dev" {
+		t.Fatalf("series = %+v", series)
+	}
+	all := series[0]
+	if len(all.Points) != 2 || all.Points[0].Period != "2023-W18" || all.Points[0].Begin != "2023-05-01" || all.Points[1].End != "2023-05-14" {
+		t.Fatalf("points = %+v", all.Points)
+	}
+	if p := all.Points[0]; p.ExpectedDays != 2 || p.PresentDays != 1 || p.AttendanceRate != 0.5 || p.AbsentCount != 1 || p.AvgArrival != "09:00" {
+		t.Fatalf("week 18 = %+v", p)
+	}
+	if p := all.Total; p.ExpectedDays != 4 || p.PresentDays != 3 || p.AnomalyCount != 3 || p.AvgArrival != "09:30" || p.AvgDuration != 8.5 {
+		t.Fatalf("total = %+v", p)
+	}
+	if p := series[2].Total; p.ExpectedDays != 2 || p.LateCount != 1 {
+		t.Fatalf("dev total = %+v", p)
+	}
+
+	series = buildTrend(at(1, 0, 0), at(14, 0, 0), GranularityMonth, "dev", teams, []*MonthResult{res}, loc)
+	if len(series) != 1 || series[0].Team != "dev" || len(series[0].Points) != 1 {
+		t.Fatalf("dev series = %+v", series)
+	}
+
+	prev := buildTrend(at(1, 0, 0), at(14, 0, 0), GranularityWeek, "", teams, nil, loc)
+	cur := buildTrend(at(1, 0, 0), at(14, 0, 0), GranularityWeek, "", teams, []*MonthResult{res}, loc)
+	pairSeries(cur, prev)
+	if cur[0].Total.Previous == nil || cur[0].Points[1].Previous == nil || cur[1].Total.Previous != nil {
+		t.Fatalf("paired = %+v", cur)
+	}
+}
+
+func TestWithAbsentEmployees(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+	res := &MonthResult{Year: 2023, Month: 5, TotalDay: 31, Users: []UserResult{
+		{UserId: "u1", Location: loc, Days: []DayResult{{Day: 2, Workday: true, Present: true}}},
+	}}
+	workdays := make([]bool, 32)
+	workdays[2], workdays[3], workdays[4] = true, true, true
+	resign := time.Date(2023, 5, 3, 0, 0, 0, 0, loc)
+	employees := map[string]model.Employee{
+		"u1": {UserId: "u1", Team: "dev"},
+		"u2": {UserId: "u2", Team: "dev", ResignDate: &resign},
+	}
+	got := withAbsentEmployees(res, employees, workdays, loc)
+	if len(res.Users) != 1 || len(got.Users) != 2 || got.Users[1].UserId != "u2" {
+		t.Fatalf("users = %+v", got.Users)
+	}
+	series := buildTrend(time.Date(2023, 5, 1, 0, 0, 0, 0, loc), time.Date(2023, 5, 31, 0, 0, 0, 0, loc),
+		GranularityMonth, "dev", map[string]string{"u1": "dev", "u2": "dev"}, []*MonthResult{got}, loc)
+	// u2 离职前的两个工作日算旷工
+	if p := series[0].Total; p.ExpectedDays != 3 || p.PresentDays != 1 || p.AbsentCount != 2 {
+		t.Fatalf("total = %+v", p)
+	}
+}
+
+func TestPreviousRange(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+	day := func(y, m, d int) time.Time { return time.Date(y, time.Month(m), d, 0, 0, 0, 0, loc) }
+	cases := []struct {
+		begin, end  time.Time
+		granularity string
+		compare     string
+		wantBegin   time.Time
+		wantEnd     time.Time
+	}{
+		{day(2023, 5, 8), day(2023, 5, 21), GranularityWeek, ComparePrevious, day(2023, 4, 24), day(2023, 5, 7)},
+		{day(2023, 3, 1), day(2023, 4, 30), GranularityMonth, ComparePrevious, day(2023, 1, 1), day(2023, 2, 28)},
+		{day(2023, 3, 1), day(2023, 4, 15), GranularityMonth, ComparePrevious, day(2023, 1, 1), day(2023, 2, 15)},
+		{day(2023, 3, 1), day(2023, 3, 30), GranularityMonth, ComparePrevious, day(2023, 2, 1), day(2023, 2, 28)},
+		{day(2023, 5, 1), day(2023, 5, 31), GranularityDay, CompareYear, day(2022, 5, 1), day(2022, 5, 31)},
+	}
+	for _, c := range cases {
+		b, e := previousRange(c.begin, c.end, c.granularity, c.compare)
+		if !b.Equal(c.wantBegin) || !getZeroTime(e).Equal(c.wantEnd) {
+			t.Errorf("previousRange(%s, %s) = %s, %s", c.begin.Format(formatDayTime), c.end.Format(formatDayTime), b, e)
+		}
+	}
+}
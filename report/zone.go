@@ -0,0 +1,74 @@
+package report
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"tool-attendance/model"
+	"tool-attendance/utils/tz"
+)
+
+// ZoneResolver 按 员工 > 工作地点 > 默认 的优先级确定用户所在时区
+type ZoneResolver struct {
+	employees map[string]model.Employee
+	sites     map[int64]model.Site
+}
+
+func NewZoneResolver() (*ZoneResolver, error) {
+	employees, err := model.FindEmployeeMap()
+	if err != nil {
+		return nil, err
+	}
+	sites, err := model.FindSiteMap()
+	if err != nil {
+		return nil, err
+	}
+	return &ZoneResolver{employees: employees, sites: sites}, nil
+}
+
+func (z *ZoneResolver) Location(userId string) *time.Location {
+	e, ok := z.employees[userId]
+	if !ok {
+		return tz.Default()
+	}
+	if e.TimeZone != "" {
+		return tz.LoadOrDefault(e.TimeZone)
+	}
+	if s, ok := z.sites[e.SiteId]; ok {
+		return tz.LoadOrDefault(s.TimeZone)
+	}
+	return tz.Default()
+}
+
+// UserLocation 查询单个用户的时区
+func UserLocation(userId string) (*time.Location, error) {
+	e, err := model.FindEmployee(userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return tz.Default(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if e.TimeZone != "" {
+		return tz.LoadOrDefault(e.TimeZone), nil
+	}
+	if e.SiteId == 0 {
+		return tz.Default(), nil
+	}
+	s, err := model.FindSite(e.SiteId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return tz.Default(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tz.LoadOrDefault(s.TimeZone), nil
+}
+
+// RecordDay 打卡所属的考勤日：按用户时区取日期，以默认时区的零点存储（与 days_date 一致）
+func RecordDay(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, tz.Default())
+}
@@ -4,6 +4,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"tool-attendance/config"
 	"tool-attendance/handler"
+	"tool-attendance/model"
+	"tool-attendance/router/middleware"
 )
 
 func InitAiRouter(cfg *config.Configuration) *gin.Engine {
@@ -12,8 +14,75 @@ func InitAiRouter(cfg *config.Configuration) *gin.Engine {
 	v1.GET("/ping", handler.Pong)
 	{
 		v1.GET("/init/calendar/:year", handler.InitCalendar)
-		v1.GET("/attendance/detail/:month", handler.AttendanceDetail)
-		v1.GET("/attendance/record/:month", handler.AttendanceRecord)
+		v1.GET("/attendance/detail/:month", middleware.MachineAuthorized(model.ApiKeyScopeReportRead), handler.AttendanceDetail)
+		v1.GET("/attendance/record/:month", middleware.MachineAuthorized(model.ApiKeyScopeReportRead), handler.AttendanceRecord)
+		v1.GET("/attendance/payroll/:month", middleware.MachineAuthorized(model.ApiKeyScopeReportRead), handler.PayrollExport)
+		v1.GET("/attendance/heatmap/:month", middleware.MachineAuthorized(model.ApiKeyScopeReportRead), handler.AttendanceHeatmap)
+		v1.GET("/attendance/anomalies", middleware.MachineAuthorized(model.ApiKeyScopeReportRead), handler.AnomalyList)
+		v1.POST("/attendance/diff", middleware.MachineAuthorized(model.ApiKeyScopeReportRead), handler.AttendanceDiff)
+		v1.GET("/attendance/summary", middleware.MachineAuthorized(model.ApiKeyScopeReportRead), handler.DailySummaryList)
+		v1.POST("/attendance/summary/rebuild/:year/:month", middleware.Authorized, handler.RebuildDailySummary)
+		v1.GET("/analytics/trend", middleware.MachineAuthorized(model.ApiKeyScopeReportRead), handler.AttendanceTrend)
+		v1.POST("/punch", middleware.MachineAuthorized(model.ApiKeyScopePunchWrite), handler.PunchIn)
+		v1.POST("/device/punch", middleware.SignVerify, handler.PunchIn)
+	}
+	{
+		apiKey := v1.Group("/api-keys", middleware.Authorized)
+		apiKey.POST("", handler.CreateApiKey)
+		apiKey.GET("", handler.ApiKeyList)
+		apiKey.DELETE("/:id", handler.RevokeApiKey)
+	}
+	{
+		reportJob := v1.Group("/report/jobs", middleware.MachineAuthorized(model.ApiKeyScopeReportRead))
+		reportJob.POST("", handler.SubmitReportJob)
+		reportJob.GET("/:id", handler.ReportJobStatus)
+		reportJob.GET("/:id/download", handler.DownloadReportJob)
+		reportJob.GET("/:id/presign", handler.PresignReportJob)
+	}
+	v1.GET("/storage/objects/*key", handler.LocalObject)
+	v1.GET("/audit/logs", middleware.Authorized, handler.AuditLogList)
+	{
+		employee := v1.Group("/employees", middleware.Authorized)
+		employee.GET("", handler.EmployeeList)
+		employee.PUT("/:user_id", handler.SaveEmployee)
+
+		site := v1.Group("/sites", middleware.Authorized)
+		site.GET("", handler.SiteList)
+		site.POST("", handler.CreateSite)
+		site.PUT("/:id", handler.UpdateSite)
+	}
+	{
+		cronJob := v1.Group("/cron", middleware.Authorized)
+		cronJob.GET("/jobs", handler.CronJobList)
+		cronJob.POST("/jobs/:name/run", handler.RunCronJob)
+		cronJob.GET("/logs", handler.CronJobLogList)
+	}
+	{
+		monthClose := v1.Group("/months", middleware.Authorized)
+		monthClose.GET("", handler.MonthCloseList)
+		monthClose.GET("/:year/:month", handler.MonthStat)
+		monthClose.POST("/:year/:month/close", handler.CloseMonth)
+		monthClose.POST("/:year/:month/reopen", handler.ReopenMonth)
+	}
+	{
+		hook := v1.Group("/webhooks", middleware.Authorized)
+		hook.POST("", handler.CreateWebhook)
+		hook.GET("", handler.WebhookList)
+		hook.PUT("/:id", handler.UpdateWebhook)
+		hook.DELETE("/:id", handler.DeleteWebhook)
+		hook.GET("/deliveries", handler.WebhookDeliveryList)
+		hook.POST("/deliveries/:id/redeliver", handler.RedeliverWebhook)
+	}
+	{
+		leave := v1.Group("/leave", middleware.Authorized)
+		leave.POST("/requests", handler.SubmitLeave)
+		leave.GET("/requests", handler.LeaveList)
+		leave.POST("/requests/:id/approve", handler.ApproveLeave)
+		leave.POST("/requests/:id/reject", handler.RejectLeave)
+		leave.POST("/requests/:id/cancel", handler.CancelLeave)
+		leave.GET("/balances/:user_id", handler.LeaveBalance)
+		leave.GET("/ledger", handler.LeaveLedgerList)
+		leave.POST("/adjust", handler.AdjustLeave)
 	}
 	return r
 }
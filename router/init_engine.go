@@ -39,7 +39,7 @@ func initDefaultRouter(cfg *config.Configuration) *gin.Engine {
 		return
 	})
 	// 最大运行上传文件大小
-	r.MaxMultipartMemory = 1024 * 1024 * 1024 //1G
+	r.MaxMultipartMemory = 32 << 20 // 32M，超出的部分由 gin 写入临时文件，上传时流式读取
 	return r
 }
 
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"tool-attendance/log"
+	"tool-attendance/model"
+	"tool-attendance/utils/apikey"
+	"tool-attendance/utils/render"
+
+	"github.com/gin-gonic/gin"
+)
+
+// last_used_at 的最小更新间隔，避免每个请求都写库
+const apiKeyTouchInterval = time.Minute
+
+// 机器客户端（考勤机、薪资任务）的 API Key 验证
+// 请求头携带 X-Api-Key 或 Authorization: ApiKey <key> 时按 API Key 验证并校验权限范围，
+// 否则回退到管理后台的 Bearer token 验证
+func MachineAuthorized(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := getApiKey(c)
+		if key == "" {
+			Authorized(c)
+			return
+		}
+		prefix, ok := apikey.Parse(key)
+		if !ok {
+			render.AbortJson(c, http.StatusUnauthorized, "The api key is malformed")
+			return
+		}
+		k, err := model.FindApiKeyByPrefix(prefix)
+		if err != nil || !apikey.Verify(key, k.KeyHash) {
+			render.AbortJson(c, http.StatusUnauthorized, "The api key is invalid")
+			return
+		}
+		now := time.Now()
+		if !k.IsActive(now) {
+			render.AbortJson(c, http.StatusUnauthorized, "The api key is expired or revoked")
+			return
+		}
+		if !k.HasScope(scope) {
+			render.AbortJson(c, http.StatusForbidden, "The api key is not allowed to access "+scope)
+			return
+		}
+		if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) > apiKeyTouchInterval {
+			go func(id int64) {
+				if err := model.UpdateApiKeyLastUsed(id, now); err != nil {
+					log.Log.Error("update api key last used err:", err)
+				}
+			}(k.ID)
+		}
+		c.Set("api_key", k)
+		c.Next()
+	}
+}
+
+func getApiKey(c *gin.Context) string {
+	if key := c.Request.Header.Get("X-Api-Key"); key != "" {
+		return key
+	}
+	header := strings.Split(c.Request.Header.Get("Authorization"), " ")
+	if len(header) == 2 && header[0] == "ApiKey" {
+		return header[1]
+	}
+	return ""
+}
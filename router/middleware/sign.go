@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"tool-attendance/config"
+	"tool-attendance/utils"
+	"tool-attendance/utils/render"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	signHeaderClientId  = "X-Client-Id"
+	signHeaderTimestamp = "X-Timestamp"
+	signHeaderNonce     = "X-Nonce"
+	signHeaderSignature = "X-Signature"
+
+	nonceMinLen = 8
+	nonceMaxLen = 64
+
+	defaultMaxBody = 1 << 20 // 未配置 max_body 时请求体的最大字节数
+)
+
+var nonces = newNonceCache()
+
+// nonceCache 记录时间窗口内已使用过的 nonce，防止请求重放
+type nonceCache struct {
+	items   map[string]int64 // key -> 过期时间戳
+	mu      *sync.Mutex
+	checked int64 // 上次清理过期 nonce 的时间戳
+}
+
+func newNonceCache() *nonceCache {
+	return &nonceCache{
+		items: make(map[string]int64),
+		mu:    &sync.Mutex{},
+	}
+}
+
+// Use 标记 nonce 已使用，nonce 在有效期内已被使用过则返回 false
+func (n *nonceCache) Use(key string, now, ttl int64) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if now-n.checked > ttl {
+		for k, expire := range n.items {
+			if expire < now {
+				delete(n.items, k)
+			}
+		}
+		n.checked = now
+	}
+	if expire, ok := n.items[key]; ok && expire >= now {
+		return false
+	}
+	n.items[key] = now + ttl
+	return true
+}
+
+// 设备请求的签名验证
+// 签名：hex(hmac_sha256(secret, method\npath\ntimestamp\nnonce\nsha256(body)))，path 包含 query
+func SignVerify(c *gin.Context) {
+	cfg := config.GetConfig().Sign
+	clientId := c.Request.Header.Get(signHeaderClientId)
+	timestampS := c.Request.Header.Get(signHeaderTimestamp)
+	nonce := c.Request.Header.Get(signHeaderNonce)
+	sign := c.Request.Header.Get(signHeaderSignature)
+	if clientId == "" || timestampS == "" || nonce == "" || sign == "" {
+		render.AbortJson(c, http.StatusUnauthorized, "Signature headers not provided")
+		return
+	}
+	timestamp, err := strconv.ParseInt(timestampS, 10, 64)
+	if err != nil {
+		render.AbortJson(c, http.StatusUnauthorized, "The timestamp is invalid")
+		return
+	}
+	if !utils.ValidateSignTimestamp(timestamp, cfg.Window) {
+		render.AbortJson(c, http.StatusUnauthorized, "The timestamp is out of the allowed window")
+		return
+	}
+	if len(nonce) < nonceMinLen || len(nonce) > nonceMaxLen {
+		render.AbortJson(c, http.StatusUnauthorized, "The nonce is invalid")
+		return
+	}
+	secret := ""
+	for _, v := range cfg.Clients {
+		if v.ClientId == clientId {
+			secret = v.Secret
+			break
+		}
+	}
+	if secret == "" {
+		render.AbortJson(c, http.StatusUnauthorized, "Unknown client")
+		return
+	}
+
+	var body []byte
+	if c.Request.Body != nil {
+		maxBody := cfg.MaxBody
+		if maxBody <= 0 {
+			maxBody = defaultMaxBody
+		}
+		body, err = ioutil.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBody))
+		if err != nil && int64(len(body)) >= maxBody {
+			render.AbortJson(c, http.StatusRequestEntityTooLarge, "The request body is too large")
+			return
+		}
+		if err != nil {
+			render.AbortJson(c, http.StatusBadRequest, "Failed to read the request body")
+			return
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+	}
+	oriStr := utils.HmacSignString(c.Request.Method, c.Request.URL.RequestURI(), timestamp, nonce, body)
+	if !utils.ValidateHmacSign(secret, oriStr, sign, timestamp, cfg.Window) {
+		render.AbortJson(c, http.StatusUnauthorized, "The signature is invalid")
+		return
+	}
+	// 签名通过后才记录 nonce，避免伪造请求占满缓存
+	if !nonces.Use(clientId+":"+nonce, time.Now().Unix(), cfg.Window*2) {
+		render.AbortJson(c, http.StatusUnauthorized, "The nonce has been used")
+		return
+	}
+	c.Set("sign_client", clientId)
+	c.Next()
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tool-attendance/config"
+	"tool-attendance/utils"
+)
+
+func newSignRequest(secret, nonce string, timestamp int64, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/device/punch?source=test", bytes.NewBufferString(body))
+	oriStr := utils.HmacSignString(req.Method, req.URL.RequestURI(), timestamp, nonce, []byte(body))
+	req.Header.Set(signHeaderClientId, "device-1")
+	req.Header.Set(signHeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(signHeaderNonce, nonce)
+	req.Header.Set(signHeaderSignature, utils.HmacSha256(secret, oriStr))
+	return req
+}
+
+func TestSignVerify(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config.Cfg.Sign = config.SignConfig{
+		Window:  300,
+		Clients: []config.SignClient{{ClientId: "device-1", Secret: "device-secret"}},
+	}
+	r := gin.New()
+	r.POST("/device/punch", SignVerify, func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	now := time.Now().Unix()
+	body := `{"punches":[{"user_id":"1","punch_time":1683162000}]}`
+	cases := []struct {
+		name string
+		req  *http.Request
+		code int
+	}{
+		{"valid", newSignRequest("device-secret", "nonce-0001", now, body), http.StatusOK},
+		{"replay", newSignRequest("device-secret", "nonce-0001", now, body), http.StatusUnauthorized},
+		{"wrong secret", newSignRequest("other-secret", "nonce-0002", now, body), http.StatusUnauthorized},
+		{"expired", newSignRequest("device-secret", "nonce-0003", now-3600, body), http.StatusUnauthorized},
+		{"short nonce", newSignRequest("device-secret", "n", now, body), http.StatusUnauthorized},
+	}
+	for _, v := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, v.req)
+		if w.Code != v.code {
+			t.Errorf("%s: got %d, want %d, body %s", v.name, w.Code, v.code, w.Body.String())
+		}
+	}
+
+	// 篡改 body
+	req := newSignRequest("device-secret", "nonce-0004", now, body)
+	req.Body = http.NoBody
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("tampered body: got %d", w.Code)
+	}
+
+	// 请求体超过 max_body
+	config.Cfg.Sign.MaxBody = 16
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, newSignRequest("device-secret", "nonce-0005", now, body))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("large body: got %d", w.Code)
+	}
+}
@@ -0,0 +1,136 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"tool-attendance/leave"
+	"tool-attendance/log"
+	"tool-attendance/model"
+	"tool-attendance/report"
+	"tool-attendance/utils/i18n"
+	"tool-attendance/utils/notifier"
+	"tool-attendance/utils/tz"
+)
+
+// 内置任务
+const (
+	JobMonthlyReport        = "monthly_report"         // 生成上月考勤报表，建议每月 1 日执行
+	JobRefreshCalendar      = "refresh_calendar"       // 刷新下一年的日历，建议每年 12 月执行
+	JobMissingPunchReminder = "missing_punch_reminder" // 提醒昨天漏打卡的员工提交补卡，建议每小时执行
+	JobMonthlyReportMail    = "monthly_report_mail"    // 把上月报表发送给 report.mail_to
+	JobAnomalySummaryMail   = "anomaly_summary_mail"   // 给上月有考勤异常的员工发送汇总邮件
+	JobAttendanceDigest     = "attendance_digest"      // 把前一天的迟到和漏打卡汇总发到聊天群
+	JobLeaveAccrual         = "leave_accrual"          // 年假发放、结转和过期作废，建议每天执行
+)
+
+func init() {
+	Register(JobMonthlyReport, monthlyReport)
+	Register(JobRefreshCalendar, refreshCalendar)
+	Register(JobMissingPunchReminder, missingPunchReminder)
+	Register(JobMonthlyReportMail, monthlyReportMail)
+	Register(JobAnomalySummaryMail, anomalySummaryMail)
+	Register(JobAttendanceDigest, attendanceDigest)
+	Register(JobLeaveAccrual, leaveAccrual)
+}
+
+func monthlyReport(ctx context.Context) error {
+	year, month := report.LastMonth(time.Now().In(tz.Default()))
+	for _, kind := range []string{report.KindDetail, report.KindRecord} {
+		job, err := report.SubmitJob(kind, year, month, i18n.ZhCN, "", "cron")
+		if err != nil {
+			return fmt.Errorf("submit %s report: %w", kind, err)
+		}
+		log.Log.Infof("monthly %s report of %d-%02d submitted, job id: %s", kind, year, month, job.ID)
+	}
+	return nil
+}
+
+func refreshCalendar(ctx context.Context) error {
+	return report.RefreshCalendar(time.Now().In(tz.Default()).Year() + 1)
+}
+
+func leaveAccrual(ctx context.Context) error {
+	return leave.Run(time.Now())
+}
+
+// attendanceDigest 前一天是工作日时，把迟到、漏打卡和缺勤的人员汇总发到订阅了 digest 的渠道。
+// 缺勤按员工表统计，当月一次都没打卡的在职员工也会列出
+func attendanceDigest(ctx context.Context) error {
+	if !notifier.Enabled(notifier.EventDigest) {
+		return errors.New("no notify channel subscribes digest")
+	}
+	day := time.Now().In(tz.Default()).AddDate(0, 0, -1)
+	workdays, err := report.CountWorkdays(day, day)
+	if err != nil {
+		return err
+	}
+	if workdays == 0 {
+		return nil
+	}
+	res, err := report.Compute(day.Year(), int(day.Month()))
+	if err != nil {
+		return err
+	}
+	employees, err := model.FindEmployeeMap()
+	if err != nil {
+		return err
+	}
+
+	var late, lackCard, absent []string
+	seen := make(map[string]bool, len(res.Users))
+	for _, u := range res.Users {
+		seen[u.UserId] = true
+		if e, ok := employees[u.UserId]; ok && !inService(e, day) {
+			continue
+		}
+		d := u.Days[day.Day()-1]
+		if d.Late {
+			late = append(late, fmt.Sprintf("%s %s", u.Name(), d.OnworkTime.In(u.Location).Format("15:04")))
+		}
+		if d.LackCard {
+			lackCard = append(lackCard, u.Name())
+		}
+		if d.Absent {
+			absent = append(absent, u.Name())
+		}
+	}
+	for _, e := range employees {
+		if seen[e.UserId] || !inService(e, day) {
+			continue
+		}
+		name := e.Name
+		if name == "" {
+			name = e.UserId
+		}
+		absent = append(absent, name)
+	}
+	sort.Strings(absent)
+
+	text := fmt.Sprintf("**迟到（%d）**：%s\n\n**漏打卡（%d）**：%s\n\n**缺勤（%d）**：%s",
+		len(late), joinOrNone(late), len(lackCard), joinOrNone(lackCard), len(absent), joinOrNone(absent))
+	return notifier.Broadcast(ctx, notifier.EventDigest, notifier.Message{
+		Title: fmt.Sprintf("%s 考勤日报", day.Format("2006-01-02")),
+		Text:  text,
+	})
+}
+
+// inService 员工当天是否在职，未填写入职或离职日期时不限制
+func inService(e model.Employee, day time.Time) bool {
+	d := day.Format("2006-01-02")
+	if e.HireDate != nil && d < e.HireDate.In(day.Location()).Format("2006-01-02") {
+		return false
+	}
+	return e.ResignDate == nil || d <= e.ResignDate.In(day.Location()).Format("2006-01-02")
+}
+
+func joinOrNone(list []string) string {
+	if len(list) == 0 {
+		return "无"
+	}
+	return strings.Join(list, "、")
+}
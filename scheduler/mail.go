@@ -0,0 +1,151 @@
+package scheduler
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"tool-attendance/config"
+	"tool-attendance/log"
+	"tool-attendance/model"
+	"tool-attendance/report"
+	"tool-attendance/utils/i18n"
+	"tool-attendance/utils/mailer"
+	"tool-attendance/utils/tz"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var mailTemplates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// monthlyReportMail 把上月的考勤明细和考勤记录发送给管理者
+func monthlyReportMail(ctx context.Context) error {
+	to := config.GetConfig().Report.MailTo
+	if len(to) == 0 {
+		return errors.New("report.mail_to is empty")
+	}
+	year, month := report.LastMonth(time.Now().In(tz.Default()))
+	res, err := report.Compute(year, month)
+	if err != nil {
+		return err
+	}
+	html, err := mailer.Render(mailTemplates, "monthly_report", res)
+	if err != nil {
+		return err
+	}
+
+	msg := &mailer.Message{
+		To:      to,
+		Subject: fmt.Sprintf("%d年%d月考勤报表", year, month),
+		Html:    html,
+	}
+	for _, kind := range []struct {
+		name  string
+		build func(year, month int, lang string) (*excelize.File, error)
+	}{
+		{report.KindDetail, report.BuildDetail},
+		{report.KindRecord, report.BuildRecord},
+	} {
+		f, err := kind.build(year, month, i18n.ZhCN)
+		if err != nil {
+			return err
+		}
+		buf, err := f.WriteToBuffer()
+		f.Close()
+		if err != nil {
+			return err
+		}
+		msg.Attachments = append(msg.Attachments, mailer.Attachment{
+			Name:        fmt.Sprintf("attendance_%s_%d%02d.xlsx", kind.name, year, month),
+			ContentType: xlsxContentType,
+			Data:        buf.Bytes(),
+		})
+	}
+	return mailer.Send(msg)
+}
+
+type anomalyDay struct {
+	report.DayResult
+	Date    string
+	OnWork  string
+	OffWork string
+}
+
+// anomalySummaryMail 给上月有考勤异常的员工发送异常汇总，未登记邮箱的员工跳过
+func anomalySummaryMail(ctx context.Context) error {
+	year, month := report.LastMonth(time.Now().In(tz.Default()))
+	res, err := report.Compute(year, month)
+	if err != nil {
+		return err
+	}
+	employees, err := model.FindEmployeeMap()
+	if err != nil {
+		return err
+	}
+
+	sent, failed := 0, 0
+	for _, u := range res.Users {
+		e, ok := employees[u.UserId]
+		if !ok || e.Email == "" || !hasAnomaly(u.Stat) {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		days := make([]anomalyDay, 0)
+		for _, d := range u.Days {
+			if !d.Absent && !d.Late && !d.Early && !d.Short && !d.LackCard {
+				continue
+			}
+			days = append(days, anomalyDay{
+				DayResult: d,
+				Date:      fmt.Sprintf("%d-%02d-%02d", year, month, d.Day),
+				OnWork:    formatClock(d.OnworkTime, u.Location),
+				OffWork:   formatClock(d.OffworkTime, u.Location),
+			})
+		}
+		html, err := mailer.Render(mailTemplates, "anomaly_summary", map[string]interface{}{
+			"Name":  u.Name(),
+			"Year":  year,
+			"Month": month,
+			"Stat":  u.Stat,
+			"Days":  days,
+		})
+		if err != nil {
+			return err
+		}
+		err = mailer.Send(&mailer.Message{
+			To:      []string{e.Email},
+			Subject: fmt.Sprintf("%d年%d月考勤异常汇总", year, month),
+			Html:    html,
+		})
+		if err != nil {
+			failed++
+			log.Log.Errorf("send anomaly summary to %s err:%v", e.Email, err)
+			continue
+		}
+		sent++
+	}
+	if failed > 0 {
+		return fmt.Errorf("anomaly summary: %d sent, %d failed", sent, failed)
+	}
+	return nil
+}
+
+func hasAnomaly(s report.UserStat) bool {
+	return s.AbsentDay+s.LateDay+s.EarlyDay+s.ShortDay+s.LackCardDay > 0
+}
+
+func formatClock(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.In(loc).Format("15:04")
+}
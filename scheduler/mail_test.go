@@ -0,0 +1,47 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"tool-attendance/report"
+	"tool-attendance/utils/mailer"
+)
+
+func TestMailTemplates(t *testing.T) {
+	res := &report.MonthResult{
+		Year:        2023,
+		Month:       5,
+		NeedWorkDay: 21,
+		Users: []report.UserResult{
+			{Firstname: "张三", Stat: report.UserStat{WorkDay: 20, LateDay: 2}},
+		},
+	}
+	html, err := mailer.Render(mailTemplates, "monthly_report", res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html, "<td>张三</td><td>20</td>") {
+		t.Fatalf("monthly report mail: %s", html)
+	}
+
+	html, err = mailer.Render(mailTemplates, "anomaly_summary", map[string]interface{}{
+		"Name":  "张三",
+		"Year":  2023,
+		"Month": 5,
+		"Stat":  res.Users[0].Stat,
+		"Days": []anomalyDay{{
+			DayResult: report.DayResult{Day: 4, Late: true},
+			Date:      "2023-05-04",
+			OnWork:    formatClock(time.Date(2023, 5, 4, 9, 45, 0, 0, time.UTC), time.UTC),
+			OffWork:   formatClock(time.Time{}, time.UTC),
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html, "<td>2023-05-04</td><td>09:45</td><td>-</td>") || !strings.Contains(html, "迟到") {
+		t.Fatalf("anomaly summary mail: %s", html)
+	}
+}
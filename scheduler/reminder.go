@@ -0,0 +1,152 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"tool-attendance/config"
+	"tool-attendance/log"
+	"tool-attendance/model"
+	"tool-attendance/report"
+	"tool-attendance/utils/mailer"
+	"tool-attendance/utils/notifier"
+	"tool-attendance/utils/tz"
+)
+
+const reminderKindMissingPunch = "missing_punch"
+
+// missingPunchReminder 提醒前一天（工作日）漏打卡的员工提交补卡：有邮箱的发邮件，订阅了 reminder 的聊天群发群消息。
+// 员工所在时区处于免打扰时段时跳过，建议每小时执行一次，已发送的提醒按员工、日期和渠道去重
+func missingPunchReminder(ctx context.Context) error {
+	day := time.Now().In(tz.Default()).AddDate(0, 0, -1)
+	res, err := report.Compute(day.Year(), int(day.Month()))
+	if err != nil {
+		return err
+	}
+	recordDay := report.RecordDay(day, tz.Default())
+	sent, err := model.FindReminderLogSet(reminderKindMissingPunch, recordDay)
+	if err != nil {
+		return err
+	}
+	employees, err := model.FindEmployeeMap()
+	if err != nil {
+		return err
+	}
+
+	cfg := config.GetConfig()
+	failed := 0
+	for _, u := range res.Users {
+		d := u.Days[day.Day()-1]
+		if !d.LackCard {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if inQuietHours(time.Now().In(u.Location), cfg.Remind.QuietStart, cfg.Remind.QuietEnd) {
+			continue
+		}
+		r := missingPunch{
+			Name:    u.Name(),
+			Date:    day.Format("2006-01-02"),
+			Missing: missingCard(d),
+			OnWork:  formatClock(d.OnworkTime, u.Location),
+			OffWork: formatClock(d.OffworkTime, u.Location),
+			Link:    correctionLink(cfg, u.UserId, day.Format("2006-01-02")),
+		}
+
+		channels := map[string]func() error{}
+		if e, ok := employees[u.UserId]; ok && e.Email != "" && mailer.Enabled() {
+			channels[model.ReminderChannelMail] = func() error { return r.mail(e.Email) }
+		}
+		if notifier.Enabled(notifier.EventReminder) {
+			channels[model.ReminderChannelWebhook] = func() error { return r.webhook(ctx) }
+		}
+		for channel, send := range channels {
+			if sent[u.UserId+":"+channel] {
+				continue
+			}
+			if err = send(); err != nil {
+				failed++
+				log.Log.Errorf("remind %s missing punch by %s err:%v", u.UserId, channel, err)
+				continue
+			}
+			err = model.CreateReminderLog(&model.ReminderLog{
+				Kind:      reminderKindMissingPunch,
+				UserId:    u.UserId,
+				Day:       recordDay,
+				Channel:   channel,
+				CreatedAt: time.Now(),
+			})
+			if err != nil {
+				log.Log.Error("create reminder log err:", err)
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("missing punch reminder: %d failed", failed)
+	}
+	return nil
+}
+
+type missingPunch struct {
+	Name    string
+	Date    string
+	Missing string
+	OnWork  string
+	OffWork string
+	Link    string
+}
+
+func (r *missingPunch) mail(to string) error {
+	html, err := mailer.Render(mailTemplates, "missing_punch", r)
+	if err != nil {
+		return err
+	}
+	return mailer.Send(&mailer.Message{
+		To:      []string{to},
+		Subject: fmt.Sprintf("%s 漏打卡提醒", r.Date),
+		Html:    html,
+	})
+}
+
+func (r *missingPunch) webhook(ctx context.Context) error {
+	return notifier.Broadcast(ctx, notifier.EventReminder, notifier.Message{
+		Title: "漏打卡提醒",
+		Text:  fmt.Sprintf("**%s** %s 漏打了%s，请[提交补卡申请](%s)", r.Name, r.Date, r.Missing, r.Link),
+	})
+}
+
+func missingCard(d report.DayResult) string {
+	if d.OnworkTime.IsZero() {
+		return "上班卡"
+	}
+	return "下班卡"
+}
+
+// correctionLink 补卡申请地址
+func correctionLink(cfg config.Configuration, userId, date string) string {
+	link := cfg.Remind.CorrectionUrl
+	if link == "" {
+		link = strings.TrimRight(cfg.App.BaseUrl, "/") + "/correction?user_id={user_id}&date={date}"
+	}
+	return strings.NewReplacer("{user_id}", url.QueryEscape(userId), "{date}", date).Replace(link)
+}
+
+// inQuietHours now 是否处于免打扰时段 [start, end)，start 大于 end 时表示跨零点；配置有误时不限制
+func inQuietHours(now time.Time, start, end string) bool {
+	s, err1 := time.Parse("15:04", start)
+	e, err2 := time.Parse("15:04", end)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	minute := now.Hour()*60 + now.Minute()
+	sm, em := s.Hour()*60+s.Minute(), e.Hour()*60+e.Minute()
+	if sm <= em {
+		return sm <= minute && minute < em
+	}
+	return minute >= sm || minute < em
+}
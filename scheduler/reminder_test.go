@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"tool-attendance/config"
+	"tool-attendance/utils/mailer"
+)
+
+func TestInQuietHours(t *testing.T) {
+	at := func(clock string) time.Time {
+		v, _ := time.Parse("15:04", clock)
+		return v
+	}
+	cases := []struct {
+		now, start, end string
+		want            bool
+	}{
+		{"23:00", "22:00", "08:00", true},
+		{"03:00", "22:00", "08:00", true},
+		{"08:00", "22:00", "08:00", false},
+		{"12:00", "22:00", "08:00", false},
+		{"12:30", "12:00", "13:00", true},
+		{"13:00", "12:00", "13:00", false},
+		{"12:00", "", "", false},
+		{"12:00", "10:00", "10:00", false},
+	}
+	for _, c := range cases {
+		if got := inQuietHours(at(c.now), c.start, c.end); got != c.want {
+			t.Errorf("inQuietHours(%s, %s, %s) = %v, want %v", c.now, c.start, c.end, got, c.want)
+		}
+	}
+}
+
+func TestCorrectionLink(t *testing.T) {
+	cfg := config.Configuration{}
+	cfg.App.BaseUrl = "https://hr.example.com/"
+	if got := correctionLink(cfg, "a b", "2023-05-04"); got != "https://hr.example.com/correction?user_id=a+b&date=2023-05-04" {
+		t.Fatalf("default link: %s", got)
+	}
+	cfg.Remind.CorrectionUrl = "https://oa.example.com/fix/{user_id}/{date}"
+	if got := correctionLink(cfg, "u1", "2023-05-04"); got != "https://oa.example.com/fix/u1/2023-05-04" {
+		t.Fatalf("custom link: %s", got)
+	}
+}
+
+func TestMissingPunchTemplate(t *testing.T) {
+	html, err := mailer.Render(mailTemplates, "missing_punch", &missingPunch{
+		Name: "张三", Date: "2023-05-04", Missing: "下班卡", OnWork: "09:10", OffWork: "-", Link: "https://hr.example.com/correction",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html, "漏打了下班卡") || !strings.Contains(html, `href="https://hr.example.com/correction"`) {
+		t.Fatalf("missing punch mail: %s", html)
+	}
+}
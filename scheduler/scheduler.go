@@ -0,0 +1,189 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"tool-attendance/config"
+	"tool-attendance/log"
+	"tool-attendance/model"
+)
+
+// JobFunc 定时任务的执行函数，服务停止时 ctx 会被取消
+type JobFunc func(ctx context.Context) error
+
+// Job 定时任务的状态
+type Job struct {
+	Name    string     `json:"name"`
+	Spec    string     `json:"spec"`    // 未配置时为空，只能手动触发
+	Enabled bool       `json:"enabled"` // 是否按 spec 自动执行
+	Running bool       `json:"running"`
+	Next    *time.Time `json:"next"`
+	Prev    *time.Time `json:"prev"`
+}
+
+var (
+	ErrUnknownJob = errors.New("unknown cron job")
+	ErrJobRunning = errors.New("cron job is running")
+)
+
+var (
+	mu       sync.Mutex
+	registry = make(map[string]JobFunc)
+	specs    = make(map[string]string)
+	entries  = make(map[string]cron.EntryID)
+	running  = make(map[string]bool)
+
+	scheduler *cron.Cron
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+)
+
+// Register 注册定时任务，同名任务会被覆盖
+func Register(name string, fn JobFunc) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[name] = fn
+}
+
+// Init 按配置把已注册的任务加入 c，c 的启动和停止由调用方负责
+func Init(c *cron.Cron, cfg config.CronConfig) error {
+	mu.Lock()
+	defer mu.Unlock()
+	scheduler = c
+	ctx, cancel = context.WithCancel(context.Background())
+	for _, v := range cfg.Jobs {
+		if _, ok := registry[v.Name]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownJob, v.Name)
+		}
+		specs[v.Name] = v.Spec
+		if v.Disable {
+			continue
+		}
+		name := v.Name
+		id, err := c.AddFunc(v.Spec, func() {
+			if _, err := start(name, model.CronTriggerSchedule, "cron", false); err != nil {
+				log.Log.Warnf("cron job %s skipped: %v", name, err)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("cron job %s: %w", name, err)
+		}
+		entries[name] = id
+	}
+	return nil
+}
+
+// Stop 取消正在执行的任务并等待其退出，需在 cron 停止后调用
+func Stop() {
+	if cancel == nil {
+		return
+	}
+	cancel()
+	wg.Wait()
+}
+
+// Jobs 所有已注册的任务
+func Jobs() []Job {
+	mu.Lock()
+	defer mu.Unlock()
+	list := make([]Job, 0, len(registry))
+	for name := range registry {
+		job := Job{Name: name, Spec: specs[name], Running: running[name]}
+		if id, ok := entries[name]; ok && scheduler != nil {
+			entry := scheduler.Entry(id)
+			job.Enabled = true
+			if !entry.Next.IsZero() {
+				job.Next = &entry.Next
+			}
+			if !entry.Prev.IsZero() {
+				job.Prev = &entry.Prev
+			}
+		}
+		list = append(list, job)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Run 手动触发任务，任务在后台执行，返回本次执行的记录
+func Run(name, triggeredBy string) (*model.CronJobLog, error) {
+	return start(name, model.CronTriggerManual, triggeredBy, true)
+}
+
+// start 记录执行历史并执行任务，同一任务不会并发执行
+func start(name, trigger, triggeredBy string, async bool) (*model.CronJobLog, error) {
+	mu.Lock()
+	fn, ok := registry[name]
+	if !ok {
+		mu.Unlock()
+		return nil, ErrUnknownJob
+	}
+	if running[name] {
+		mu.Unlock()
+		return nil, ErrJobRunning
+	}
+	running[name] = true
+	mu.Unlock()
+
+	l := &model.CronJobLog{
+		Name:        name,
+		Trigger:     trigger,
+		TriggeredBy: triggeredBy,
+		Status:      model.CronJobRunning,
+		StartedAt:   time.Now(),
+	}
+	if err := model.CreateCronJobLog(l); err != nil {
+		finish(name)
+		return nil, err
+	}
+
+	wg.Add(1)
+	if async {
+		go execute(fn, l)
+	} else {
+		execute(fn, l)
+	}
+	return l, nil
+}
+
+func execute(fn JobFunc, l *model.CronJobLog) {
+	defer wg.Done()
+	defer finish(l.Name)
+
+	err := call(fn)
+	finishedAt := time.Now()
+	values := map[string]interface{}{
+		"status":      model.CronJobSuccess,
+		"finished_at": finishedAt,
+	}
+	if err != nil {
+		log.Log.WithAlarm().Errorf("cron job %s failed: %v", l.Name, err)
+		values["status"], values["error"] = model.CronJobFailed, err.Error()
+	}
+	if err = model.UpdateCronJobLog(l.ID, values); err != nil {
+		log.Log.Error("update cron job log err:", err)
+	}
+}
+
+// call 执行任务，任务 panic 时按失败处理
+func call(fn JobFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+func finish(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(running, name)
+}
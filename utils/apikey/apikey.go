@@ -0,0 +1,57 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+// API Key 格式：tk_<prefix>_<secret>
+// prefix 明文存储，用于快速定位记录；完整的 key 只保存 sha256 摘要，明文仅在创建时返回一次
+
+const (
+	keyTag       = "tk"
+	prefixBytes  = 4
+	secretBytes  = 20
+	keySeparator = "_"
+)
+
+// Generate 生成一个新的 API Key，返回明文、前缀和摘要
+func Generate() (plain, prefix, hash string, err error) {
+	p := make([]byte, prefixBytes)
+	if _, err = rand.Read(p); err != nil {
+		return "", "", "", err
+	}
+	s := make([]byte, secretBytes)
+	if _, err = rand.Read(s); err != nil {
+		return "", "", "", err
+	}
+	prefix = hex.EncodeToString(p)
+	plain = strings.Join([]string{keyTag, prefix, hex.EncodeToString(s)}, keySeparator)
+	return plain, prefix, Hash(plain), nil
+}
+
+// Parse 校验 key 的格式并取出前缀
+func Parse(plain string) (prefix string, ok bool) {
+	parts := strings.Split(plain, keySeparator)
+	if len(parts) != 3 || parts[0] != keyTag {
+		return "", false
+	}
+	if len(parts[1]) != prefixBytes*2 || len(parts[2]) != secretBytes*2 {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// Hash 计算 key 的摘要
+func Hash(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify 使用常量时间比较明文 key 与存储的摘要
+func Verify(plain, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(Hash(plain)), []byte(hash)) == 1
+}
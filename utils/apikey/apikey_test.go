@@ -0,0 +1,29 @@
+package apikey
+
+import "testing"
+
+func TestGenerate(t *testing.T) {
+	plain, prefix, hash, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(plain)
+	p, ok := Parse(plain)
+	if !ok || p != prefix {
+		t.Fatalf("parse %s got prefix %s, want %s", plain, p, prefix)
+	}
+	if !Verify(plain, hash) {
+		t.Error("verify failed")
+	}
+	if Verify(plain+"0", hash) {
+		t.Error("verify should fail for a modified key")
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, s := range []string{"", "tk_abc", "ak_01234567_0123456789012345678901234567890123456789", "tk_0123_0123456789"} {
+		if _, ok := Parse(s); ok {
+			t.Errorf("parse %q should fail", s)
+		}
+	}
+}
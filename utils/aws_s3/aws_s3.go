@@ -2,18 +2,20 @@ package aws_s3
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"github.com/gabriel-vasile/mimetype"
 	"io/ioutil"
 	"mime/multipart"
 	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"path"
-	"path/filepath"
 	"regexp"
 	"time"
 
@@ -21,9 +23,9 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/sts"
 
+	"tool-attendance/config"
 	"tool-attendance/log"
 )
 
@@ -47,6 +49,7 @@ func Init(accessKey, secretKey, bucket string) error {
 	}
 	s3Sess.Sess = s
 	s3Sess.Bucket = bucket
+	store = &s3Storage{sess: s3Sess}
 	return nil
 }
 
@@ -63,6 +66,35 @@ func InitTempUser(accessKey, secretKey, token, bucket string) error {
 	}
 	s3Sess.Sess = s
 	s3Sess.Bucket = bucket
+	store = &s3Storage{sess: s3Sess}
+	return nil
+}
+
+// InitWithConfig 按配置初始化存储：driver 为 local 时使用本地目录，否则使用 S3，
+// S3 支持自定义 endpoint（MinIO 等 S3 兼容存储）和 region
+func InitWithConfig(cfg *config.S3Config) error {
+	if cfg.Driver == DriverLocal {
+		app := config.GetConfig().App
+		return InitLocal(cfg.LocalDir, app.BaseUrl, app.Secret)
+	}
+	awsCfg := &aws.Config{
+		Credentials:      credentials.NewStaticCredentials(cfg.AccessKey, cfg.SecretKey, ""),
+		Region:           aws.String(cfg.Region),
+		S3ForcePathStyle: aws.Bool(cfg.PathStyle),
+	}
+	if cfg.Region == "" {
+		awsCfg.Region = aws.String(endpoints.UsEast2RegionID)
+	}
+	if cfg.Endpoint != "" {
+		awsCfg.Endpoint = aws.String(cfg.Endpoint)
+	}
+	s, err := session.NewSession(awsCfg)
+	if err != nil {
+		log.Log.Error("aws_s3 Init:", err)
+		return err
+	}
+	s3Sess = &S3Session{Sess: s, Bucket: cfg.Bucket}
+	store = &s3Storage{sess: s3Sess}
 	return nil
 }
 
@@ -127,29 +159,20 @@ func UploadImage(imagePath string, fileName string, proxy *url.URL) (string, err
 
 	defer resp.Body.Close()
 
-	data, err := ioutil.ReadAll(resp.Body)
-
-	s := s3Sess
-	var objBody *bytes.Reader
-	objBody = bytes.NewReader(data)
-
-	contentLength := resp.ContentLength
-	if contentLength == -1 {
-		contentLength = objBody.Size()
-	}
-
-	_, err = s3.New(s.Sess).PutObject(&s3.PutObjectInput{
-		Bucket:      aws.String(s.Bucket),
-		Key:         aws.String(fileName),
-		Body:        objBody,
-		ContentType: aws.String(contentType),
-		//ContentLength: aws.Int64(contentLength),
-		//ContentDisposition: aws.String("attachment"),
-	})
+	limit := SizeLimits[ResourceAnimation]
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, limit+1))
 	if err != nil {
 		return "", err
 	}
-	return contentType, nil
+	if int64(len(data)) > limit {
+		return "", ErrTooLarge
+	}
+	// 响应头的类型不可信，按内容嗅探的类型再校验一次
+	if mime := mimetype.Detect(data); !contentTypeReg.MatchString(mime.String()) {
+		return "", quarantine(context.Background(), fileName, bytes.NewReader(data), mime.String(),
+			errors.New("wrong content type: "+mime.String()))
+	}
+	return putChecked(context.Background(), fileName, path.Ext(fileName), data)
 }
 
 var (
@@ -176,29 +199,25 @@ var (
 )
 
 func ApiUploadImage(file multipart.File, fileHeader *multipart.FileHeader, dir string) (string, error) {
-	originFilename := filepath.Base(fileHeader.Filename)
-	ext := path.Ext(originFilename)
-	if _, ok := allowFileExt[ext]; !ok {
-		return "", NotAllowExt
-	}
+	return ApiUploadImageWithContext(context.Background(), file, fileHeader, dir)
+}
 
-	//size := fileHeader.Size
-	buffer := make([]byte, fileHeader.Size)
-	file.Read(buffer)
-	return uploadFileToAws(buffer, dir, ext)
+func ApiUploadImageWithContext(ctx context.Context, file multipart.File, fileHeader *multipart.FileHeader, dir string) (string, error) {
+	return uploadMultipartFile(ctx, file, fileHeader, ResourceImage, allowFileExt, imageKey(dir))
 }
 
 func ApiUploadAnimation(file multipart.File, fileHeader *multipart.FileHeader, dir string) (string, error) {
-	originFilename := filepath.Base(fileHeader.Filename)
-	ext := path.Ext(originFilename)
-	if _, ok := allowFileExt2[ext]; !ok {
-		return "", NotAllowExt
-	}
+	return ApiUploadAnimationWithContext(context.Background(), file, fileHeader, dir)
+}
 
-	//size := fileHeader.Size
-	buffer := make([]byte, fileHeader.Size)
-	file.Read(buffer)
-	return uploadFileToAws(buffer, dir, ext)
+func ApiUploadAnimationWithContext(ctx context.Context, file multipart.File, fileHeader *multipart.FileHeader, dir string) (string, error) {
+	return uploadMultipartFile(ctx, file, fileHeader, ResourceAnimation, allowFileExt2, imageKey(dir))
+}
+
+func imageKey(dir string) func(md5, ext string) string {
+	return func(md5, ext string) string {
+		return fmt.Sprintf("images/%s/%s%s", dir, md5, ext)
+	}
 }
 
 // nft 头像有些本来就没有后缀名
@@ -212,69 +231,36 @@ func uploadFileToAws(data []byte, dir, ext string) (string, error) {
 	sh.Write(data)
 	imageNameHash := hex.EncodeToString(sh.Sum([]byte("")))
 
-	s := s3Sess
 	fileName := fmt.Sprintf("images/%s/%s%s", dir, imageNameHash, ext)
-	_, err := s3.New(s.Sess).PutObject(&s3.PutObjectInput{
-		Bucket:        aws.String(s.Bucket),
-		Key:           aws.String(fileName),
-		Body:          bytes.NewReader(data),
-		ContentType:   aws.String(mimetype.Detect(data).String()),
-		ContentLength: aws.Int64(int64(len(data))),
-	})
-	if err != nil {
+	if _, err := putChecked(context.Background(), fileName, ext, data); err != nil {
 		return "", err
 	}
 	return fileName, nil
 }
 
 func UploadEditorSpaceConfigFile(file []byte, spaceId int64) (string, error) {
-	s := s3Sess
 	fileName := fmt.Sprintf("space_editor/%d/config.json", spaceId)
-	_, err := s3.New(s.Sess).PutObject(&s3.PutObjectInput{
-		Bucket:        aws.String(s.Bucket),
-		Key:           aws.String(fileName),
-		Body:          bytes.NewReader(file),
-		ContentType:   aws.String("application/json"),
-		ContentLength: aws.Int64(int64(len(file))),
-	})
-	if err != nil {
+	if _, err := putChecked(context.Background(), fileName, ".json", file); err != nil {
 		return "", err
 	}
 	return fileName, nil
 }
 
 func UploadEditorSpaceFile(file multipart.File, fileHeader *multipart.FileHeader, spaceId int64, fileType string) (string, error) {
-	originFilename := filepath.Base(fileHeader.Filename)
-	ext := path.Ext(originFilename)
-	if _, ok := allowFileExt[ext]; !ok {
-		return "", NotAllowExt
-	}
-	size := fileHeader.Size
-	buffer := make([]byte, size)
-	file.Read(buffer)
-	sh := md5.New()
-	sh.Write(buffer)
-	//fileHash := hex.EncodeToString(sh.Sum([]byte("")))
-	s := s3Sess
-	fileName := fmt.Sprintf("space_editor/%d/%s%s", spaceId, fileType, ext)
-	_, err := s3.New(s.Sess).PutObject(&s3.PutObjectInput{
-		Bucket:        aws.String(s.Bucket),
-		Key:           aws.String(fileName),
-		Body:          bytes.NewReader(buffer),
-		ContentType:   aws.String(mimetype.Detect(buffer).String()),
-		ContentLength: aws.Int64(size),
+	return UploadEditorSpaceFileWithContext(context.Background(), file, fileHeader, spaceId, fileType)
+}
+
+func UploadEditorSpaceFileWithContext(ctx context.Context, file multipart.File, fileHeader *multipart.FileHeader, spaceId int64, fileType string) (string, error) {
+	return uploadMultipartFile(ctx, file, fileHeader, ResourceEditor, allowFileExt, func(_, ext string) string {
+		return fmt.Sprintf("space_editor/%d/%s%s", spaceId, fileType, ext)
 	})
-	if err != nil {
-		return "", err
-	}
-	return fileName, nil
 }
 
 func DeleteFileByKey(key string) error {
-	_, err := s3.New(s3Sess.Sess).DeleteObject(&s3.DeleteObjectInput{
-		Bucket: aws.String(s3Sess.Bucket),
-		Key:    aws.String(key),
-	})
+	if store == nil {
+		return ErrNotInit
+	}
+	err := store.Delete(context.Background(), key)
 	if err != nil {
 		log.Log.Errorf("Delete S3 File [%s] Error:%v", key, err)
 	}
@@ -282,72 +268,78 @@ func DeleteFileByKey(key string) error {
 }
 
 func DeleteDirFiles(dir string) error {
-	ss := s3.New(s3Sess.Sess)
-	out, err := ss.ListObjects(&s3.ListObjectsInput{
-		Bucket: aws.String(s3Sess.Bucket),
-		Prefix: aws.String(dir),
-	})
+	if store == nil {
+		return ErrNotInit
+	}
+	ctx := context.Background()
+	list, err := store.List(ctx, dir)
 	if err != nil {
 		log.Log.Error("Get Dir files err:", err)
 		return err
 	}
-	for _, obj := range out.Contents {
-		_, err = ss.DeleteObject(&s3.DeleteObjectInput{
-			Bucket: aws.String(s3Sess.Bucket),
-			Key:    obj.Key,
-		})
+	for _, obj := range list {
+		err = store.Delete(ctx, obj.Key)
 		if err != nil {
-			log.Log.Errorf("Delete S3 File [%s] Error:%v", *obj.Key, err)
+			log.Log.Errorf("Delete S3 File [%s] Error:%v", obj.Key, err)
 		}
 	}
 	return err
 }
 
+// UploadLocalFile 上传本地文件到指定 key
+func UploadLocalFile(key, filePath, contentType string) error {
+	if store == nil {
+		return ErrNotInit
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return store.Put(context.Background(), key, f, contentType)
+}
+
+// PresignGetObject 生成限时的下载链接，fileName 不为空时浏览器按附件下载并使用该文件名
+func PresignGetObject(key, fileName string, expire time.Duration) (string, error) {
+	if store == nil {
+		return "", ErrNotInit
+	}
+	return store.PresignGet(key, fileName, expire)
+}
+
 func GetFileByKey(key string) ([]byte, error) {
-	out, err := s3.New(s3Sess.Sess).GetObject(&s3.GetObjectInput{Bucket: aws.String(s3Sess.Bucket), Key: aws.String(key)})
+	if store == nil {
+		return nil, ErrNotInit
+	}
+	body, err := store.Get(context.Background(), key)
 	if err != nil {
 		return nil, err
 	}
-	return ioutil.ReadAll(out.Body)
+	defer body.Close()
+	return ioutil.ReadAll(body)
 }
 
 func AdminUploadResource(file multipart.File, fileHeader *multipart.FileHeader, dir string, checkExt bool) (string, error) {
-	originFilename := filepath.Base(fileHeader.Filename)
-	ext := path.Ext(originFilename)
+	return AdminUploadResourceWithContext(context.Background(), file, fileHeader, dir, checkExt)
+}
+
+func AdminUploadResourceWithContext(ctx context.Context, file multipart.File, fileHeader *multipart.FileHeader, dir string, checkExt bool) (string, error) {
+	var allowExt map[string]int
 	if checkExt {
-		if _, ok := allowAdminFileExt[ext]; !ok {
-			return "", NotAllowExt
-		}
+		allowExt = allowAdminFileExt
 	}
-	size := fileHeader.Size
-	buffer := make([]byte, size)
-	file.Read(buffer)
-	sh := md5.New()
-	sh.Write(buffer)
-	imageNameHash := hex.EncodeToString(sh.Sum([]byte("")))
-	s := s3Sess
 	if dir == "" {
 		dir = "images/official"
 	}
-	fileName := fmt.Sprintf("%s/%s%s", dir, imageNameHash, ext)
-	_, err := s3.New(s.Sess).PutObject(&s3.PutObjectInput{
-		Bucket:        aws.String(s.Bucket),
-		Key:           aws.String(fileName),
-		Body:          bytes.NewReader(buffer),
-		ContentType:   aws.String(mimetype.Detect(buffer).String()),
-		ContentLength: aws.Int64(size),
+	return uploadMultipartFile(ctx, file, fileHeader, ResourceAdmin, allowExt, func(md5, ext string) string {
+		return fmt.Sprintf("%s/%s%s", dir, md5, ext)
 	})
-	if err != nil {
-		return "", err
-	}
-	return fileName, nil
 }
 
 func UploadResource(fileBuffer []byte, size int64, ext, dir string) (string, error) {
 	sh := md5.New()
 	sh.Write(fileBuffer)
 	imageNameHash := hex.EncodeToString(sh.Sum([]byte("")))
-	s := s3Sess
 	if dir == "" {
 		dir = "resource/common"
 	}
@@ -357,14 +349,7 @@ func UploadResource(fileBuffer []byte, size int64, ext, dir string) (string, err
 	//}
 
 	fileName := fmt.Sprintf("%s/%s%s", dir, imageNameHash, ext)
-	_, err := s3.New(s.Sess).PutObject(&s3.PutObjectInput{
-		Bucket:        aws.String(s.Bucket),
-		Key:           aws.String(fileName),
-		Body:          bytes.NewReader(fileBuffer),
-		ContentType:   aws.String(mimetype.Detect(fileBuffer).String()),
-		ContentLength: aws.Int64(size),
-	})
-	if err != nil {
+	if _, err := putChecked(context.Background(), fileName, ext, fileBuffer); err != nil {
 		return "", err
 	}
 	return fileName, nil
@@ -379,22 +364,18 @@ func UploadByteFile(data []byte, dir, ext string) (string, error) {
 	sh.Write(data)
 	imageNameHash := hex.EncodeToString(sh.Sum([]byte("")))
 
-	s := s3Sess
 	fileName := fmt.Sprintf("file/%s/%s%s", dir, imageNameHash, ext)
-	_, err := s3.New(s.Sess).PutObject(&s3.PutObjectInput{
-		Bucket:        aws.String(s.Bucket),
-		Key:           aws.String(fileName),
-		Body:          bytes.NewReader(data),
-		ContentType:   aws.String(http.DetectContentType(data)),
-		ContentLength: aws.Int64(int64(len(data))),
-	})
-	if err != nil {
+	if _, err := putChecked(context.Background(), fileName, ext, data); err != nil {
 		return "", err
 	}
 	return fileName, nil
 }
 
+// CreateSpaceEditorTempAccessToken 通过 STS 获取临时凭证，仅 S3 存储可用
 func CreateSpaceEditorTempAccessToken(dir string) (string, string, string, int64, error) {
+	if s3Sess == nil {
+		return "", "", "", 0, ErrNotInit
+	}
 	uploadPath := fmt.Sprintf("%s/%s", s3Sess.Bucket, dir)
 	policy := fmt.Sprintf("{\"Version\": \"2012-10-17\",\"Statement\": [{\"Sid\": \"VisualEditor0\",\"Effect\": \"Allow\",\"Action\": [\"s3:PutObject\",\"s3:GetObject\",\"s3:ListBucketMultipartUploads\",\"s3:AbortMultipartUpload\",\"s3:GetMultiRegionAccessPoint\",\"s3:DeleteMultiRegionAccessPoint\",\"s3:DeleteObject\",\"s3:CreateMultiRegionAccessPoint\",\"s3:ListMultipartUploadParts\"],\"Resource\": [\"arn:aws:s3:::%s\",\"arn:aws:s3:::%s/*\",\"arn:aws:s3::*:accesspoint/*\"]}]}", uploadPath, uploadPath)
 	s := s3Sess
@@ -415,18 +396,10 @@ func CreateSpaceEditorTempAccessToken(dir string) (string, string, string, int64
 }
 
 func UploadByteImage(data []byte, fileName string) (string, error) {
-	s := s3Sess
-	resp, err := s3.New(s.Sess).PutObject(&s3.PutObjectInput{
-		Bucket:        aws.String(s.Bucket),
-		Key:           aws.String(fileName),
-		Body:          bytes.NewReader(data),
-		ContentType:   aws.String("image/png"),
-		ContentLength: aws.Int64(int64(len(data))),
-	})
+	_, err := putChecked(context.Background(), fileName, path.Ext(fileName), data)
 	if err != nil {
 		fmt.Printf("UploadByteImage error: %v\n", err)
 		return "", err
 	}
-	fmt.Printf("UploadByteImage resp: %v\n", resp)
 	return fileName, nil
 }
@@ -6,8 +6,11 @@ import (
 	"golang.org/x/net/http2"
 	"net"
 	"net/http"
+	"net/url"
 	"testing"
 	"time"
+
+	"tool-attendance/config"
 )
 
 func TestCreateTempAccessToken(t *testing.T) {
@@ -71,3 +74,35 @@ func TestSignature(t *testing.T) {
 	err = DeleteDirFiles("space_editor/369")
 	t.Log(err)
 }*/
+
+func TestPresignGetObject(t *testing.T) {
+	err := InitWithConfig(&config.S3Config{
+		AccessKey: "minio",
+		SecretKey: "minio123",
+		Bucket:    "attendance",
+		Endpoint:  "http://127.0.0.1:9000",
+		Region:    "us-east-1",
+		PathStyle: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawUrl, err := PresignGetObject("reports/2023/05/detail.xlsx", "detail.xlsx", 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Host != "127.0.0.1:9000" || u.Path != "/attendance/reports/2023/05/detail.xlsx" {
+		t.Fatalf("unexpected url: %s", rawUrl)
+	}
+	q := u.Query()
+	if q.Get("X-Amz-Signature") == "" || q.Get("X-Amz-Expires") != "600" {
+		t.Fatalf("url not presigned: %s", rawUrl)
+	}
+	if q.Get("response-content-disposition") != `attachment; filename="detail.xlsx"` {
+		t.Fatalf("unexpected content disposition: %s", q.Get("response-content-disposition"))
+	}
+}
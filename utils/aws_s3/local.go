@@ -0,0 +1,148 @@
+package aws_s3
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"tool-attendance/utils"
+)
+
+// LocalObjectPath 本地存储下载接口的路由前缀
+const LocalObjectPath = "/api/v1/storage/objects/"
+
+var ErrInvalidKey = errors.New("invalid object key")
+
+// localStorage 本地磁盘存储，用于开发测试或没有对象存储的部署，下载链接由服务自身签名并提供
+type localStorage struct {
+	dir     string
+	baseUrl string
+	secret  string
+}
+
+// InitLocal 使用本地目录作为存储，baseUrl 为服务对外地址，secret 用于签名下载链接
+func InitLocal(dir, baseUrl, secret string) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	store = &localStorage{dir: dir, baseUrl: strings.TrimRight(baseUrl, "/"), secret: secret}
+	return nil
+}
+
+// path 把 key 转换为本地路径，拒绝跳出存储目录的 key
+func (s *localStorage) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if key == "" || clean == "/" || clean[1:] != strings.TrimPrefix(key, "/") {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(clean[1:])), nil
+}
+
+func (s *localStorage) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+		return err
+	}
+	// 先写临时文件再重命名，避免读到写了一半的文件
+	tmp, err := ioutil.TempFile(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (s *localStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+func (s *localStorage) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *localStorage) List(ctx context.Context, prefix string) ([]Object, error) {
+	list := make([]Object, 0)
+	err := filepath.Walk(s.dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".upload-") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			list = append(list, Object{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+		}
+		return nil
+	})
+	return list, err
+}
+
+func (s *localStorage) PresignGet(key, fileName string, expire time.Duration) (string, error) {
+	if _, err := s.path(key); err != nil {
+		return "", err
+	}
+	expires := time.Now().Add(expire).Unix()
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("filename", fileName)
+	q.Set("signature", s.sign(key, expires, fileName))
+	return fmt.Sprintf("%s%s%s?%s", s.baseUrl, LocalObjectPath, key, q.Encode()), nil
+}
+
+func (s *localStorage) sign(key string, expires int64, fileName string) string {
+	return utils.HmacSha256(s.secret, strings.Join([]string{key, strconv.FormatInt(expires, 10), fileName}, "\n"))
+}
+
+// LocalPresignedPath 校验本地存储下载链接的签名和有效期，返回文件的本地路径
+func LocalPresignedPath(key string, expires int64, fileName, signature string) (string, error) {
+	s, ok := store.(*localStorage)
+	if !ok {
+		return "", ErrNotInit
+	}
+	if expires < time.Now().Unix() {
+		return "", errors.New("link expired")
+	}
+	if !hmac.Equal([]byte(s.sign(key, expires, fileName)), []byte(strings.ToLower(signature))) {
+		return "", errors.New("invalid signature")
+	}
+	return s.path(key)
+}
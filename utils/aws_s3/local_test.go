@@ -0,0 +1,95 @@
+package aws_s3
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalStorage(t *testing.T) {
+	dir := t.TempDir()
+	if err := InitLocal(dir, "http://127.0.0.1:8080/", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	s := GetStorage()
+
+	for _, key := range []string{"reports/2023/05/a.xlsx", "reports/2023/06/b.xlsx", "images/c.png"} {
+		if err := s.Put(ctx, key, bytes.NewReader([]byte(key)), "application/octet-stream"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	body, err := s.Get(ctx, "reports/2023/05/a.xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(body)
+	body.Close()
+	if string(data) != "reports/2023/05/a.xlsx" {
+		t.Fatalf("unexpected content: %s", data)
+	}
+
+	list, err := s.List(ctx, "reports/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("list reports/ got %d objects, want 2", len(list))
+	}
+
+	if err = s.Delete(ctx, "images/c.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Get(ctx, "images/c.png"); err == nil {
+		t.Fatal("deleted object still readable")
+	}
+	// 删除不存在的文件不报错
+	if err = s.Delete(ctx, "images/c.png"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.Put(ctx, "../escape.txt", strings.NewReader("x"), ""); err != ErrInvalidKey {
+		t.Fatalf("put ../escape.txt got %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestLocalPresign(t *testing.T) {
+	if err := InitLocal(t.TempDir(), "http://127.0.0.1:8080", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	rawUrl, err := PresignGetObject("reports/2023/05/a.xlsx", "a.xlsx", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Path != LocalObjectPath+"reports/2023/05/a.xlsx" {
+		t.Fatalf("unexpected path: %s", u.Path)
+	}
+	key := strings.TrimPrefix(u.Path, LocalObjectPath)
+	q := u.Query()
+	expires, _ := strconv.ParseInt(q.Get("expires"), 10, 64)
+
+	if _, err = LocalPresignedPath(key, expires, q.Get("filename"), q.Get("signature")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LocalPresignedPath("reports/2023/05/b.xlsx", expires, q.Get("filename"), q.Get("signature")); err == nil {
+		t.Fatal("signature accepted for another key")
+	}
+	if _, err = LocalPresignedPath(key, expires+1, q.Get("filename"), q.Get("signature")); err == nil {
+		t.Fatal("signature accepted for another expiry")
+	}
+	expired := time.Now().Add(-time.Minute).Unix()
+	sign := GetStorage().(*localStorage).sign(key, expired, "a.xlsx")
+	if _, err = LocalPresignedPath(key, expired, "a.xlsx", sign); err == nil {
+		t.Fatal("expired link accepted")
+	}
+}
@@ -0,0 +1,120 @@
+package aws_s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// 存储后端
+const (
+	DriverS3    = "s3"
+	DriverLocal = "local"
+)
+
+var ErrNotInit = errors.New("storage is not initialised")
+
+// Object 存储中的文件
+type Object struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// Storage 文件存储，key 使用 / 分隔的相对路径
+type Storage interface {
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// PresignGet 生成限时下载链接，fileName 不为空时按附件下载并使用该文件名
+	PresignGet(key, fileName string, expire time.Duration) (string, error)
+}
+
+var store Storage
+
+// GetStorage 当前使用的存储，未初始化时为 nil
+func GetStorage() Storage {
+	return store
+}
+
+// s3Storage S3 及 S3 兼容存储
+type s3Storage struct {
+	sess *S3Session
+}
+
+// 分片上传的分片大小和并发数，小于一个分片的文件直接 PutObject
+const (
+	uploadPartSize    = 8 << 20
+	uploadConcurrency = 3
+)
+
+// Put 流式上传，大文件自动分片，上传失败时已上传的分片会被清理
+func (s *s3Storage) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	uploader := s3manager.NewUploader(s.sess.Sess, func(u *s3manager.Uploader) {
+		u.PartSize = uploadPartSize
+		u.Concurrency = uploadConcurrency
+	})
+	_, err := uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket:      aws.String(s.sess.Bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	})
+	return err
+}
+
+func (s *s3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s3.New(s.sess.Sess).GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.sess.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+func (s *s3Storage) Delete(ctx context.Context, key string) error {
+	_, err := s3.New(s.sess.Sess).DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.sess.Bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func (s *s3Storage) List(ctx context.Context, prefix string) ([]Object, error) {
+	list := make([]Object, 0)
+	err := s3.New(s.sess.Sess).ListObjectsPagesWithContext(ctx, &s3.ListObjectsInput{
+		Bucket: aws.String(s.sess.Bucket),
+		Prefix: aws.String(prefix),
+	}, func(out *s3.ListObjectsOutput, lastPage bool) bool {
+		for _, v := range out.Contents {
+			list = append(list, Object{
+				Key:          aws.StringValue(v.Key),
+				Size:         aws.Int64Value(v.Size),
+				LastModified: aws.TimeValue(v.LastModified),
+			})
+		}
+		return true
+	})
+	return list, err
+}
+
+func (s *s3Storage) PresignGet(key, fileName string, expire time.Duration) (string, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.sess.Bucket),
+		Key:    aws.String(key),
+	}
+	if fileName != "" {
+		input.ResponseContentDisposition = aws.String(fmt.Sprintf("attachment; filename=%q", fileName))
+	}
+	req, _ := s3.New(s.sess.Sess).GetObjectRequest(input)
+	return req.Presign(expire)
+}
@@ -0,0 +1,169 @@
+package aws_s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"mime/multipart"
+	"path"
+	"path/filepath"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// 上传资源类型，不同类型的大小限制不同
+const (
+	ResourceImage     = "image"
+	ResourceAnimation = "animation"
+	ResourceEditor    = "editor"
+	ResourceAdmin     = "admin"
+)
+
+// SizeLimits 各类资源允许的最大字节数
+var SizeLimits = map[string]int64{
+	ResourceImage:     10 << 20,
+	ResourceAnimation: 50 << 20,
+	ResourceEditor:    20 << 20,
+	ResourceAdmin:     200 << 20,
+}
+
+var (
+	ErrTooLarge         = errors.New("file too large")
+	ErrSizeMismatch     = errors.New("file size mismatch")
+	ErrChecksumMismatch = errors.New("file checksum mismatch")
+)
+
+// checksum 文件的大小和摘要
+type checksum struct {
+	size   int64
+	md5    string
+	sha256 string
+}
+
+// sumFile 流式计算文件摘要，超过 limit 时返回 ErrTooLarge，结束后把读取位置恢复到文件开头
+func sumFile(r io.ReadSeeker, limit int64) (*checksum, error) {
+	hMd5, hSha256 := md5.New(), sha256.New()
+	n, err := io.Copy(io.MultiWriter(hMd5, hSha256), io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if n > limit {
+		return nil, ErrTooLarge
+	}
+	if _, err = r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return &checksum{
+		size:   n,
+		md5:    hex.EncodeToString(hMd5.Sum(nil)),
+		sha256: hex.EncodeToString(hSha256.Sum(nil)),
+	}, nil
+}
+
+// checkedReader 上传时再次计算 sha256，读完后与预先计算的摘要不一致则返回错误中止上传
+type checkedReader struct {
+	r    io.Reader
+	h    hash.Hash
+	want string
+}
+
+func (c *checkedReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.h.Write(p[:n])
+	if err == io.EOF && hex.EncodeToString(c.h.Sum(nil)) != c.want {
+		return n, ErrChecksumMismatch
+	}
+	return n, err
+}
+
+// uploadMultipartFile 流式上传表单文件：校验大小和后缀，计算摘要，嗅探类型后由 keyFn 根据 md5 生成 key
+func uploadMultipartFile(ctx context.Context, file multipart.File, fileHeader *multipart.FileHeader, kind string,
+	allowExt map[string]int, keyFn func(md5, ext string) string) (string, error) {
+	if store == nil {
+		return "", ErrNotInit
+	}
+	ext := path.Ext(filepath.Base(fileHeader.Filename))
+	if allowExt != nil {
+		if _, ok := allowExt[ext]; !ok {
+			return "", NotAllowExt
+		}
+	}
+	limit := SizeLimits[kind]
+	if fileHeader.Size > limit {
+		return "", ErrTooLarge
+	}
+	sum, err := sumFile(file, limit)
+	if err != nil {
+		return "", err
+	}
+	if sum.size != fileHeader.Size {
+		return "", ErrSizeMismatch
+	}
+	mime, err := mimetype.DetectReader(file)
+	if err != nil {
+		return "", err
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	key := keyFn(sum.md5, ext)
+	if err = checkContent(file, ext, mime); err != nil {
+		return "", quarantine(ctx, key, file, mime.String(), err)
+	}
+
+	if mime.Is("image/svg+xml") {
+		data, err := SanitizeSvg(file)
+		if err != nil {
+			return "", quarantine(ctx, key, file, mime.String(), err)
+		}
+		if err = store.Put(ctx, key, bytes.NewReader(data), mime.String()); err != nil {
+			return "", err
+		}
+		return key, nil
+	}
+
+	body := &checkedReader{r: file, h: sha256.New(), want: sum.sha256}
+	if err = store.Put(ctx, key, body, mime.String()); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// checkContent 校验文件类型与后缀一致、图片尺寸不超限，结束后把读取位置恢复到文件开头
+func checkContent(r io.ReadSeeker, ext string, mime *mimetype.MIME) error {
+	if err := checkMime(ext, mime); err != nil {
+		return err
+	}
+	err := checkImageSize(r, mime)
+	if _, seekErr := r.Seek(0, io.SeekStart); seekErr != nil {
+		return seekErr
+	}
+	return err
+}
+
+// putChecked 上传内存中的文件，校验与表单上传一致：类型与后缀不符、图片尺寸超限或 svg 无法解析时放入隔离区，
+// svg 清理后再上传；返回嗅探出的文件类型
+func putChecked(ctx context.Context, key, ext string, data []byte) (string, error) {
+	if store == nil {
+		return "", ErrNotInit
+	}
+	mime := mimetype.Detect(data)
+	r := bytes.NewReader(data)
+	if err := checkContent(r, ext, mime); err != nil {
+		return "", quarantine(ctx, key, r, mime.String(), err)
+	}
+	if mime.Is("image/svg+xml") {
+		clean, err := SanitizeSvg(r)
+		if err != nil {
+			return "", quarantine(ctx, key, bytes.NewReader(data), mime.String(), err)
+		}
+		data = clean
+	}
+	return mime.String(), store.Put(ctx, key, bytes.NewReader(data), mime.String())
+}
@@ -0,0 +1,109 @@
+package aws_s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"mime/multipart"
+	"os"
+	"strings"
+	"testing"
+
+	"tool-attendance/config"
+	"tool-attendance/log"
+)
+
+func TestMain(m *testing.M) {
+	log.Init(&config.LoggerConfig{Level: "error"})
+	os.Exit(m.Run())
+}
+
+// formFile 构造表单上传的文件
+func formFile(t *testing.T, name string, data []byte) (multipart.File, *multipart.FileHeader) {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	fw, err := w.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(data)
+	w.Close()
+
+	form, err := multipart.NewReader(body, w.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fh := form.File["file"][0]
+	f, err := fh.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f, fh
+}
+
+func TestApiUploadImage(t *testing.T) {
+	if err := InitLocal(t.TempDir(), "", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	data := pngImage(t, 32, 32)
+	sum := md5.Sum(data)
+
+	f, fh := formFile(t, "a.png", data)
+	key, err := ApiUploadImage(f, fh, "avatar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "images/avatar/" + hex.EncodeToString(sum[:]) + ".png"; key != want {
+		t.Fatalf("key = %s, want %s", key, want)
+	}
+	got, err := GetFileByKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("uploaded content mismatch")
+	}
+
+	f, fh = formFile(t, "a.exe", data)
+	if _, err = ApiUploadImage(f, fh, "avatar"); err != NotAllowExt {
+		t.Fatalf("upload .exe got %v, want NotAllowExt", err)
+	}
+
+	limit := SizeLimits[ResourceImage]
+	SizeLimits[ResourceImage] = 10
+	defer func() { SizeLimits[ResourceImage] = limit }()
+	f, fh = formFile(t, "a.png", data)
+	if _, err = ApiUploadImage(f, fh, "avatar"); err != ErrTooLarge {
+		t.Fatalf("upload large file got %v, want ErrTooLarge", err)
+	}
+}
+
+func TestCheckedReader(t *testing.T) {
+	if err := InitLocal(t.TempDir(), "", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := sumFile(bytes.NewReader([]byte("hello")), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 上传的内容与计算摘要时不一致
+	body := &checkedReader{r: strings.NewReader("hellO"), h: sha256.New(), want: sum.sha256}
+	if err = GetStorage().Put(context.Background(), "a.txt", body, ""); err != ErrChecksumMismatch {
+		t.Fatalf("put got %v, want ErrChecksumMismatch", err)
+	}
+	if _, err = GetFileByKey("a.txt"); err == nil {
+		t.Fatal("corrupted file stored")
+	}
+
+	body = &checkedReader{r: strings.NewReader("hello"), h: sha256.New(), want: sum.sha256}
+	if err = GetStorage().Put(context.Background(), "a.txt", body, ""); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := GetFileByKey("a.txt")
+	if string(data) != "hello" {
+		t.Fatalf("unexpected content: %s", data)
+	}
+}
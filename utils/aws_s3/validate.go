@@ -0,0 +1,230 @@
+package aws_s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	"tool-attendance/log"
+)
+
+// QuarantinePrefix 内容校验不通过的文件存放在该前缀下，便于人工排查，不对外提供访问
+const QuarantinePrefix = "quarantine/"
+
+// 图片最大宽高（像素）
+var (
+	MaxImageWidth  = 8192
+	MaxImageHeight = 8192
+)
+
+var ErrQuarantined = errors.New("file content rejected")
+
+// extMimes 后缀对应的文件类型，嗅探出的类型（或其父类型）须在其中；未列出的后缀（.res、.ab 等私有格式）不校验
+var extMimes = map[string][]string{
+	".png":  {"image/png"},
+	".jpg":  {"image/jpeg"},
+	".jpeg": {"image/jpeg"},
+	".gif":  {"image/gif"},
+	".svg":  {"image/svg+xml"},
+	".mp4":  {"video/mp4"},
+	".json": {"application/json"},
+	".glb":  {"model/gltf-binary"},
+	".zip":  {"application/zip"},
+	".xlsx": {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+	".csv":  {"text/csv", "text/plain"},
+}
+
+// checkMime 校验嗅探出的文件类型与后缀是否一致
+func checkMime(ext string, mime *mimetype.MIME) error {
+	allowed, ok := extMimes[strings.ToLower(ext)]
+	if !ok {
+		return nil
+	}
+	for m := mime; m != nil; m = m.Parent() {
+		for _, v := range allowed {
+			if m.Is(v) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("content type %s does not match %s", mime.String(), ext)
+}
+
+// checkImageSize 校验位图的宽高，非位图不校验
+func checkImageSize(r io.Reader, mime *mimetype.MIME) error {
+	if !mime.Is("image/png") && !mime.Is("image/jpeg") && !mime.Is("image/gif") {
+		return nil
+	}
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return fmt.Errorf("decode image: %v", err)
+	}
+	if cfg.Width > MaxImageWidth || cfg.Height > MaxImageHeight {
+		return fmt.Errorf("image %dx%d exceeds %dx%d", cfg.Width, cfg.Height, MaxImageWidth, MaxImageHeight)
+	}
+	return nil
+}
+
+// svg 中允许保留的元素（小写），其余元素连同其内容一起移除，包括 script、style、foreignObject、animate、set 等
+var svgAllowedElements = toSet(
+	"svg", "g", "defs", "symbol", "use", "title", "desc", "metadata", "switch", "view", "a", "image",
+	"path", "rect", "circle", "ellipse", "line", "polyline", "polygon", "text", "tspan", "textpath",
+	"lineargradient", "radialgradient", "stop", "pattern", "clippath", "mask", "marker",
+	"filter", "feblend", "fecolormatrix", "fecomponenttransfer", "fecomposite", "feflood", "fegaussianblur",
+	"femerge", "femergenode", "feoffset", "fefunca", "fefuncb", "fefuncg", "fefuncr", "fedropshadow",
+)
+
+// svg 中允许保留的属性（小写，带前缀的写全名），其余属性移除，包括 on* 事件
+var svgAllowedAttrs = toSet(
+	"id", "class", "style", "version", "baseprofile", "lang", "xml:space", "xml:lang", "xmlns", "href", "xlink:href", "xlink:title",
+	"x", "y", "x1", "y1", "x2", "y2", "cx", "cy", "r", "rx", "ry", "fx", "fy", "fr", "dx", "dy", "width", "height",
+	"d", "points", "viewbox", "preserveaspectratio", "transform", "pathlength", "rotate", "textlength", "lengthadjust",
+	"fill", "fill-opacity", "fill-rule", "stroke", "stroke-width", "stroke-linecap", "stroke-linejoin",
+	"stroke-miterlimit", "stroke-dasharray", "stroke-dashoffset", "stroke-opacity", "opacity", "color",
+	"display", "visibility", "overflow", "font-family", "font-size", "font-weight", "font-style", "text-anchor",
+	"dominant-baseline", "alignment-baseline", "baseline-shift", "letter-spacing", "word-spacing", "text-decoration",
+	"clip-path", "clip-rule", "mask", "filter", "marker-start", "marker-mid", "marker-end", "shape-rendering",
+	"image-rendering", "stop-color", "stop-opacity", "offset", "gradientunits", "gradienttransform", "spreadmethod",
+	"patternunits", "patterncontentunits", "patterntransform", "clippathunits", "maskunits", "maskcontentunits",
+	"markerwidth", "markerheight", "markerunits", "refx", "refy", "orient", "filterunits", "primitiveunits",
+	"color-interpolation-filters", "in", "in2", "result", "stddeviation", "mode", "operator", "values", "type",
+	"k1", "k2", "k3", "k4", "flood-color", "flood-opacity", "tablevalues", "slope", "intercept", "amplitude", "exponent",
+)
+
+func toSet(list ...string) map[string]bool {
+	m := make(map[string]bool, len(list))
+	for _, v := range list {
+		m[v] = true
+	}
+	return m
+}
+
+// SanitizeSvg 按白名单清理 svg：只保留常用的图形元素和属性，链接只能指向文档内的锚点（a 可以是 http(s)，image 可以是位图 data URI），
+// 属性和 style 中的 url() 只能引用文档内的锚点；移除 DOCTYPE（避免实体注入）、注释和 xml 声明以外的处理指令（如 xml-stylesheet）
+func SanitizeSvg(r io.Reader) ([]byte, error) {
+	dec := xml.NewDecoder(r)
+	buf := &bytes.Buffer{}
+	enc := xml.NewEncoder(buf)
+	skip := 0 // 处于被移除元素内部的层数
+	for {
+		// RawToken 不解析命名空间，保留原始的前缀
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if skip > 0 || t.Name.Space != "" || !svgAllowedElements[strings.ToLower(t.Name.Local)] {
+				skip++
+				continue
+			}
+			element := strings.ToLower(t.Name.Local)
+			attrs := make([]xml.Attr, 0, len(t.Attr))
+			for _, a := range t.Attr {
+				if !isSafeSvgAttr(element, a) {
+					continue
+				}
+				a.Name = rawName(a.Name)
+				attrs = append(attrs, a)
+			}
+			t.Attr = attrs
+			tok = t
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			t.Name = rawName(t.Name)
+			tok = t
+		case xml.ProcInst:
+			if t.Target != "xml" {
+				continue
+			}
+		case xml.Directive, xml.Comment:
+			continue
+		default:
+			if skip > 0 {
+				continue
+			}
+		}
+		if err = enc.EncodeToken(xml.CopyToken(tok)); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// rawName 把前缀合并到名称中，避免 encoder 改写命名空间
+func rawName(n xml.Name) xml.Name {
+	if n.Space == "" {
+		return n
+	}
+	return xml.Name{Local: n.Space + ":" + n.Local}
+}
+
+func isSafeSvgAttr(element string, a xml.Attr) bool {
+	name := strings.ToLower(rawName(a.Name).Local)
+	// 命名空间声明
+	if a.Name.Space == "xmlns" {
+		return true
+	}
+	if !svgAllowedAttrs[name] {
+		return false
+	}
+	v := strings.ToLower(strings.Join(strings.Fields(a.Value), ""))
+	if strings.Contains(v, "javascript:") || strings.Contains(v, "vbscript:") ||
+		strings.Contains(v, "expression(") || strings.Contains(v, "@import") {
+		return false
+	}
+	// url() 只能引用文档内的锚点，如 fill="url(#gradient)"
+	for i := strings.Index(v, "url("); i >= 0; i = strings.Index(v, "url(") {
+		v2 := strings.TrimLeft(v[i+4:], `'"`)
+		if !strings.HasPrefix(v2, "#") {
+			return false
+		}
+		v = v[i+4:]
+	}
+	if name == "href" || name == "xlink:href" {
+		return isSafeSvgHref(element, strings.ToLower(strings.Join(strings.Fields(a.Value), "")))
+	}
+	return true
+}
+
+func isSafeSvgHref(element, v string) bool {
+	switch {
+	case strings.HasPrefix(v, "#"):
+		return true
+	case element == "a":
+		return strings.HasPrefix(v, "https://") || strings.HasPrefix(v, "http://")
+	case element == "image":
+		return strings.HasPrefix(v, "data:image/png;") || strings.HasPrefix(v, "data:image/jpeg;") ||
+			strings.HasPrefix(v, "data:image/gif;")
+	}
+	return false
+}
+
+// quarantine 把校验不通过的文件存到隔离前缀下，返回的错误包含原因
+func quarantine(ctx context.Context, key string, body io.ReadSeeker, contentType string, reason error) error {
+	if _, err := body.Seek(0, io.SeekStart); err == nil {
+		if err = store.Put(ctx, QuarantinePrefix+key, body, contentType); err != nil {
+			log.Log.Errorf("quarantine %s err:%v", key, err)
+		}
+	}
+	log.Log.WithAlarm().Warnf("upload %s quarantined: %v", key, reason)
+	return fmt.Errorf("%w: %v", ErrQuarantined, reason)
+}
@@ -0,0 +1,159 @@
+package aws_s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+func TestSanitizeSvg(t *testing.T) {
+	src := `<?xml version="1.0"?>
+<!DOCTYPE svg [<!ENTITY x "y">]>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" onload="alert(1)">
+<script>alert(2)</script>
+<foreignObject><div>x</div></foreignObject>
+<a xlink:href=" java script:alert(3)"><rect width="10" height="10" onclick="alert(4)"/></a>
+<use xlink:href="#icon"/>
+</svg>`
+	out, err := SanitizeSvg(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(out)
+	for _, bad := range []string{"alert", "script", "foreignObject", "DOCTYPE", "onload", "onclick"} {
+		if strings.Contains(s, bad) {
+			t.Fatalf("sanitized svg still contains %q: %s", bad, s)
+		}
+	}
+	for _, keep := range []string{`xmlns:xlink="http://www.w3.org/1999/xlink"`, `<use xlink:href="#icon"></use>`, `<rect width="10" height="10"></rect>`} {
+		if !strings.Contains(s, keep) {
+			t.Fatalf("sanitized svg lost %q: %s", keep, s)
+		}
+	}
+	if mimetype.Detect(out).String() != "image/svg+xml" {
+		t.Fatalf("sanitized output is not svg: %s", s)
+	}
+
+	src = `<?xml version="1.0"?>
+<?xml-stylesheet href="https://evil.example/x.css"?>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink">
+<a xlink:href="#ok"><set attributeName="href" to="javascript:evil1"/><animate attributeName="xlink:href" values="javascript:evil2"/>link</a>
+<rect style="background:url(javascript:evil3)" fill="url(#grad)" width="1" height="1"/>
+<circle style="fill:url( 'https://evil.example/track' )" r="1"/>
+<style>@import url(https://evil.example/x.css);</style>
+<image href="data:image/svg+xml;base64,PHN2Zz4=" width="1" height="1"/>
+</svg>`
+	if out, err = SanitizeSvg(strings.NewReader(src)); err != nil {
+		t.Fatal(err)
+	}
+	s = string(out)
+	for _, bad := range []string{"evil", "xml-stylesheet", "<set", "<animate", "<style", "data:image/svg"} {
+		if strings.Contains(s, bad) {
+			t.Fatalf("sanitized svg still contains %q: %s", bad, s)
+		}
+	}
+	for _, keep := range []string{`<a xlink:href="#ok">`, `fill="url(#grad)"`, `<circle r="1">`} {
+		if !strings.Contains(s, keep) {
+			t.Fatalf("sanitized svg lost %q: %s", keep, s)
+		}
+	}
+
+	if _, err = SanitizeSvg(strings.NewReader("<svg><g></svg>")); err == nil {
+		t.Fatal("malformed svg accepted")
+	}
+}
+
+func TestCheckMime(t *testing.T) {
+	pngData := pngImage(t, 1, 1)
+	cases := []struct {
+		ext  string
+		data []byte
+		ok   bool
+	}{
+		{".png", pngData, true},
+		{".PNG", pngData, true},
+		{".jpg", pngData, false},
+		{".json", []byte(`{"a":1}`), true},
+		{".json", pngData, false},
+		{".svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), true},
+		{".res", pngData, true}, // 私有格式不校验
+	}
+	for _, c := range cases {
+		err := checkMime(c.ext, mimetype.Detect(c.data))
+		if (err == nil) != c.ok {
+			t.Errorf("checkMime(%s) err = %v, want ok = %v", c.ext, err, c.ok)
+		}
+	}
+}
+
+func TestUploadQuarantine(t *testing.T) {
+	if err := InitLocal(t.TempDir(), "", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 后缀与内容不一致
+	f, fh := formFile(t, "a.jpg", pngImage(t, 1, 1))
+	if _, err := ApiUploadImage(f, fh, "avatar"); !errors.Is(err, ErrQuarantined) {
+		t.Fatalf("upload mismatched file got %v, want ErrQuarantined", err)
+	}
+
+	// 尺寸超限
+	width := MaxImageWidth
+	MaxImageWidth = 10
+	defer func() { MaxImageWidth = width }()
+	f, fh = formFile(t, "b.png", pngImage(t, 20, 1))
+	if _, err := ApiUploadImage(f, fh, "avatar"); !errors.Is(err, ErrQuarantined) {
+		t.Fatalf("upload large image got %v, want ErrQuarantined", err)
+	}
+
+	list, err := GetStorage().List(context.Background(), QuarantinePrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("quarantined %d files, want 2", len(list))
+	}
+	if list, _ = GetStorage().List(context.Background(), "images/"); len(list) != 0 {
+		t.Fatalf("rejected files stored under images/: %v", list)
+	}
+
+	// svg 上传后脚本被移除
+	f, fh = formFile(t, "c.svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`))
+	key, err := ApiUploadImage(f, fh, "avatar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := GetFileByKey(key)
+	if bytes.Contains(data, []byte("script")) {
+		t.Fatalf("svg not sanitized: %s", data)
+	}
+
+	// 内存中的文件走同样的校验
+	if _, err = UploadResource(pngImage(t, 1, 1), 0, ".jpg", ""); !errors.Is(err, ErrQuarantined) {
+		t.Fatalf("upload mismatched resource got %v, want ErrQuarantined", err)
+	}
+	if _, err = UploadNftImage(pngImage(t, 20, 1), "d.png", "nft"); !errors.Is(err, ErrQuarantined) {
+		t.Fatalf("upload large nft image got %v, want ErrQuarantined", err)
+	}
+	key, err = UploadByteFile([]byte(`<svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"/>`), "doc", ".svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ = GetFileByKey(key); bytes.Contains(data, []byte("alert")) {
+		t.Fatalf("svg not sanitized: %s", data)
+	}
+}
+
+func pngImage(t *testing.T, w, h int) []byte {
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -19,7 +19,8 @@ func TestSetInterval(t *testing.T) {
 }
 
 func TestSetInterval2(t *testing.T) {
-	ctx, _ := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	SetInterval(time.Second*1, ctx, func() {
 		fmt.Println("--------2----")
 	})
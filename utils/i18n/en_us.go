@@ -0,0 +1,131 @@
+package i18n
+
+var enUS = map[string]string{
+	// 接口返回信息
+	"code.0":    "Success",
+	"code.1000": "Failed",
+	"code.1001": "Not Found",
+	"code.1002": "Params Error",
+	"code.1003": "Times used up",
+	"code.1009": "Forbidden Address",
+	"code.1052": "The user account doesn’t exist.",
+	"code.1062": "You have not bound your wallet.",
+	"code.1068": "You repetitive operation.",
+	"code.2001": "Service Unavailable",
+	"code.5001": "Service Error",
+	"code.6000": "Unknown Error",
+
+	// 报表
+	"report.title":       "Attendance %d-%02d",
+	"report.serial":      "No.",
+	"report.name":        "Name",
+	"report.week":        "Weekday",
+	"report.date":        "Date",
+	"report.summary":     "Summary (%d workdays this month)",
+	"report.onwork":      "In",
+	"report.offwork":     "Out",
+	"report.duration":    "Hours",
+	"report.late":        "Late",
+	"report.early":       "Early",
+	"stat.attend":        "Present",
+	"stat.absent":        "Absent",
+	"stat.late":          "Late",
+	"stat.early":         "Left early",
+	"stat.short":         "Short hours",
+	"stat.lack":          "Missed punch",
+	"stat.late_minutes":  "Late minutes",
+	"stat.early_minutes": "Early leave minutes",
+	"stat.half_absent":   "Half-day absence",
+	"stat.out_of_fence":  "Out of fence",
+	"stat.remote":        "Remote",
+	"legend.title":       "Legend",
+	"legend.card":        "√ punched",
+	"legend.no_card":     "× not punched",
+	"legend.no_duration": "- hours unknown",
+	"legend.late_early":  "n minutes late / left early",
+	"legend.rest":        "blank rest day",
+	"legend.fence":       "red out-of-fence punch",
+	"week.1":             "Mon",
+	"week.2":             "Tue",
+	"week.3":             "Wed",
+	"week.4":             "Thu",
+	"week.5":             "Fri",
+	"week.6":             "Sat",
+	"week.7":             "Sun",
+
+	// 薪资扣款
+	"payroll.title":             "Payroll %d-%02d",
+	"payroll.user_id":           "User ID",
+	"payroll.name":              "Name",
+	"payroll.work_day":          "Days present",
+	"payroll.absent_day":        "Days absent",
+	"payroll.half_absent_day":   "Half-day absences",
+	"payroll.late_count":        "Late count",
+	"payroll.late_minutes":      "Late minutes",
+	"payroll.early_count":       "Early leave count",
+	"payroll.early_minutes":     "Early leave minutes",
+	"payroll.unpaid_leave_days": "Unpaid leave days",
+	"payroll.late_penalty":      "Late deduction",
+	"payroll.early_penalty":     "Early leave deduction",
+	"payroll.total_penalty":     "Total deduction",
+
+	// 打卡时间热力图
+	"heatmap.title":     "Punches %d-%02d",
+	"heatmap.arrival":   "Arrivals",
+	"heatmap.departure": "Departures",
+	"heatmap.slot":      "Slot",
+
+	// 异常打卡
+	"anomaly.sheet":               "Anomalies",
+	"anomaly.kind":                "Type",
+	"anomaly.desc":                "Details",
+	"anomaly.kind.off_before_on":  "Out before in",
+	"anomaly.kind.too_long":       "Span too long",
+	"anomaly.kind.out_of_service": "Outside employment",
+	"anomaly.kind.same_timestamp": "Same time as others",
+	"anomaly.kind.pattern_shift":  "Arrival pattern shift",
+	"anomaly.desc.too_long":       "%.1f hours between punches",
+	"anomaly.desc.pattern_shift":  "usually arrives at %s",
+	"anomaly.desc.same_timestamp": "same as: %s",
+
+	// 结果对比
+	"diff.title":                 "Attendance diff",
+	"diff.side":                  "%d-%02d",
+	"diff.side_rules":            "%d-%02d (new rules)",
+	"diff.side_stored":           "%d-%02d (previous rules and calendar)",
+	"diff.status":                "Change",
+	"diff.status.added":          "Added",
+	"diff.status.removed":        "Removed",
+	"diff.status.changed":        "Changed",
+	"diff.field":                 "Field",
+	"diff.month":                 "Month total",
+	"diff.yes":                   "Yes",
+	"diff.no":                    "No",
+	"diff.field.work_day":        "Days present",
+	"diff.field.absent_day":      "Days absent",
+	"diff.field.late_day":        "Days late",
+	"diff.field.early_day":       "Days left early",
+	"diff.field.short_day":       "Days short",
+	"diff.field.lack_card_day":   "Days missed punch",
+	"diff.field.half_absent_day": "Half-day absences",
+	"diff.field.workday":         "Workday",
+	"diff.field.onwork_time":     "In",
+	"diff.field.offwork_time":    "Out",
+	"diff.field.present":         "Present",
+	"diff.field.absent":          "Absent",
+	"diff.field.late":            "Late",
+	"diff.field.early":           "Left early",
+	"diff.field.short":           "Short hours",
+	"diff.field.lack_card":       "Missed punch",
+	"diff.field.duration":        "Hours",
+	"diff.field.late_minutes":    "Late minutes",
+	"diff.field.early_minutes":   "Early leave minutes",
+	"diff.field.late_tier":       "Late tier",
+	"diff.field.early_tier":      "Early leave tier",
+	"diff.field.half_absent":     "Half-day absence",
+
+	// 打卡地点
+	"diff.field.place":            "Punch place",
+	"diff.field.out_of_fence_day": "Out-of-fence days",
+	"diff.field.remote_day":       "Remote days",
+}
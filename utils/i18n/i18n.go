@@ -0,0 +1,107 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ZhCN = "zh-CN"
+	EnUS = "en-US"
+)
+
+// 语言的通用前缀到支持语言的映射，如 zh-TW、zh 都使用 zh-CN
+var baseLang = map[string]string{
+	"zh": ZhCN,
+	"en": EnUS,
+}
+
+var catalogue = map[string]map[string]string{
+	ZhCN: zhCN,
+	EnUS: enUS,
+}
+
+// T 翻译 key，当前语言缺失时回退到 en-US，仍缺失时返回 key 本身
+func T(lang, key string, args ...interface{}) string {
+	msg, ok := catalogue[lang][key]
+	if !ok {
+		if msg, ok = catalogue[EnUS][key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Has 判断 key 是否在 lang 或回退语言中存在
+func Has(lang, key string) bool {
+	if _, ok := catalogue[lang][key]; ok {
+		return true
+	}
+	_, ok := catalogue[EnUS][key]
+	return ok
+}
+
+// FromContext 获取请求语言：query 参数 lang 优先，其次 Accept-Language，都无法匹配时返回 fallback
+func FromContext(c *gin.Context, fallback string) string {
+	if lang := Match(c.Query("lang")); lang != "" {
+		return lang
+	}
+	if lang := Match(c.GetHeader("Accept-Language")); lang != "" {
+		return lang
+	}
+	return fallback
+}
+
+type weightedLang struct {
+	tag string
+	q   float64
+}
+
+// Match 按权重解析 Accept-Language（如 zh-CN,zh;q=0.9,en;q=0.8），返回第一个支持的语言，无匹配时返回空字符串
+func Match(header string) string {
+	if header == "" {
+		return ""
+	}
+	langs := make([]weightedLang, 0, 4)
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			langs = append(langs, weightedLang{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].q > langs[j].q
+	})
+	for _, v := range langs {
+		for supported := range catalogue {
+			if strings.EqualFold(v.tag, supported) {
+				return supported
+			}
+		}
+		base := strings.ToLower(strings.SplitN(strings.Replace(v.tag, "_", "-", 1), "-", 2)[0])
+		if lang, ok := baseLang[base]; ok {
+			return lang
+		}
+	}
+	return ""
+}
@@ -0,0 +1,49 @@
+package i18n
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	cases := map[string]string{
+		"":                             "",
+		"fr-FR":                        "",
+		"zh-CN,zh;q=0.9,en;q=0.8":      ZhCN,
+		"en-GB,en;q=0.9":               EnUS,
+		"fr;q=1,en-us;q=0.5,zh;q=0.7":  ZhCN,
+		"zh_TW":                        ZhCN,
+		"zh;q=0,en":                    EnUS,
+		" en-US ; q=0.3 , de ; q=0.9 ": EnUS,
+	}
+	for header, want := range cases {
+		if got := Match(header); got != want {
+			t.Errorf("Match(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	if got := T(ZhCN, "report.title", 2023, 5); got != "2023年5月考勤记录" {
+		t.Errorf("got %s", got)
+	}
+	if got := T(EnUS, "report.title", 2023, 5); got != "Attendance 2023-05" {
+		t.Errorf("got %s", got)
+	}
+	if got := T("ja-JP", "code.0"); got != "Success" {
+		t.Errorf("got %s", got)
+	}
+	if got := T(ZhCN, "no.such.key"); got != "no.such.key" {
+		t.Errorf("got %s", got)
+	}
+}
+
+// 每种语言的 key 必须一致
+func TestCatalogueKeys(t *testing.T) {
+	for lang, messages := range catalogue {
+		for other, otherMessages := range catalogue {
+			for key := range messages {
+				if _, ok := otherMessages[key]; !ok {
+					t.Errorf("%s missing key %s (present in %s)", other, key, lang)
+				}
+			}
+		}
+	}
+}
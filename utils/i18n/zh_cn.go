@@ -0,0 +1,131 @@
+package i18n
+
+var zhCN = map[string]string{
+	// 接口返回信息
+	"code.0":    "成功",
+	"code.1000": "失败",
+	"code.1001": "未找到",
+	"code.1002": "参数错误",
+	"code.1003": "次数已用完",
+	"code.1009": "禁止访问",
+	"code.1052": "用户账号不存在。",
+	"code.1062": "您还未绑定钱包。",
+	"code.1068": "重复操作。",
+	"code.2001": "服务不可用",
+	"code.5001": "服务错误",
+	"code.6000": "未知错误",
+
+	// 报表
+	"report.title":       "%d年%d月考勤记录",
+	"report.serial":      "序号",
+	"report.name":        "姓名",
+	"report.week":        "星期",
+	"report.date":        "日期",
+	"report.summary":     "统计（本月需出勤 %d 天）",
+	"report.onwork":      "上班",
+	"report.offwork":     "下班",
+	"report.duration":    "时长",
+	"report.late":        "迟到",
+	"report.early":       "早退",
+	"stat.attend":        "出勤",
+	"stat.absent":        "旷工",
+	"stat.late":          "迟到",
+	"stat.early":         "早退",
+	"stat.short":         "时长不足",
+	"stat.lack":          "漏打卡",
+	"stat.late_minutes":  "迟到分钟",
+	"stat.early_minutes": "早退分钟",
+	"stat.half_absent":   "半天旷工",
+	"stat.out_of_fence":  "围栏外",
+	"stat.remote":        "远程",
+	"legend.title":       "图例",
+	"legend.card":        "√ 正常打卡",
+	"legend.no_card":     "× 未打卡",
+	"legend.no_duration": "- 无法计算时长",
+	"legend.late_early":  "数字 迟到/早退分钟数",
+	"legend.rest":        "空白 休息日",
+	"legend.fence":       "红色 围栏外打卡",
+	"week.1":             "一",
+	"week.2":             "二",
+	"week.3":             "三",
+	"week.4":             "四",
+	"week.5":             "五",
+	"week.6":             "六",
+	"week.7":             "日",
+
+	// 薪资扣款
+	"payroll.title":             "%d年%d月薪资扣款",
+	"payroll.user_id":           "工号",
+	"payroll.name":              "姓名",
+	"payroll.work_day":          "出勤天数",
+	"payroll.absent_day":        "旷工天数",
+	"payroll.half_absent_day":   "半天旷工天数",
+	"payroll.late_count":        "迟到次数",
+	"payroll.late_minutes":      "迟到分钟",
+	"payroll.early_count":       "早退次数",
+	"payroll.early_minutes":     "早退分钟",
+	"payroll.unpaid_leave_days": "无薪假天数",
+	"payroll.late_penalty":      "迟到扣款",
+	"payroll.early_penalty":     "早退扣款",
+	"payroll.total_penalty":     "扣款合计",
+
+	// 打卡时间热力图
+	"heatmap.title":     "%d年%d月打卡分布",
+	"heatmap.arrival":   "上班打卡",
+	"heatmap.departure": "下班打卡",
+	"heatmap.slot":      "时段",
+
+	// 异常打卡
+	"anomaly.sheet":               "异常打卡",
+	"anomaly.kind":                "异常类型",
+	"anomaly.desc":                "说明",
+	"anomaly.kind.off_before_on":  "下班早于上班",
+	"anomaly.kind.too_long":       "打卡间隔过长",
+	"anomaly.kind.out_of_service": "不在职期间打卡",
+	"anomaly.kind.same_timestamp": "与他人打卡时间相同",
+	"anomaly.kind.pattern_shift":  "上班时间突变",
+	"anomaly.desc.too_long":       "间隔 %.1f 小时",
+	"anomaly.desc.pattern_shift":  "近期通常 %s 上班",
+	"anomaly.desc.same_timestamp": "相同：%s",
+
+	// 结果对比
+	"diff.title":                 "考勤结果对比",
+	"diff.side":                  "%d年%d月",
+	"diff.side_rules":            "%d年%d月（新规则）",
+	"diff.side_stored":           "%d年%d月（之前的规则和日历）",
+	"diff.status":                "变化",
+	"diff.status.added":          "新增",
+	"diff.status.removed":        "移除",
+	"diff.status.changed":        "变更",
+	"diff.field":                 "项目",
+	"diff.month":                 "月度统计",
+	"diff.yes":                   "是",
+	"diff.no":                    "否",
+	"diff.field.work_day":        "出勤天数",
+	"diff.field.absent_day":      "旷工天数",
+	"diff.field.late_day":        "迟到天数",
+	"diff.field.early_day":       "早退天数",
+	"diff.field.short_day":       "时长不足天数",
+	"diff.field.lack_card_day":   "漏打卡天数",
+	"diff.field.half_absent_day": "半天旷工天数",
+	"diff.field.workday":         "工作日",
+	"diff.field.onwork_time":     "上班",
+	"diff.field.offwork_time":    "下班",
+	"diff.field.present":         "出勤",
+	"diff.field.absent":          "旷工",
+	"diff.field.late":            "迟到",
+	"diff.field.early":           "早退",
+	"diff.field.short":           "时长不足",
+	"diff.field.lack_card":       "漏打卡",
+	"diff.field.duration":        "时长",
+	"diff.field.late_minutes":    "迟到分钟",
+	"diff.field.early_minutes":   "早退分钟",
+	"diff.field.late_tier":       "迟到分档",
+	"diff.field.early_tier":      "早退分档",
+	"diff.field.half_absent":     "半天旷工",
+
+	// 打卡地点
+	"diff.field.place":            "打卡地点",
+	"diff.field.out_of_fence_day": "围栏外天数",
+	"diff.field.remote_day":       "远程天数",
+}
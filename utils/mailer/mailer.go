@@ -0,0 +1,263 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"tool-attendance/config"
+)
+
+// 连接加密方式
+const (
+	SecurityTLS      = "tls"
+	SecurityStartTLS = "starttls"
+	SecurityNone     = "none"
+)
+
+var (
+	ErrNotConfigured = errors.New("mailer is not configured")
+	ErrNoRecipient   = errors.New("mail has no recipient")
+)
+
+// Attachment 邮件附件
+type Attachment struct {
+	Name        string
+	ContentType string // 为空时使用 application/octet-stream
+	Data        []byte
+}
+
+// Message 邮件内容，正文为 html
+type Message struct {
+	To          []string
+	Cc          []string
+	Subject     string
+	Html        string
+	Attachments []Attachment
+}
+
+type Mailer struct {
+	cfg config.MailConfig
+}
+
+func New(cfg config.MailConfig) *Mailer {
+	return &Mailer{cfg: cfg}
+}
+
+var defaultMailer *Mailer
+
+// Init 初始化默认的发信客户端，host 为空时不启用
+func Init(cfg config.MailConfig) {
+	if cfg.Host == "" {
+		return
+	}
+	defaultMailer = New(cfg)
+}
+
+// Enabled 是否配置了发信
+func Enabled() bool {
+	return defaultMailer != nil
+}
+
+// Send 使用默认客户端发送邮件
+func Send(msg *Message) error {
+	if defaultMailer == nil {
+		return ErrNotConfigured
+	}
+	return defaultMailer.Send(msg)
+}
+
+// Render 渲染 html 模板
+func Render(t *template.Template, name string, data interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := t.ExecuteTemplate(buf, name, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Send 建立 smtp 连接并发送邮件，每封邮件使用单独的连接
+func (m *Mailer) Send(msg *Message) error {
+	if len(msg.To)+len(msg.Cc) == 0 {
+		return ErrNoRecipient
+	}
+	from, err := mail.ParseAddress(m.cfg.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address: %w", err)
+	}
+	data, err := msg.build(from)
+	if err != nil {
+		return err
+	}
+
+	c, err := m.dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if m.cfg.Username != "" {
+		if ok, _ := c.Extension("AUTH"); ok {
+			if err = c.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+				return err
+			}
+		}
+	}
+	if err = c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, addr := range append(append([]string{}, msg.To...), msg.Cc...) {
+		a, err := mail.ParseAddress(addr)
+		if err != nil {
+			return fmt.Errorf("invalid recipient %q: %w", addr, err)
+		}
+		if err = c.Rcpt(a.Address); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(data); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (m *Mailer) dial() (*smtp.Client, error) {
+	timeout := m.cfg.Timeout * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	// 整个会话共用一个超时
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	tlsCfg := &tls.Config{ServerName: m.cfg.Host}
+	if m.cfg.Security == SecurityTLS {
+		tlsConn := tls.Client(conn, tlsCfg)
+		if err = tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	c, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if m.cfg.Security == SecurityStartTLS {
+		if err = c.StartTLS(tlsCfg); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// build 生成 MIME 格式的邮件内容
+func (msg *Message) build(from *mail.Address) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+	headers := [][2]string{
+		{"From", from.String()},
+		{"To", strings.Join(msg.To, ", ")},
+		{"Cc", strings.Join(msg.Cc, ", ")},
+		{"Subject", mime.BEncoding.Encode("UTF-8", msg.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-Id", messageId(from.Address)},
+		{"Mime-Version", "1.0"},
+		{"Content-Type", "multipart/mixed; boundary=" + w.Boundary()},
+	}
+	for _, h := range headers {
+		if h[1] != "" {
+			fmt.Fprintf(buf, "%s: %s\r\n", h[0], h[1])
+		}
+	}
+	buf.WriteString("\r\n")
+
+	body, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = writeBase64(body, []byte(msg.Html)); err != nil {
+		return nil, err
+	}
+
+	for _, a := range msg.Attachments {
+		contentType := a.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		name := mime.BEncoding.Encode("UTF-8", a.Name)
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {fmt.Sprintf("%s; name=%q", contentType, name)},
+			"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", name)},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err = writeBase64(part, a.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64 按每行 76 个字符写入 base64 编码的内容
+func writeBase64(w io.Writer, data []byte) error {
+	const lineLen = 76
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		n := lineLen
+		if len(encoded) < n {
+			n = len(encoded)
+		}
+		if _, err := fmt.Fprintf(w, "%s\r\n", encoded[:n]); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
+
+func messageId(from string) string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = from[i+1:]
+	}
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(b), domain)
+}
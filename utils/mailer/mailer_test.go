@@ -0,0 +1,165 @@
+package mailer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"html/template"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"testing"
+
+	"tool-attendance/config"
+)
+
+// fakeSmtp 本地 smtp 服务，记录收到的信封和内容
+type fakeSmtp struct {
+	ln   net.Listener
+	from string
+	rcpt []string
+	data chan string
+}
+
+func newFakeSmtp(t *testing.T) *fakeSmtp {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSmtp{ln: ln, data: make(chan string, 1)}
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeSmtp) serve() {
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			s.from = strings.Trim(strings.TrimPrefix(cmd, "MAIL FROM:"), "<>")
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.rcpt = append(s.rcpt, strings.Trim(strings.TrimPrefix(cmd, "RCPT TO:"), "<>"))
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var b strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				b.WriteString(strings.TrimPrefix(l, "."))
+			}
+			s.data <- b.String()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	srv := newFakeSmtp(t)
+	host, port, _ := net.SplitHostPort(srv.ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+	m := New(config.MailConfig{
+		Host:     host,
+		Port:     p,
+		From:     "考勤 <hr@example.com>",
+		Security: SecurityNone,
+		Timeout:  5,
+	})
+
+	tpl := template.Must(template.New("mail").Parse(`{{define "hello"}}<p>{{.}}</p>{{end}}`))
+	html, err := Render(tpl, "hello", "<张三>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Send(&Message{
+		To:          []string{"a@example.com"},
+		Cc:          []string{"李四 <b@example.com>"},
+		Subject:     "2023年5月考勤",
+		Html:        html,
+		Attachments: []Attachment{{Name: "考勤.xlsx", Data: []byte("excel data")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if srv.from != "hr@example.com" || strings.Join(srv.rcpt, ",") != "a@example.com,b@example.com" {
+		t.Fatalf("unexpected envelope: from %s, rcpt %v", srv.from, srv.rcpt)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(<-srv.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "2023年5月考勤" {
+		t.Fatalf("subject = %s", subject)
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+
+	part, err := mr.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(part)
+	if got := decodeBase64(t, body); got != "<p>&lt;张三&gt;</p>" {
+		t.Fatalf("html = %s", got)
+	}
+
+	part, err = mr.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := new(mime.WordDecoder).DecodeHeader(part.FileName()); name != "考勤.xlsx" {
+		t.Fatalf("attachment name = %s", name)
+	}
+	data, _ := ioutil.ReadAll(part)
+	if got := decodeBase64(t, data); got != "excel data" {
+		t.Fatalf("attachment = %s", got)
+	}
+}
+
+func TestSendNoRecipient(t *testing.T) {
+	if err := New(config.MailConfig{}).Send(&Message{}); err != ErrNoRecipient {
+		t.Fatalf("got %v, want ErrNoRecipient", err)
+	}
+}
+
+func decodeBase64(t *testing.T, data []byte) string {
+	b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(data)), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -0,0 +1,104 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	alarmQueueSize = 100
+	alarmTimeout   = 10 * time.Second
+	// 相同内容的告警在该时间内只发送一次
+	alarmSilence = 5 * time.Minute
+)
+
+// AlarmHook 把 log.Log.WithAlarm() 标记的日志转发到订阅了 alarm 的渠道，发送在后台协程中完成
+type AlarmHook struct {
+	server string
+	queue  chan Message
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	sent   map[string]time.Time
+	closed bool
+}
+
+// NewAlarmHook server 为告警中显示的服务名
+func NewAlarmHook(server string) *AlarmHook {
+	h := &AlarmHook{
+		server: server,
+		queue:  make(chan Message, alarmQueueSize),
+		sent:   make(map[string]time.Time),
+	}
+	h.wg.Add(1)
+	go h.loop()
+	return h
+}
+
+func (h *AlarmHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire 队列满或处于静默期时直接丢弃，不阻塞打日志
+func (h *AlarmHook) Fire(entry *logrus.Entry) error {
+	if alarm, _ := entry.Data["alarm"].(bool); !alarm {
+		return nil
+	}
+	text := entry.Message
+	if entry.HasCaller() {
+		text = fmt.Sprintf("%s\n\n> %s:%d", text, entry.Caller.File, entry.Caller.Line)
+	}
+	msg := Message{
+		Title: fmt.Sprintf("[%s] %s", entry.Level.String(), h.server),
+		Text:  text,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed || h.silenced(entry.Message, entry.Time) {
+		return nil
+	}
+	select {
+	case h.queue <- msg:
+	default:
+	}
+	return nil
+}
+
+// silenced 调用方需持有锁
+func (h *AlarmHook) silenced(key string, now time.Time) bool {
+	if last, ok := h.sent[key]; ok && now.Sub(last) < alarmSilence {
+		return true
+	}
+	h.sent[key] = now
+	// 清理过期的记录，避免无限增长
+	for k, v := range h.sent {
+		if now.Sub(v) >= alarmSilence {
+			delete(h.sent, k)
+		}
+	}
+	return false
+}
+
+func (h *AlarmHook) loop() {
+	defer h.wg.Done()
+	for msg := range h.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), alarmTimeout)
+		// 发送失败不能再打告警日志，否则会循环触发
+		_ = Broadcast(ctx, EventAlarm, msg)
+		cancel()
+	}
+}
+
+// Close 停止接收告警，并等待队列中的告警发送完
+func (h *AlarmHook) Close() {
+	h.mu.Lock()
+	h.closed = true
+	close(h.queue)
+	h.mu.Unlock()
+	h.wg.Wait()
+}
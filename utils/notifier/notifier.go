@@ -0,0 +1,144 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"tool-attendance/config"
+)
+
+// 平台类型
+const (
+	TypeDingTalk = "dingtalk"
+	TypeFeishu   = "feishu"
+	TypeSlack    = "slack"
+)
+
+// 消息类型，渠道按类型订阅
+const (
+	EventAlarm    = "alarm"    // 服务告警（log.Log.WithAlarm()）
+	EventDigest   = "digest"   // 每日考勤汇总
+	EventReminder = "reminder" // 员工打卡提醒
+)
+
+// Message 通知内容，Text 使用 markdown，不支持 markdown 的平台按纯文本发送
+type Message struct {
+	Title string
+	Text  string
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// New 按配置创建渠道
+func New(cfg config.NotifyChannel) (Notifier, error) {
+	if cfg.Webhook == "" {
+		return nil, errors.New("notify webhook is empty")
+	}
+	switch cfg.Type {
+	case TypeDingTalk:
+		return &dingTalk{webhook: cfg.Webhook, secret: cfg.Secret}, nil
+	case TypeFeishu:
+		return &feishu{webhook: cfg.Webhook, secret: cfg.Secret}, nil
+	case TypeSlack:
+		return &slack{webhook: cfg.Webhook}, nil
+	default:
+		return nil, fmt.Errorf("unknown notify type: %s", cfg.Type)
+	}
+}
+
+type channel struct {
+	Notifier
+	events []string
+}
+
+func (c *channel) accept(event string) bool {
+	if len(c.events) == 0 {
+		return true
+	}
+	for _, v := range c.events {
+		if v == event {
+			return true
+		}
+	}
+	return false
+}
+
+var channels []*channel
+
+// Init 按配置创建所有渠道
+func Init(cfg config.NotifyConfig) error {
+	list := make([]*channel, 0, len(cfg.Channels))
+	for _, v := range cfg.Channels {
+		n, err := New(v)
+		if err != nil {
+			return err
+		}
+		list = append(list, &channel{Notifier: n, events: v.Events})
+	}
+	channels = list
+	return nil
+}
+
+// Enabled 是否有渠道订阅了该类型的消息
+func Enabled(event string) bool {
+	for _, c := range channels {
+		if c.accept(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// Broadcast 发送到订阅了该类型消息的所有渠道，部分渠道失败时返回合并的错误
+func Broadcast(ctx context.Context, event string, msg Message) error {
+	var errs []string
+	for _, c := range channels {
+		if !c.accept(event) {
+			continue
+		}
+		if err := c.Notify(ctx, msg); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// postJson 发送 json 请求，返回响应内容
+func postJson(ctx context.Context, url string, payload interface{}) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("webhook status %d: %s", resp.StatusCode, body)
+	}
+	return body, nil
+}
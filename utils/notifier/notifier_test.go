@@ -0,0 +1,135 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"tool-attendance/config"
+)
+
+// webhookServer 记录收到的请求，按 reply 返回
+type webhookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	queries  []map[string]string
+	payloads []map[string]interface{}
+}
+
+func newWebhookServer(t *testing.T, reply string) *webhookServer {
+	s := &webhookServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		payload := map[string]interface{}{}
+		_ = json.Unmarshal(body, &payload)
+		q := map[string]string{}
+		for k := range r.URL.Query() {
+			q[k] = r.URL.Query().Get(k)
+		}
+		s.mu.Lock()
+		s.queries = append(s.queries, q)
+		s.payloads = append(s.payloads, payload)
+		s.mu.Unlock()
+		w.Write([]byte(reply))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *webhookServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.payloads)
+}
+
+func TestDingTalk(t *testing.T) {
+	srv := newWebhookServer(t, `{"errcode":0,"errmsg":"ok"}`)
+	n, _ := New(config.NotifyChannel{Type: TypeDingTalk, Webhook: srv.URL + "/robot/send?access_token=x", Secret: "SEC"})
+	if err := n.Notify(context.Background(), Message{Title: "日报", Text: "**迟到**：张三"}); err != nil {
+		t.Fatal(err)
+	}
+	q := srv.queries[0]
+	ts, _ := strconv.ParseInt(q["timestamp"], 10, 64)
+	if q["access_token"] != "x" || q["sign"] != dingTalkSign("SEC", ts) {
+		t.Fatalf("unexpected query: %v", q)
+	}
+	if srv.payloads[0]["msgtype"] != "markdown" {
+		t.Fatalf("unexpected payload: %v", srv.payloads[0])
+	}
+
+	srv = newWebhookServer(t, `{"errcode":310000,"errmsg":"sign not match"}`)
+	n, _ = New(config.NotifyChannel{Type: TypeDingTalk, Webhook: srv.URL})
+	if err := n.Notify(context.Background(), Message{}); err == nil {
+		t.Fatal("dingtalk error not returned")
+	}
+}
+
+func TestFeishu(t *testing.T) {
+	srv := newWebhookServer(t, `{"code":0,"msg":"success"}`)
+	n, _ := New(config.NotifyChannel{Type: TypeFeishu, Webhook: srv.URL, Secret: "SEC"})
+	if err := n.Notify(context.Background(), Message{Title: "日报", Text: "张三"}); err != nil {
+		t.Fatal(err)
+	}
+	p := srv.payloads[0]
+	ts, _ := strconv.ParseInt(p["timestamp"].(string), 10, 64)
+	if p["sign"] != feishuSign("SEC", ts) || p["content"].(map[string]interface{})["text"] != "日报\n张三" {
+		t.Fatalf("unexpected payload: %v", p)
+	}
+}
+
+func TestSlack(t *testing.T) {
+	srv := newWebhookServer(t, "ok")
+	n, _ := New(config.NotifyChannel{Type: TypeSlack, Webhook: srv.URL})
+	if err := n.Notify(context.Background(), Message{Title: "日报", Text: "**迟到**：张三"}); err != nil {
+		t.Fatal(err)
+	}
+	if srv.payloads[0]["text"] != "*日报*\n*迟到*：张三" {
+		t.Fatalf("unexpected payload: %v", srv.payloads[0])
+	}
+}
+
+func TestBroadcastAndAlarmHook(t *testing.T) {
+	alarm := newWebhookServer(t, "ok")
+	digest := newWebhookServer(t, "ok")
+	err := Init(config.NotifyConfig{Channels: []config.NotifyChannel{
+		{Type: TypeSlack, Webhook: alarm.URL, Events: []string{EventAlarm}},
+		{Type: TypeSlack, Webhook: digest.URL, Events: []string{EventDigest}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Init(config.NotifyConfig{})
+
+	if err = Broadcast(context.Background(), EventDigest, Message{Title: "日报"}); err != nil {
+		t.Fatal(err)
+	}
+	if alarm.count() != 0 || digest.count() != 1 {
+		t.Fatalf("broadcast digest: alarm %d, digest %d", alarm.count(), digest.count())
+	}
+	if Enabled(EventReminder) {
+		t.Fatal("no channel subscribes reminder")
+	}
+
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	hook := NewAlarmHook("test")
+	logger.AddHook(hook)
+	logger.Error("not alarm")
+	logger.WithField("alarm", true).Error("db down")
+	logger.WithField("alarm", true).Error("db down") // 静默期内重复的告警不发送
+	hook.Close()
+	logger.WithField("alarm", true).Error("after close")
+
+	if alarm.count() != 1 || digest.count() != 1 {
+		t.Fatalf("alarm hook: alarm %d, digest %d", alarm.count(), digest.count())
+	}
+	if text := alarm.payloads[0]["text"]; text != "*[error] test*\ndb down" {
+		t.Fatalf("unexpected alarm: %v", text)
+	}
+}
@@ -0,0 +1,122 @@
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dingTalk 钉钉群机器人，开启加签时 url 需带上 timestamp 和 sign
+type dingTalk struct {
+	webhook string
+	secret  string
+}
+
+func dingTalkSign(secret string, timestamp int64) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(fmt.Sprintf("%d\n%s", timestamp, secret)))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func (d *dingTalk) Notify(ctx context.Context, msg Message) error {
+	webhook := d.webhook
+	if d.secret != "" {
+		ts := time.Now().UnixNano() / int64(time.Millisecond)
+		u, err := url.Parse(webhook)
+		if err != nil {
+			return err
+		}
+		q := u.Query()
+		q.Set("timestamp", strconv.FormatInt(ts, 10))
+		q.Set("sign", dingTalkSign(d.secret, ts))
+		u.RawQuery = q.Encode()
+		webhook = u.String()
+	}
+	body, err := postJson(ctx, webhook, map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": msg.Title,
+			"text":  fmt.Sprintf("### %s\n\n%s", msg.Title, msg.Text),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("dingtalk: %w", err)
+	}
+	var res struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err = json.Unmarshal(body, &res); err != nil {
+		return fmt.Errorf("dingtalk: %w", err)
+	}
+	if res.ErrCode != 0 {
+		return fmt.Errorf("dingtalk: %d %s", res.ErrCode, res.ErrMsg)
+	}
+	return nil
+}
+
+// feishu 飞书群机器人，开启签名校验时 body 需带上 timestamp 和 sign
+type feishu struct {
+	webhook string
+	secret  string
+}
+
+// feishuSign 飞书以 timestamp\nsecret 为密钥对空内容签名
+func feishuSign(secret string, timestamp int64) string {
+	h := hmac.New(sha256.New, []byte(fmt.Sprintf("%d\n%s", timestamp, secret)))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func (f *feishu) Notify(ctx context.Context, msg Message) error {
+	payload := map[string]interface{}{
+		"msg_type": "text",
+		"content": map[string]string{
+			"text": msg.Title + "\n" + msg.Text,
+		},
+	}
+	if f.secret != "" {
+		ts := time.Now().Unix()
+		payload["timestamp"] = strconv.FormatInt(ts, 10)
+		payload["sign"] = feishuSign(f.secret, ts)
+	}
+	body, err := postJson(ctx, f.webhook, payload)
+	if err != nil {
+		return fmt.Errorf("feishu: %w", err)
+	}
+	var res struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err = json.Unmarshal(body, &res); err != nil {
+		return fmt.Errorf("feishu: %w", err)
+	}
+	if res.Code != 0 {
+		return fmt.Errorf("feishu: %d %s", res.Code, res.Msg)
+	}
+	return nil
+}
+
+// slack incoming webhook，成功时返回 ok
+type slack struct {
+	webhook string
+}
+
+func (s *slack) Notify(ctx context.Context, msg Message) error {
+	// slack 的 mrkdwn 用 *粗体*
+	text := fmt.Sprintf("*%s*\n%s", msg.Title, strings.ReplaceAll(msg.Text, "**", "*"))
+	body, err := postJson(ctx, s.webhook, map[string]string{"text": text})
+	if err != nil {
+		return fmt.Errorf("slack: %w", err)
+	}
+	if strings.TrimSpace(string(body)) != "ok" {
+		return fmt.Errorf("slack: %s", body)
+	}
+	return nil
+}
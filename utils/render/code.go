@@ -16,17 +16,4 @@ const (
 	UnKnowError        = 6000
 )
 
-var statusMsg = map[int]string{
-	Ok:                  "Success",
-	Failed:              "Failed",
-	NotFound:            "Not Found",
-	ErrParams:           "Params Error",
-	TimesUsedUp:         "Times used up",
-	ErrForbidden:        "Forbidden Address",
-	ServiceUnavailable:  "Service Unavailable",
-	ServiceError:        "Service Error",
-	UnKnowError:         "Unknown Error",
-	UserNotExist:        "The user account doesn’t exist.",
-	NoBindingWallet:     "You have not bound your wallet.",
-	RepetitiveOperation: "You repetitive operation.",
-}
+// 各语言的提示信息见 utils/i18n 中的 code.<状态码>
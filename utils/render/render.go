@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"tool-attendance/log"
+	"tool-attendance/utils/i18n"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,7 +16,7 @@ type RespJsonData struct {
 }
 
 func AbortJson(c *gin.Context, code int, data interface{}) {
-	msg := getMessage(code)
+	msg := getMessage(c, code)
 	result := &RespJsonData{
 		Code: code,
 		Msg:  msg,
@@ -32,7 +33,7 @@ func Json(c *gin.Context, code int, data interface{}) {
 			code = UnKnowError
 		}
 	}
-	msg := getMessage(code)
+	msg := getMessage(c, code)
 	if code != Ok {
 		if log.Log != nil {
 			log.Log.Errorf("Request:%s, Code:%d, Msg:%s,Data:%v", c.Request.RequestURI, code, msg, data)
@@ -48,12 +49,16 @@ func Json(c *gin.Context, code int, data interface{}) {
 	c.JSON(http.StatusOK, result)
 }
 
-func getMessage(code int) string {
+// getMessage 按请求语言获取状态码的提示信息，未指定语言时使用英文
+func getMessage(c *gin.Context, code int) string {
 	msg := ""
 	if 100 <= code && code <= 600 {
 		msg = http.StatusText(code)
 	} else {
-		msg = statusMsg[code]
+		key := fmt.Sprintf("code.%d", code)
+		if i18n.Has(i18n.EnUS, key) {
+			msg = i18n.T(i18n.FromContext(c, i18n.EnUS), key)
+		}
 	}
 	return msg
 }
@@ -0,0 +1,84 @@
+package safehttp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrNotAllowed 地址不是 http(s)，或指向内网、回环等不允许访问的地址
+var ErrNotAllowed = errors.New("url is not allowed")
+
+// 不允许访问的地址段：本机、内网、链路本地、运营商 NAT、组播和保留地址
+var blockedNets = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, v := range []string{
+		"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12",
+		"192.0.0.0/24", "192.168.0.0/16", "198.18.0.0/15", "224.0.0.0/4", "240.0.0.0/4",
+		"::/128", "::1/128", "fc00::/7", "fe80::/10", "ff00::/8",
+	} {
+		_, n, _ := net.ParseCIDR(v)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+// IsPublicIP 是否为公网地址
+func IsPublicIP(ip net.IP) bool {
+	for _, n := range blockedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckHost 主机解析出的地址都必须是公网地址，解析失败时返回解析错误
+func CheckHost(host string) error {
+	ips, err := net.DefaultResolver.LookupIP(context.Background(), "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, ip := range ips {
+		if !IsPublicIP(ip) {
+			return ErrNotAllowed
+		}
+	}
+	return nil
+}
+
+// CheckUrl 校验地址只能是 http(s)，且主机解析出的地址都是公网地址
+func CheckUrl(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrNotAllowed
+	}
+	return CheckHost(u.Hostname())
+}
+
+// NewClient 只连接公网地址的客户端，连接时再检查一次解析结果，避免域名在校验后改为解析到内网；不跟随跳转
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout: 5 * time.Second,
+				Control: func(network, address string, _ syscall.RawConn) error {
+					host, _, err := net.SplitHostPort(address)
+					if err != nil {
+						return err
+					}
+					if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+						return ErrNotAllowed
+					}
+					return nil
+				},
+			}).DialContext,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
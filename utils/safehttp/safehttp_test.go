@@ -0,0 +1,30 @@
+package safehttp
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestCheckUrl(t *testing.T) {
+	for _, v := range []string{
+		"ftp://8.8.8.8/cb", "http://127.0.0.1:8080/cb", "http://10.1.2.3/cb", "http://169.254.169.254/latest",
+		"http://[::1]/cb", "http://[::ffff:192.168.1.1]/cb", "not a url",
+	} {
+		if err := CheckUrl(v); !errors.Is(err, ErrNotAllowed) {
+			t.Errorf("%s: err = %v", v, err)
+		}
+	}
+	if err := CheckUrl("https://8.8.8.8/cb"); err != nil {
+		t.Errorf("public ip: %v", err)
+	}
+	if IsPublicIP(net.ParseIP("100.64.0.1")) || !IsPublicIP(net.ParseIP("1.1.1.1")) {
+		t.Error("IsPublicIP")
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	if _, err := NewClient(0).Get("http://127.0.0.1:1/"); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("loopback: err = %v", err)
+	}
+}
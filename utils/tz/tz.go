@@ -0,0 +1,60 @@
+package tz
+
+import (
+	"sync"
+	"time"
+	_ "time/tzdata" // 内置 IANA 时区数据库，避免运行环境缺少 zoneinfo
+)
+
+var (
+	defaultLoc = time.FixedZone("CST", 8*3600) // 未配置时默认东八区
+	locations  sync.Map
+	mu         sync.RWMutex
+)
+
+// Init 设置默认时区，name 为 IANA 时区名，如 Asia/Shanghai
+func Init(name string) error {
+	if name == "" {
+		return nil
+	}
+	loc, err := Load(name)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defaultLoc = loc
+	mu.Unlock()
+	return nil
+}
+
+// Default 默认时区
+func Default() *time.Location {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultLoc
+}
+
+// Load 加载 IANA 时区（含夏令时规则），name 为空时返回默认时区
+func Load(name string) (*time.Location, error) {
+	if name == "" {
+		return Default(), nil
+	}
+	if v, ok := locations.Load(name); ok {
+		return v.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// LoadOrDefault 加载时区，失败时返回默认时区
+func LoadOrDefault(name string) *time.Location {
+	loc, err := Load(name)
+	if err != nil {
+		return Default()
+	}
+	return loc
+}
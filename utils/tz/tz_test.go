@@ -0,0 +1,41 @@
+package tz
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	if _, err := Load("Mars/Olympus"); err == nil {
+		t.Error("invalid zone should fail")
+	}
+	if loc, _ := Load(""); loc != Default() {
+		t.Error("empty name should return default zone")
+	}
+
+	// 夏令时：纽约冬季 UTC-5，夏季 UTC-4
+	loc, err := Load("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, winter := time.Date(2023, 1, 10, 9, 0, 0, 0, loc).Zone()
+	_, summer := time.Date(2023, 7, 10, 9, 0, 0, 0, loc).Zone()
+	if winter != -5*3600 || summer != -4*3600 {
+		t.Errorf("got offsets %d %d", winter, summer)
+	}
+}
+
+func TestInit(t *testing.T) {
+	old := Default()
+	defer func() { defaultLoc = old }()
+
+	if err := Init("Europe/Berlin"); err != nil {
+		t.Fatal(err)
+	}
+	if Default().String() != "Europe/Berlin" {
+		t.Errorf("got %s", Default())
+	}
+	if err := Init("Nowhere/City"); err == nil {
+		t.Error("invalid zone should fail")
+	}
+}
@@ -2,8 +2,10 @@ package utils
 
 import (
 	"bytes"
+	"crypto/hmac"
 	"crypto/md5"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
@@ -451,12 +453,47 @@ func UUID() string {
 const salt = "iuakj72394273lsdH6(HF20hfiunKGG21bn9nah82l-N9hjfb0*n209N)2h9f9198^4hJ1ghd^hj2JHJGU%232js0ybFFGGchbq90ev2-wendlsa09ht54govrndlhHhj9a0&*n29naKL"
 
 func ValidateSign(oriStr string, sign string, timestamp int64) bool {
-	if math.Abs(float64(time.Now().Unix()-timestamp)) > 600 {
+	if !ValidateSignTimestamp(timestamp, 600) {
 		return false
 	}
 	return Md5(oriStr+salt) == sign
 }
 
+// ValidateSignTimestamp 签名时间戳与当前时间的误差不超过 window 秒
+func ValidateSignTimestamp(timestamp int64, window int64) bool {
+	return math.Abs(float64(time.Now().Unix()-timestamp)) <= float64(window)
+}
+
+// ValidateHmacSign 使用客户端密钥校验 hmac-sha256 签名，sign 为小写 hex
+func ValidateHmacSign(secret, oriStr, sign string, timestamp int64, window int64) bool {
+	if !ValidateSignTimestamp(timestamp, window) {
+		return false
+	}
+	return hmac.Equal([]byte(HmacSha256(secret, oriStr)), []byte(strings.ToLower(sign)))
+}
+
+// HmacSignString 拼接待签名字符串：method\npath\ntimestamp\nnonce\nsha256(body)
+func HmacSignString(method, path string, timestamp int64, nonce string, body []byte) string {
+	return strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		strconv.FormatInt(timestamp, 10),
+		nonce,
+		Sha256(body),
+	}, "\n")
+}
+
+func HmacSha256(secret, oriStr string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(oriStr))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func Sha256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
 func Sha1(oriStr string) string {
 	sha1 := sha1.New()
 	sha1.Write([]byte(oriStr))
@@ -17,22 +17,20 @@ type Pool struct {
 }
 
 func New(num int) *Pool {
+	return NewWithQueue(num, 0)
+}
+
+// NewWithQueue 创建 num 个 worker 的协程池，最多排队 queue 个未开始的任务
+func NewWithQueue(num, queue int) *Pool {
 	p := Pool{
-		work: make(chan Worker),
+		work: make(chan Worker, queue),
 		open: true,
 	}
 	p.wg.Add(num)
 	for i := 0; i < num; i++ {
 		go func() {
 			for w := range p.work {
-				defer func() {
-					if err := recover(); err != nil {
-						log.Log.Error("task error:", err)
-					}
-				}()
-				if err := w.Task(); err != nil {
-					log.Log.Error("task error:", err)
-				}
+				runTask(w)
 			}
 			p.wg.Done()
 		}()
@@ -41,6 +39,18 @@ func New(num int) *Pool {
 	return &p
 }
 
+// runTask 执行单个任务，任务 panic 时不影响 worker 继续处理后续任务
+func runTask(w Worker) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Log.Error("task panic:", err)
+		}
+	}()
+	if err := w.Task(); err != nil {
+		log.Log.Error("task error:", err)
+	}
+}
+
 func (p *Pool) SubmitWork(w Worker) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -50,6 +60,21 @@ func (p *Pool) SubmitWork(w Worker) {
 
 }
 
+// TrySubmit 不阻塞地提交任务，协程池已关闭或排队已满时返回 false
+func (p *Pool) TrySubmit(w Worker) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.open {
+		return false
+	}
+	select {
+	case p.work <- w:
+		return true
+	default:
+		return false
+	}
+}
+
 func (p *Pool) Shutdown() {
 	p.mu.Lock()
 	p.open = false
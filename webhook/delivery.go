@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"tool-attendance/model"
+	"tool-attendance/utils"
+)
+
+// 推送请求头
+// 签名：hex(hmac_sha256(secret, timestamp + "." + body))
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderEventId   = "X-Webhook-Id"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// 记录的响应内容长度上限
+const maxResponseBody = 1024
+
+// Sign 推送内容的签名，接收方用同样的方式计算后比对
+func Sign(secret string, timestamp int64, body []byte) string {
+	return utils.HmacSha256(secret, strconv.FormatInt(timestamp, 10)+"."+string(body))
+}
+
+type deliveryWorker struct {
+	id int64
+}
+
+func (w *deliveryWorker) Task() error {
+	defer inflight.Delete(w.id)
+	d, err := model.FindWebhookDelivery(w.id)
+	if err != nil {
+		return err
+	}
+	if d.Status != model.WebhookDeliveryPending {
+		return nil
+	}
+
+	var (
+		code   int
+		body   string
+		giveUp bool // 订阅已删除，不再重试
+	)
+	sub, err := model.FindWebhookSubscription(d.SubscriptionId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err, giveUp = errors.New("subscription not found"), true
+	} else if err == nil {
+		code, body, err = send(sub, d)
+	}
+
+	now := time.Now()
+	d.Attempts++
+	values := map[string]interface{}{
+		"attempts":      d.Attempts,
+		"response_code": code,
+		"response_body": body,
+		"error":         "",
+		"next_retry_at": nil,
+	}
+	switch {
+	case err == nil:
+		values["status"], values["finished_at"] = model.WebhookDeliverySuccess, now
+	case giveUp || d.Attempts >= hookCfg.MaxAttempts:
+		values["status"], values["finished_at"], values["error"] = model.WebhookDeliveryFailed, now, err.Error()
+	default:
+		values["next_retry_at"], values["error"] = now.Add(backoff(hookCfg, d.Attempts)), err.Error()
+	}
+	if uerr := model.UpdateWebhookDelivery(d.ID, values); uerr != nil {
+		return uerr
+	}
+	if err != nil {
+		return fmt.Errorf("webhook delivery %d attempt %d: %w", d.ID, d.Attempts, err)
+	}
+	return nil
+}
+
+// send 推送一次，返回状态码和截断后的响应内容，非 2xx 视为失败
+func send(sub *model.WebhookSubscription, d *model.WebhookDelivery) (int, string, error) {
+	body := []byte(d.Payload)
+	req, err := http.NewRequest(http.MethodPost, sub.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderEventId, d.EventId)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, timestamp, body))
+
+	resp, err := hookClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(respBody), fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(respBody), nil
+}
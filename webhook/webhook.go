@@ -0,0 +1,218 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"tool-attendance/config"
+	"tool-attendance/log"
+	"tool-attendance/model"
+	"tool-attendance/utils"
+	"tool-attendance/utils/gtimer"
+	"tool-attendance/utils/safehttp"
+	"tool-attendance/utils/workpool"
+)
+
+// 事件类型
+const (
+	EventPunchCreated   = "punch.created"   // 收到打卡记录
+	EventLeaveApproved  = "leave.approved"  // 请假审批通过
+	EventMonthFinalised = "month.finalised" // 月度考勤已关账
+)
+
+var Events = []string{EventPunchCreated, EventLeaveApproved, EventMonthFinalised}
+
+const (
+	// 扫描待重试推送的间隔
+	scanInterval = 10 * time.Second
+	scanLimit    = 100
+)
+
+var (
+	hookCfg    config.WebhookConfig
+	hookPool   *workpool.Pool
+	hookClient = safehttp.NewClient(10 * time.Second) // 只连接公网地址，避免订阅地址指向内网
+	hookCancel context.CancelFunc
+	inflight   sync.Map // 已提交到 worker 的推送 id，避免扫描时重复提交
+)
+
+// Payload 推送的请求体
+type Payload struct {
+	Id        string      `json:"id"` // 事件 id，重试和重新推送时不变
+	Event     string      `json:"event"`
+	CreatedAt int64       `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+func IsValidEvent(event string) bool {
+	return utils.InSliceString(Events, event)
+}
+
+// GenSecret 生成订阅的签名密钥
+func GenSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Init 启动推送 worker 和重试扫描，服务重启前未完成的推送会在扫描时继续
+func Init(cfg config.WebhookConfig) {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 100
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	hookCfg = cfg
+	hookClient = safehttp.NewClient(cfg.Timeout * time.Second)
+	hookPool = workpool.NewWithQueue(cfg.Workers, cfg.QueueSize)
+
+	var ctx context.Context
+	ctx, hookCancel = context.WithCancel(context.Background())
+	gtimer.SetInterval(scanInterval, ctx, scanDue)
+}
+
+// Stop 停止接收推送并等待正在执行的推送结束
+func Stop() {
+	if hookPool == nil {
+		return
+	}
+	hookCancel()
+	hookPool.Shutdown()
+}
+
+// Publish 给订阅了 event 的所有订阅方创建推送，推送在后台 worker 中执行，失败只记录日志
+func Publish(event string, data interface{}) {
+	NewPublisher().Publish(event, data)
+}
+
+// Publisher 在一次请求中发布多个事件时复用订阅列表，订阅在第一次发布时查询
+type Publisher struct {
+	subs   []model.WebhookSubscription
+	loaded bool
+}
+
+func NewPublisher() *Publisher {
+	return &Publisher{}
+}
+
+// Publish 同包级 Publish，订阅列表只查询一次
+func (p *Publisher) Publish(event string, data interface{}) {
+	if hookPool == nil {
+		return
+	}
+	if !p.loaded {
+		subs, err := model.FindActiveWebhookSubscriptions()
+		if err != nil {
+			log.Log.Error("find webhook subscriptions err:", err)
+			return
+		}
+		p.subs, p.loaded = subs, true
+	}
+	var (
+		body []byte
+		err  error
+	)
+	payload := Payload{Id: utils.UUID(), Event: event, CreatedAt: time.Now().Unix(), Data: data}
+	for _, s := range p.subs {
+		if !subscribed(s, event) {
+			continue
+		}
+		if body == nil {
+			if body, err = json.Marshal(payload); err != nil {
+				log.Log.Errorf("marshal webhook %s payload err:%v", event, err)
+				return
+			}
+		}
+		now := time.Now()
+		d := &model.WebhookDelivery{
+			SubscriptionId: s.ID,
+			EventId:        payload.Id,
+			Event:          event,
+			Payload:        string(body),
+			Status:         model.WebhookDeliveryPending,
+			NextRetryAt:    &now,
+			CreatedAt:      now,
+		}
+		if err = model.CreateWebhookDelivery(d); err != nil {
+			log.Log.Errorf("create webhook delivery of subscription %d err:%v", s.ID, err)
+			continue
+		}
+		submit(d.ID)
+	}
+}
+
+// Redeliver 以原推送的事件和内容重新推送一次，重试次数重新计算
+func Redeliver(id int64) (*model.WebhookDelivery, error) {
+	old, err := model.FindWebhookDelivery(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	d := &model.WebhookDelivery{
+		SubscriptionId: old.SubscriptionId,
+		EventId:        old.EventId,
+		Event:          old.Event,
+		Payload:        old.Payload,
+		Status:         model.WebhookDeliveryPending,
+		RedeliveryOf:   old.ID,
+		NextRetryAt:    &now,
+		CreatedAt:      now,
+	}
+	if err = model.CreateWebhookDelivery(d); err != nil {
+		return nil, err
+	}
+	submit(d.ID)
+	return d, nil
+}
+
+func subscribed(s model.WebhookSubscription, event string) bool {
+	return utils.InSliceString(strings.Split(s.Events, ","), event)
+}
+
+// submit 不阻塞地提交推送，排队已满时放弃，推送仍是 pending，由 scanDue 到期后重新提交
+func submit(id int64) {
+	if hookPool == nil {
+		return
+	}
+	if _, loaded := inflight.LoadOrStore(id, struct{}{}); loaded {
+		return
+	}
+	if !hookPool.TrySubmit(&deliveryWorker{id: id}) {
+		inflight.Delete(id)
+	}
+}
+
+// scanDue 提交到了重试时间的推送
+func scanDue() {
+	rows, err := model.FindDueWebhookDeliveries(time.Now(), scanLimit)
+	if err != nil {
+		log.Log.Error("find due webhook deliveries err:", err)
+		return
+	}
+	for _, v := range rows {
+		submit(v.ID)
+	}
+}
+
+// backoff 第 attempts 次失败后的重试等待时长
+func backoff(cfg config.WebhookConfig, attempts int) time.Duration {
+	wait, max := cfg.Backoff*time.Second, cfg.MaxBackoff*time.Second
+	for i := 1; i < attempts && wait < max; i++ {
+		wait *= 2
+	}
+	if max > 0 && wait > max {
+		wait = max
+	}
+	return wait
+}
@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"tool-attendance/config"
+	"tool-attendance/model"
+	"tool-attendance/utils/safehttp"
+)
+
+func TestBackoff(t *testing.T) {
+	cfg := config.WebhookConfig{Backoff: 30, MaxBackoff: 300}
+	want := []time.Duration{30, 60, 120, 240, 300, 300}
+	for i, w := range want {
+		if got := backoff(cfg, i+1); got != w*time.Second {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, w*time.Second)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	const secret = "whsec_test"
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		if r.Header.Get(HeaderSignature) != Sign(secret, timestamp, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get(HeaderEvent) != EventPunchCreated || r.Header.Get(HeaderEventId) != "e1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	sub := &model.WebhookSubscription{Url: srv.URL, Secret: secret}
+	d := &model.WebhookDelivery{ID: 1, EventId: "e1", Event: EventPunchCreated, Payload: `{"id":"e1"}`}
+	// 默认客户端不允许访问回环地址
+	if _, _, err := send(sub, d); !errors.Is(err, safehttp.ErrNotAllowed) {
+		t.Fatalf("loopback: err=%v", err)
+	}
+
+	client := hookClient
+	hookClient = srv.Client()
+	defer func() { hookClient = client }()
+	code, body, err := send(sub, d)
+	if err != nil || code != http.StatusOK || body != "ok" {
+		t.Fatalf("send: code=%d body=%s err=%v", code, body, err)
+	}
+
+	sub.Secret = "wrong"
+	if code, _, err = send(sub, d); err == nil || code != http.StatusUnauthorized {
+		t.Fatalf("bad signature: code=%d err=%v", code, err)
+	}
+
+	sub.Secret, status = secret, http.StatusInternalServerError
+	if code, _, err = send(sub, d); err == nil || code != http.StatusInternalServerError {
+		t.Fatalf("server error: code=%d err=%v", code, err)
+	}
+}
+
+func TestSubscribed(t *testing.T) {
+	s := model.WebhookSubscription{Events: "punch.created,month.finalised"}
+	if !subscribed(s, EventMonthFinalised) || subscribed(s, EventLeaveApproved) {
+		t.Fatal("subscribed")
+	}
+}